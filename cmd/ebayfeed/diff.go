@@ -0,0 +1,140 @@
+package main
+
+import (
+	"ebay-api-client/diff"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// diffChange is a changed field in the JSON Lines output of the diff command
+type diffChange struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// diffEvent is the JSON Lines output of the diff command
+type diffEvent struct {
+	Type    diff.EventType        `json:"type"`
+	ID      string                `json:"id"`
+	Fields  []string              `json:"fields,omitempty"`
+	Changes map[string]diffChange `json:"changes,omitempty"`
+}
+
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "text", "output format: text or jsonl")
+	output := fs.String("o", "-", "output file, - for the standard output. The output is gzipped if the name ends with .gz")
+	ignore := fs.String("ignore", "", "comma separated Item fields not compared")
+	memory := fs.Int64("memory", diff.DefaultMemoryLimit>>20, "MB of each feed sorted in memory, bigger feeds are sorted in temporary files")
+	tmp := fs.String("tmp", "", "directory of the temporary files, defaults to the os temporary directory")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ebayfeed diff [flags] <old.tsv.gz> <new.tsv.gz>")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitUsage
+	}
+	if *format != "text" && *format != "jsonl" {
+		fmt.Fprintf(stderr, "diff: unsupported format %q\n", *format)
+		return exitUsage
+	}
+
+	opts := &diff.Options{MemoryLimit: *memory << 20, TempDir: *tmp}
+	if *ignore != "" {
+		fields, err := parseFields(*ignore)
+		if err != nil {
+			fmt.Fprintf(stderr, "diff: %v\n", err)
+			return exitUsage
+		}
+		opts.IgnoreFields = fields
+	}
+
+	oldFile, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: cannot open feed: %v\n", err)
+		return exitError
+	}
+	defer oldFile.Close()
+
+	newFile, err := os.Open(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: cannot open feed: %v\n", err)
+		return exitError
+	}
+	defer newFile.Close()
+
+	out, closeOutput, err := createOutput(*output, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitError
+	}
+
+	write := writeDiffText
+	if *format == "jsonl" {
+		write = writeDiffJSON
+	}
+
+	stats, err := diff.Feeds(oldFile, newFile, opts, func(e *diff.Event) error {
+		return write(out, e)
+	})
+	if err != nil {
+		closeOutput()
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitError
+	}
+
+	if err := closeOutput(); err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: cannot write output: %v\n", err)
+		return exitError
+	}
+
+	fmt.Fprintf(stderr, "%v added, %v removed, %v changed, %v unchanged\n", stats.Added, stats.Removed, stats.Changed, stats.Unchanged)
+	return exitOK
+}
+
+// writeDiffText writes the event as "+ id", "- id" or "~ id fields"
+func writeDiffText(w io.Writer, e *diff.Event) error {
+	var err error
+	switch e.Type {
+	case diff.Added:
+		_, err = fmt.Fprintf(w, "+ %v\n", e.ID)
+	case diff.Removed:
+		_, err = fmt.Fprintf(w, "- %v\n", e.ID)
+	default:
+		_, err = fmt.Fprintf(w, "~ %v %v\n", e.ID, strings.Join(e.Fields, ","))
+	}
+	return err
+}
+
+// writeDiffJSON writes the event as a JSON object with the old and new values of the changed fields
+func writeDiffJSON(w io.Writer, e *diff.Event) error {
+	out := &diffEvent{Type: e.Type, ID: e.ID, Fields: e.Fields}
+	if e.Type == diff.Changed {
+		out.Changes = make(map[string]diffChange, len(e.Fields))
+		for _, field := range e.Fields {
+			oldValue, _ := e.Old.Field(field)
+			newValue, _ := e.New.Field(field)
+			out.Changes[field] = diffChange{Old: oldValue, New: newValue}
+		}
+	}
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
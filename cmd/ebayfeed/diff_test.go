@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"ebay-api-client/ebay"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func Test_IsDiffComparingFeeds(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ebayfeed")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	oldFeed, newFeed := filepath.Join(dir, "old.tsv.gz"), filepath.Join(dir, "new.tsv.gz")
+	writeFeedFile(t, oldFeed, testItems...)
+	writeFeedFile(t, newFeed,
+		&ebay.Item{ID: "v1|1|0", Title: "Red phone", CategoryID: "9355", PriceValue: "8.5", PriceCurrency: "USD"},
+		testItems[2],
+		&ebay.Item{ID: "v1|4|0", Title: "Tablet"},
+	)
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	assert.Equal(t, run([]string{"diff", oldFeed, newFeed}, stdout, stderr), exitOK, stderr.String())
+	assert.Equal(t, stdout.String(), "~ v1|1|0 PriceValue\n- v1|2|0\n+ v1|4|0\n")
+	assert.Equal(t, stderr.String(), "1 added, 1 removed, 1 changed, 1 unchanged\n")
+
+	stdout.Reset()
+	assert.Equal(t, run([]string{"diff", "-format", "jsonl", "-tmp", dir, oldFeed, newFeed}, stdout, stderr), exitOK)
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	assert.Equal(t, len(lines), 3)
+
+	e := &diffEvent{}
+	assert.NilError(t, json.Unmarshal([]byte(lines[0]), e))
+	assert.DeepEqual(t, e.Changes, map[string]diffChange{"PriceValue": {Old: "9.5", New: "8.5"}})
+
+	stdout.Reset()
+	assert.Equal(t, run([]string{"diff", "-ignore", "pricevalue", oldFeed, newFeed}, stdout, stderr), exitOK)
+	assert.Equal(t, stdout.String(), "- v1|2|0\n+ v1|4|0\n")
+
+	assert.Equal(t, run([]string{"diff", oldFeed}, stdout, stderr), exitUsage)
+	assert.Equal(t, run([]string{"diff", "-ignore", "Price", oldFeed, newFeed}, stdout, stderr), exitUsage)
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"ebay-api-client/config"
+	"ebay-api-client/ebay"
+	"ebay-api-client/oauth2"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	xoauth2 "golang.org/x/oauth2"
+)
+
+const (
+	dateLayout     string = "2006-01-02"
+	manifestSuffix string = ".manifest.json"
+
+	// progressInterval is the minimum time between two progress updates
+	progressInterval = 200 * time.Millisecond
+)
+
+// downloadFlags are the flags shared by the download commands
+type downloadFlags struct {
+	market     string
+	category   string
+	date       string
+	hour       int
+	env        string
+	configPath string
+	output     string
+	manifest   string
+	quiet      bool
+}
+
+func newDownloadFlagSet(name string, stderr io.Writer, withDate, withHour bool) (*flag.FlagSet, *downloadFlags) {
+	f := &downloadFlags{}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&f.market, "market", "EBAY_US", "eBay marketplace id")
+	fs.StringVar(&f.category, "category", "", "eBay top level category id (required)")
+	if withDate {
+		fs.StringVar(&f.date, "date", "", "feed date in yyyy-mm-dd format (UTC)")
+	}
+	if withHour {
+		fs.IntVar(&f.hour, "hour", -1, "snapshot hour 0-23 (UTC), defaults to the previous hour")
+	}
+	fs.StringVar(&f.env, "env", "", "eBay environment, sandbox or production (overrides "+config.EnvName+")")
+	fs.StringVar(&f.configPath, "config", "", "environment configuration file (json, yaml or toml)")
+	fs.StringVar(&f.output, "o", "", "output file, defaults to a name built from the feed criteria")
+	fs.StringVar(&f.manifest, "manifest", "", "manifest file, defaults to the output file with the "+manifestSuffix+" suffix")
+	fs.BoolVar(&f.quiet, "quiet", false, "do not show the download progress")
+
+	return fs, f
+}
+
+// download is the function downloading a feed with the given service
+type download func(ctx context.Context, s *ebay.FeedService, dst io.Writer) (*ebay.FeedInfo, error)
+
+func runBootstrap(args []string, stdout, stderr io.Writer) int {
+	fs, f := newDownloadFlagSet("bootstrap", stderr, false, false)
+	if code, ok := parseFlags(fs, f, args); !ok {
+		return code
+	}
+
+	return runDownload(f, "", stdout, stderr, func(ctx context.Context, s *ebay.FeedService, dst io.Writer) (*ebay.FeedInfo, error) {
+		return s.WeeklyItemBoostrap(ctx, f.market, f.category, dst)
+	})
+}
+
+func runDaily(args []string, stdout, stderr io.Writer) int {
+	fs, f := newDownloadFlagSet("daily", stderr, true, false)
+	if code, ok := parseFlags(fs, f, args); !ok {
+		return code
+	}
+
+	date, err := parseDate(f.date, time.Now().UTC().AddDate(0, 0, -1))
+	if err != nil {
+		fmt.Fprintf(stderr, "daily: %v\n", err)
+		return exitUsage
+	}
+
+	return runDownload(f, date.Format("20060102"), stdout, stderr, func(ctx context.Context, s *ebay.FeedService, dst io.Writer) (*ebay.FeedInfo, error) {
+		return s.DailyNewlyItems(ctx, f.market, f.category, date, dst)
+	})
+}
+
+func runSnapshot(args []string, stdout, stderr io.Writer) int {
+	fs, f := newDownloadFlagSet("snapshot", stderr, true, true)
+	if code, ok := parseFlags(fs, f, args); !ok {
+		return code
+	}
+
+	previous := time.Now().UTC().Add(-time.Hour).Truncate(time.Hour)
+	date, err := parseDate(f.date, previous)
+	if err != nil {
+		fmt.Fprintf(stderr, "snapshot: %v\n", err)
+		return exitUsage
+	}
+
+	hour := f.hour
+	if hour < 0 {
+		hour = previous.Hour()
+	}
+	if hour > 23 {
+		fmt.Fprintf(stderr, "snapshot: invalid hour %v\n", hour)
+		return exitUsage
+	}
+	date = time.Date(date.Year(), date.Month(), date.Day(), hour, 0, 0, 0, time.UTC)
+
+	return runDownload(f, date.Format("2006010215"), stdout, stderr, func(ctx context.Context, s *ebay.FeedService, dst io.Writer) (*ebay.FeedInfo, error) {
+		return s.ItemShapshot(ctx, f.market, f.category, date, dst)
+	})
+}
+
+func runItemGroup(args []string, stdout, stderr io.Writer) int {
+	fs, f := newDownloadFlagSet("item-group", stderr, true, false)
+	if code, ok := parseFlags(fs, f, args); !ok {
+		return code
+	}
+
+	if f.date == "" {
+		return runDownload(f, "", stdout, stderr, func(ctx context.Context, s *ebay.FeedService, dst io.Writer) (*ebay.FeedInfo, error) {
+			return s.WeeklyItemGroupBoostrap(ctx, f.market, f.category, dst)
+		})
+	}
+
+	date, err := parseDate(f.date, time.Time{})
+	if err != nil {
+		fmt.Fprintf(stderr, "item-group: %v\n", err)
+		return exitUsage
+	}
+
+	return runDownload(f, date.Format("20060102"), stdout, stderr, func(ctx context.Context, s *ebay.FeedService, dst io.Writer) (*ebay.FeedInfo, error) {
+		return s.DailyNewlyItemGroups(ctx, f.market, f.category, date, dst)
+	})
+}
+
+// parseFlags parses the command flags, returning the exit code and false if the command cannot run
+func parseFlags(fs *flag.FlagSet, f *downloadFlags, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+
+	if f.category == "" {
+		fmt.Fprintf(fs.Output(), "%v: -category is required\n", fs.Name())
+		fs.Usage()
+		return exitUsage, false
+	}
+
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "%v: unexpected arguments %v\n", fs.Name(), fs.Args())
+		return exitUsage, false
+	}
+
+	return exitOK, true
+}
+
+// parseDate parses the date flag, returning def if not set
+func parseDate(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected format is yyyy-mm-dd", value)
+	}
+	return date, nil
+}
+
+// manifest describes a downloaded feed file
+type manifest struct {
+	File         string    `json:"file"`
+	Environment  string    `json:"environment"`
+	Type         string    `json:"type"`
+	MarketID     string    `json:"market_id"`
+	CategoryID   string    `json:"category_id"`
+	Scope        string    `json:"scope,omitempty"`
+	Date         string    `json:"date,omitempty"`
+	LastModified time.Time `json:"last_modified"`
+	Size         int64     `json:"size"`
+	SHA256       string    `json:"sha256"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+}
+
+// runDownload downloads the feed into the output file and writes its manifest
+func runDownload(f *downloadFlags, date string, stdout, stderr io.Writer, fn download) int {
+	// An interrupt cancels the download, downloadFile removes the partial file
+	ctx, cancel := interruptContext()
+	defer cancel()
+
+	env, err := loadEnvironment(f.configPath, f.env)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitUsage
+	}
+
+	httpClient, err := env.NewClientCredentialsClient(ctx, []string{oauth2.ScopeBuyFeedAPI})
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: cannot create the HTTP client: %v\n", err)
+		return exitAuth
+	}
+
+	service, err := env.NewFeedService(httpClient)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitAuth
+	}
+
+	output := f.output
+	if output == "" {
+		output = defaultOutput(f, date)
+	}
+
+	var p *progress
+	if !f.quiet {
+		p = &progress{out: stderr, name: output}
+	}
+
+	info, sum, err := downloadFile(ctx, service, output, p, fn)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitCode(err)
+	}
+
+	m := &manifest{
+		File:         output,
+		Environment:  env.Name,
+		Type:         info.Type,
+		MarketID:     info.MarketID,
+		CategoryID:   info.CategoryID,
+		Scope:        info.Scope,
+		Date:         date,
+		LastModified: info.LastModified,
+		Size:         info.Size,
+		SHA256:       sum,
+		DownloadedAt: time.Now().UTC(),
+	}
+
+	manifestPath := f.manifest
+	if manifestPath == "" {
+		manifestPath = output + manifestSuffix
+	}
+
+	if err := writeManifest(manifestPath, m); err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitError
+	}
+
+	fmt.Fprintf(stdout, "%v %v bytes, last modified %v\n", output, info.Size, info.LastModified.Format(time.RFC3339))
+	return exitOK
+}
+
+// loadEnvironment reads the environment from the configuration file or from the os environment variables
+func loadEnvironment(configPath, name string) (*config.Environment, error) {
+	if configPath != "" {
+		return config.Load(configPath, name)
+	}
+	return config.FromEnv(name)
+}
+
+// interruptContext returns a context cancelled on SIGINT or SIGTERM, cancel releases the signal handler
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+// defaultOutput builds the output file name from the feed criteria
+func defaultOutput(f *downloadFlags, date string) string {
+	name := fmt.Sprintf("%v_%v", f.market, f.category)
+	if date != "" {
+		name += "_" + date
+	}
+	return name + ".tsv.gz"
+}
+
+// downloadFile downloads the feed into a temporary file renamed to output once completed.
+// It returns the feed info and the SHA-256 checksum of the file.
+func downloadFile(ctx context.Context, service *ebay.FeedService, output string, p *progress, fn download) (*ebay.FeedInfo, string, error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(output), filepath.Base(output)+".*.tmp")
+	if err != nil {
+		return nil, "", fmt.Errorf("downloadFile(): cannot create output file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	var dst io.Writer = io.MultiWriter(tmp, hash)
+	if p != nil {
+		dst = io.MultiWriter(dst, p)
+	}
+
+	info, err := fn(ctx, service, dst)
+	if p != nil {
+		p.done()
+	}
+	if err != nil {
+		tmp.Close()
+		return nil, "", err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return nil, "", fmt.Errorf("downloadFile(): cannot write output file: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), output); err != nil {
+		return nil, "", fmt.Errorf("downloadFile(): cannot move output file: %v", err)
+	}
+
+	return info, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func writeManifest(path string, m *manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("writeManifest(): cannot encode manifest: %v", err)
+	}
+
+	if err := ioutil.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writeManifest(): cannot write manifest: %v", err)
+	}
+	return nil
+}
+
+// exitCode maps the download error to the exit code
+func exitCode(err error) int {
+	var retrieveErr *xoauth2.RetrieveError
+
+	switch {
+	case ebay.IsNoContent(err):
+		return exitEmptyFeed
+	case ebay.IsRateLimited(err):
+		return exitRateLimited
+	case ebay.IsAuthError(err), errors.As(err, &retrieveErr):
+		return exitAuth
+	}
+	return exitError
+}
+
+// progress is an io.Writer reporting the number of bytes downloaded
+type progress struct {
+	out     io.Writer
+	name    string
+	written int64
+	last    time.Time
+}
+
+func (p *progress) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	if now := time.Now(); now.Sub(p.last) >= progressInterval {
+		p.last = now
+		p.print()
+	}
+	return len(b), nil
+}
+
+func (p *progress) print() {
+	fmt.Fprintf(p.out, "\rdownloading %v: %.1f MB", p.name, float64(p.written)/(1<<20))
+}
+
+// done prints the final progress line
+func (p *progress) done() {
+	p.print()
+	fmt.Fprintln(p.out)
+}
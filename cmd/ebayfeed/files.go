@@ -0,0 +1,408 @@
+package main
+
+import (
+	"compress/gzip"
+	"ebay-api-client/ebay"
+	"ebay-api-client/export"
+	"ebay-api-client/filter"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxReportedLines is the maximum number of line numbers listed in the inspect schema report
+	maxReportedLines = 5
+)
+
+// stringsFlag is a repeatable string flag
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func runInspect(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	sample := fs.Int("n", 5, "number of sample rows to show")
+	workers := workersFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ebayfeed inspect [flags] <feed.tsv.gz>")
+		fs.PrintDefaults()
+	}
+
+	path, code, ok := parseFileFlags(fs, args)
+	if !ok {
+		return code
+	}
+
+	r, closeFeed, err := openFeed(path, *workers)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitError
+	}
+	defer closeFeed()
+
+	var (
+		rows       int
+		badColumns []int
+		noID       []int
+		samples    []*ebay.Item
+	)
+
+	header := r.Header()
+	for {
+		item, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+			return exitError
+		}
+
+		rows++
+		if r.Columns() != len(header) {
+			badColumns = append(badColumns, r.Line())
+		}
+		if item.ID == "" {
+			noID = append(noID, r.Line())
+		}
+		if len(samples) < *sample {
+			samples = append(samples, item)
+		}
+	}
+
+	fmt.Fprintf(stdout, "file:    %v\n", path)
+	fmt.Fprintf(stdout, "header:  %v columns\n", len(header))
+	fmt.Fprintf(stdout, "rows:    %v\n", rows)
+
+	valid := true
+	if len(header) != len(ebay.ItemFields) {
+		valid = false
+		fmt.Fprintf(stdout, "schema:  header has %v columns, Item has %v fields\n", len(header), len(ebay.ItemFields))
+	}
+	if len(badColumns) > 0 {
+		valid = false
+		fmt.Fprintf(stdout, "schema:  %v rows with a column count different from the header (lines %v)\n", len(badColumns), formatLines(badColumns))
+	}
+	if len(noID) > 0 {
+		valid = false
+		fmt.Fprintf(stdout, "schema:  %v rows without item id (lines %v)\n", len(noID), formatLines(noID))
+	}
+	if valid {
+		fmt.Fprintln(stdout, "schema:  ok")
+	}
+
+	if len(samples) > 0 {
+		fmt.Fprintln(stdout, "sample:")
+		for _, item := range samples {
+			fmt.Fprintf(stdout, "  %v\t%v\t%v %v\t%v\n", item.ID, item.CategoryID, item.PriceValue, item.PriceCurrency, item.Title)
+		}
+	}
+
+	if !valid {
+		return exitInvalidFeed
+	}
+	return exitOK
+}
+
+func runConvert(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "jsonl", "output format: jsonl, csv or tsv")
+	fields := fs.String("fields", "", "comma separated Item fields to write, defaults to all")
+	output := fs.String("o", "-", "output file, - for the standard output. The output is gzipped if the name ends with .gz")
+	workers := workersFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ebayfeed convert [flags] <feed.tsv.gz>")
+		fs.PrintDefaults()
+	}
+
+	path, code, ok := parseFileFlags(fs, args)
+	if !ok {
+		return code
+	}
+
+	_, code = copyItems(path, *workers, *format, *fields, *output, nil, stdout, stderr)
+	return code
+}
+
+func runGrep(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("grep", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var where stringsFlag
+	fs.Var(&where, "where", "field predicate, i.e. PriceCurrency=USD, PriceValue>=10 or Title~phone (repeatable, all must match)")
+	expr := fs.String("filter", "", `filter expression, i.e. "PriceValue < 100 AND CategoryID IN (9355, 177) AND NOT Title ~ '(?i)case'"`)
+	format := fs.String("format", "tsv", "output format: jsonl, csv or tsv")
+	fields := fs.String("fields", "", "comma separated Item fields to write, defaults to all")
+	output := fs.String("o", "-", "output file, - for the standard output. The output is gzipped if the name ends with .gz")
+	count := fs.Bool("count", false, "only print the number of matching rows")
+	workers := workersFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ebayfeed grep [flags] <feed.tsv.gz>")
+		fs.PrintDefaults()
+	}
+
+	path, code, ok := parseFileFlags(fs, args)
+	if !ok {
+		return code
+	}
+
+	predicates := make([]filter.Predicate, 0, len(where)+1)
+	for _, w := range where {
+		p, err := parseWhere(w)
+		if err != nil {
+			fmt.Fprintf(stderr, "grep: %v\n", err)
+			return exitUsage
+		}
+		predicates = append(predicates, p)
+	}
+
+	selected, err := filter.Parse(*expr)
+	if err != nil {
+		fmt.Fprintf(stderr, "grep: %v\n", err)
+		return exitUsage
+	}
+	match := filter.And(append(predicates, selected)...).Match
+
+	if *count {
+		*output = os.DevNull
+	}
+
+	matched, code := copyItems(path, *workers, *format, *fields, *output, match, stdout, stderr)
+	if *count && code == exitOK {
+		fmt.Fprintln(stdout, matched)
+	}
+	return code
+}
+
+// parseFileFlags parses the flags of the commands reading a feed file and returns the file path
+func parseFileFlags(fs *flag.FlagSet, args []string) (string, int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return "", exitOK, false
+		}
+		return "", exitUsage, false
+	}
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return "", exitUsage, false
+	}
+	return fs.Arg(0), exitOK, true
+}
+
+// workersFlag defines the -workers flag of the commands reading a feed file
+func workersFlag(fs *flag.FlagSet) *int {
+	return fs.Int("workers", 0, "number of goroutines parsing the feed rows, 0 for the number of CPUs")
+}
+
+// openFeed opens the feed file, - being the standard input. The rows are parsed by the given number of workers,
+// the items are read in the order of the feed.
+func openFeed(path string, workers int) (*ebay.ParallelItemReader, func(), error) {
+	var f io.ReadCloser = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("openFeed(): cannot open feed: %v", err)
+		}
+		f = file
+	}
+
+	r, err := ebay.NewParallelItemReader(f, &ebay.ParallelOptions{Workers: workers, Ordered: true})
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	return r, func() {
+		r.Close()
+		f.Close()
+	}, nil
+}
+
+// copyItems writes the feed items accepted by match (all if nil) to the output in the given format.
+// It returns the number of items written and the exit code.
+func copyItems(path string, workers int, format, fieldList, output string, match func(*ebay.Item) bool, stdout, stderr io.Writer) (int, int) {
+	fields, err := parseFields(fieldList)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return 0, exitUsage
+	}
+
+	r, closeFeed, err := openFeed(path, workers)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return 0, exitError
+	}
+	defer closeFeed()
+
+	out, closeOutput, err := createOutput(output, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return 0, exitError
+	}
+
+	w, err := newRowWriter(format, out, fields)
+	if err != nil {
+		closeOutput()
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return 0, exitUsage
+	}
+
+	written := 0
+	for {
+		item, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			closeOutput()
+			fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+			return written, exitError
+		}
+
+		if match != nil && !match(item) {
+			continue
+		}
+
+		if err := w.Write(item); err != nil {
+			closeOutput()
+			fmt.Fprintf(stderr, "ebayfeed: cannot write output: %v\n", err)
+			return written, exitError
+		}
+		written++
+	}
+
+	if err := w.Close(); err != nil {
+		closeOutput()
+		fmt.Fprintf(stderr, "ebayfeed: cannot write output: %v\n", err)
+		return written, exitError
+	}
+
+	if err := closeOutput(); err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: cannot write output: %v\n", err)
+		return written, exitError
+	}
+	return written, exitOK
+}
+
+// parseFields parses the comma separated list of Item fields, returning all the fields if empty
+func parseFields(list string) ([]string, error) {
+	if list == "" {
+		return ebay.ItemFields, nil
+	}
+
+	var fields []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		field, ok := itemField(name)
+		if !ok {
+			return nil, fmt.Errorf("parseFields(): unknown field %q", name)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// itemField returns the Item field name matching the given case insensitive name
+func itemField(name string) (string, bool) {
+	for _, field := range ebay.ItemFields {
+		if strings.EqualFold(field, name) {
+			return field, true
+		}
+	}
+	return "", false
+}
+
+// createOutput creates the output file, gzipped if its name ends with .gz. - is the standard output.
+func createOutput(path string, stdout io.Writer) (io.Writer, func() error, error) {
+	if path == "-" {
+		return stdout, func() error { return nil }, nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("createOutput(): cannot create output: %v", err)
+	}
+
+	if !strings.HasSuffix(path, ".gz") {
+		return f, f.Close, nil
+	}
+
+	gz := gzip.NewWriter(f)
+	return gz, func() error {
+		if err := gz.Close(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}, nil
+}
+
+// newRowWriter creates the export writer of the output format writing the given fields
+func newRowWriter(format string, w io.Writer, fields []string) (export.Writer, error) {
+	opts := &export.Options{Fields: fields}
+	switch format {
+	case "jsonl":
+		return export.NewJSONLinesWriter(w, opts)
+	case "csv":
+		return export.NewCSVWriter(w, opts)
+	case "tsv":
+		return export.NewTSVWriter(w, opts)
+	}
+	return nil, fmt.Errorf("newRowWriter(): unsupported format %q", format)
+}
+
+// whereOps are the operators of the -where predicates, the two characters ones first so that they are matched before
+// their prefixes. ~ is a case insensitive substring match, the others are filter comparisons.
+var whereOps = []string{"!=", ">=", "<=", "=", ">", "<", "~"}
+
+// parseWhere parses a -where predicate in the form <field><op><value>
+func parseWhere(s string) (filter.Predicate, error) {
+	at, op := -1, ""
+	for _, o := range whereOps {
+		if i := strings.Index(s, o); i > 0 && (at < 0 || i < at || (i == at && len(o) > len(op))) {
+			at, op = i, o
+		}
+	}
+	if at < 0 {
+		return nil, fmt.Errorf("invalid predicate %q, expected <field><op><value> with op one of %v", s, strings.Join(whereOps, " "))
+	}
+
+	field, value := strings.TrimSpace(s[:at]), s[at+len(op):]
+	var p filter.Predicate
+	var err error
+	if op == "~" {
+		p, err = filter.Contains(field, value)
+	} else {
+		p, err = filter.Compare(field, filter.Op(op), value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid predicate %q: %v", s, err)
+	}
+	return p, nil
+}
+
+// formatLines formats the first line numbers of a report
+func formatLines(lines []int) string {
+	var s []string
+	for n, line := range lines {
+		if n == maxReportedLines {
+			s = append(s, "...")
+			break
+		}
+		s = append(s, strconv.Itoa(line))
+	}
+	return strings.Join(s, ", ")
+}
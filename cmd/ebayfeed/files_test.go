@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"ebay-api-client/ebay"
+	"ebay-api-client/ebay/ebaytest"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+// newTestFeedFile writes a gzipped feed with the Item columns and the given rows into a temporary directory
+func newTestFeedFile(t *testing.T, rows ...*ebay.Item) (string, func()) {
+	dir, err := ioutil.TempDir("", "ebayfeed")
+	assert.NilError(t, err)
+
+	path := filepath.Join(dir, "feed.tsv.gz")
+	writeFeedFile(t, path, rows...)
+
+	return path, func() { os.RemoveAll(dir) }
+}
+
+var testItems = []*ebay.Item{
+	{ID: "v1|1|0", Title: "Red phone", CategoryID: "9355", PriceValue: "9.5", PriceCurrency: "USD"},
+	{ID: "v1|2|0", Title: "Blue Phone case", CategoryID: "9355", PriceValue: "12", PriceCurrency: "USD"},
+	{ID: "v1|3|0", Title: "Laptop", CategoryID: "177", PriceValue: "350", PriceCurrency: "EUR"},
+}
+
+func Test_IsInspectReportingFeed(t *testing.T) {
+	path, cleanup := newTestFeedFile(t, testItems...)
+	defer cleanup()
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	code := run([]string{"inspect", "-n", "2", path}, stdout, stderr)
+	assert.Equal(t, code, exitOK, stderr.String())
+
+	out := stdout.String()
+	assert.Assert(t, strings.Contains(out, "rows:    3\n"), out)
+	assert.Assert(t, strings.Contains(out, "schema:  ok\n"), out)
+	assert.Assert(t, strings.Contains(out, "v1|2|0"), out)
+	assert.Assert(t, !strings.Contains(out, "v1|3|0"), out)
+}
+
+func Test_IsInspectReportingInvalidSchema(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ebayfeed")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "feed.tsv.gz")
+	data := ebaytest.GzipTSV([]string{"ItemId", "Title"},
+		[]string{"v1|1|0", "Item 1"},
+		[]string{"v1|2|0"},
+		[]string{"", "No id"},
+	)
+	assert.NilError(t, ioutil.WriteFile(path, data, 0644))
+
+	stdout := new(bytes.Buffer)
+	assert.Equal(t, run([]string{"inspect", path}, stdout, new(bytes.Buffer)), exitInvalidFeed)
+
+	out := stdout.String()
+	assert.Assert(t, strings.Contains(out, "header has 2 columns"), out)
+	assert.Assert(t, strings.Contains(out, "1 rows with a column count different from the header (lines 3)"), out)
+	assert.Assert(t, strings.Contains(out, "1 rows without item id (lines 4)"), out)
+}
+
+func Test_IsConvertWritingFormats(t *testing.T) {
+	path, cleanup := newTestFeedFile(t, testItems...)
+	defer cleanup()
+
+	stdout := new(bytes.Buffer)
+	code := run([]string{"convert", "-fields", "id,PriceValue", path}, stdout, new(bytes.Buffer))
+	assert.Equal(t, code, exitOK)
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	assert.Equal(t, len(lines), 3)
+	assert.Equal(t, lines[0], `{"itemId":"v1|1|0","priceValue":"9.5"}`)
+
+	stdout.Reset()
+	code = run([]string{"convert", "-format", "csv", "-workers", "2", path}, stdout, new(bytes.Buffer))
+	assert.Equal(t, code, exitOK)
+
+	records, err := csv.NewReader(stdout).ReadAll()
+	assert.NilError(t, err)
+	assert.Equal(t, len(records), 4)
+	assert.DeepEqual(t, records[0], ebay.ItemFields)
+	assert.DeepEqual(t, records[3], testItems[2].Values())
+
+	output := filepath.Join(filepath.Dir(path), "items.jsonl.gz")
+	code = run([]string{"convert", "-o", output, path}, new(bytes.Buffer), new(bytes.Buffer))
+	assert.Equal(t, code, exitOK)
+
+	_, err = os.Stat(output)
+	assert.NilError(t, err)
+
+	assert.Equal(t, run([]string{"convert", "-format", "xml", path}, new(bytes.Buffer), new(bytes.Buffer)), exitUsage)
+	assert.Equal(t, run([]string{"convert", "-fields", "Price", path}, new(bytes.Buffer), new(bytes.Buffer)), exitUsage)
+}
+
+func Test_IsGrepFilteringRows(t *testing.T) {
+	path, cleanup := newTestFeedFile(t, testItems...)
+	defer cleanup()
+
+	tests := []struct {
+		where []string
+		want  []string
+	}{
+		{where: []string{"Title~PHONE"}, want: []string{"v1|1|0", "v1|2|0"}},
+		{where: []string{"PriceValue>=10"}, want: []string{"v1|2|0", "v1|3|0"}},
+		{where: []string{"PriceValue<10"}, want: []string{"v1|1|0"}},
+		{where: []string{"PriceCurrency!=USD"}, want: []string{"v1|3|0"}},
+		{where: []string{"CategoryID=9355", "PriceValue>10"}, want: []string{"v1|2|0"}},
+		{where: []string{"CategoryID=1"}, want: nil},
+	}
+	for _, tt := range tests {
+		args := []string{"grep", "-format", "jsonl", "-fields", "ID"}
+		for _, w := range tt.where {
+			args = append(args, "-where", w)
+		}
+
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		assert.Equal(t, run(append(args, path), stdout, stderr), exitOK, stderr.String())
+
+		var got []string
+		dec := json.NewDecoder(stdout)
+		for dec.More() {
+			row := map[string]string{}
+			assert.NilError(t, dec.Decode(&row))
+			got = append(got, row["itemId"])
+		}
+		assert.DeepEqual(t, got, tt.want)
+	}
+}
+
+func Test_IsGrepCountingRows(t *testing.T) {
+	path, cleanup := newTestFeedFile(t, testItems...)
+	defer cleanup()
+
+	stdout := new(bytes.Buffer)
+	assert.Equal(t, run([]string{"grep", "-count", "-where", "PriceCurrency=USD", path}, stdout, new(bytes.Buffer)), exitOK)
+	assert.Equal(t, stdout.String(), "2\n")
+
+	assert.Equal(t, run([]string{"grep", "-where", "Price", path}, new(bytes.Buffer), new(bytes.Buffer)), exitUsage)
+	assert.Equal(t, run([]string{"grep", "-where", "Unknown=1", path}, new(bytes.Buffer), new(bytes.Buffer)), exitUsage)
+}
+
+func Test_IsGrepFilteringExpression(t *testing.T) {
+	path, cleanup := newTestFeedFile(t, testItems...)
+	defer cleanup()
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	code := run([]string{"grep", "-format", "csv", "-fields", "ID", "-where", "PriceCurrency=USD", "-filter", `PriceValue > 5 AND NOT Title ~ "(?i)case"`, path}, stdout, stderr)
+	assert.Equal(t, code, exitOK, stderr.String())
+	assert.Equal(t, stdout.String(), "ID\nv1|1|0\n")
+
+	stdout = new(bytes.Buffer)
+	code = run([]string{"grep", "-count", "-filter", "CategoryID IN (177, 1) OR Title CONTAINS red", path}, stdout, new(bytes.Buffer))
+	assert.Equal(t, code, exitOK)
+	assert.Equal(t, stdout.String(), "2\n")
+
+	stderr = new(bytes.Buffer)
+	assert.Equal(t, run([]string{"grep", "-filter", "PriceValue >", path}, new(bytes.Buffer), stderr), exitUsage)
+	assert.Assert(t, strings.Contains(stderr.String(), "expected a value"))
+}
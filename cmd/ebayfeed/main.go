@@ -0,0 +1,110 @@
+// Command ebayfeed downloads the eBay Feed API files and works with the downloaded files.
+//
+// Usage:
+//
+//	ebayfeed <command> [flags]
+//
+// The commands are:
+//
+//	bootstrap   download the weekly item bootstrap feed
+//	daily       download the daily newly listed items feed
+//	snapshot    download the hourly item snapshot feed
+//	item-group  download the weekly (or daily with -date) item group feed
+//	inspect     show the header, row count, schema check and sample rows of a feed file
+//	convert     convert a feed file to JSON Lines, CSV or TSV
+//	parquet     convert a feed file to Parquet, with the raw or typed item columns
+//	grep        write the rows of a feed file matching field predicates
+//	diff        compare two feed files and list the items added, removed and changed
+//	merge       apply hourly snapshot files onto a bootstrap file and write the current inventory
+//	mirror      keep a local copy of the configured feeds up to date
+//	load        load feed files into an item store
+//	serve       serve the items of an item store over HTTP as JSON
+//	publish     publish the items of a feed file to the configured sinks (files, HTTP endpoints)
+//	token       mint an application access token and print it, optionally checking it against the Feed API
+//
+// The environment is read from the configuration file given with -config, or from the os environment
+// variables (see package config). The application credentials are read from EBAY_API_CLIENT_ID and EBAY_API_CLIENT_SECRET.
+//
+// The exit code is 0 on success, 2 on invalid usage, 3 if the feed is empty, 4 on authentication errors,
+// 5 if the call limit has been exceeded, 6 if inspect finds an invalid feed file and 1 on any other error.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Exit codes
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitUsage       = 2
+	exitEmptyFeed   = 3
+	exitAuth        = 4
+	exitRateLimited = 5
+	exitInvalidFeed = 6
+)
+
+// command is an ebayfeed subcommand
+type command struct {
+	name  string
+	usage string
+	run   func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands = []command{
+	{name: "bootstrap", usage: "download the weekly item bootstrap feed", run: runBootstrap},
+	{name: "daily", usage: "download the daily newly listed items feed", run: runDaily},
+	{name: "snapshot", usage: "download the hourly item snapshot feed", run: runSnapshot},
+	{name: "item-group", usage: "download the weekly (or daily with -date) item group feed", run: runItemGroup},
+	{name: "inspect", usage: "show the header, row count, schema check and sample rows of a feed file", run: runInspect},
+	{name: "convert", usage: "convert a feed file to JSON Lines, CSV or TSV", run: runConvert},
+	{name: "parquet", usage: "convert a feed file to Parquet, with the raw or typed item columns", run: runParquet},
+	{name: "grep", usage: "write the rows of a feed file matching field predicates", run: runGrep},
+	{name: "diff", usage: "compare two feed files and list the items added, removed and changed", run: runDiff},
+	{name: "merge", usage: "apply hourly snapshot files onto a bootstrap file and write the current inventory", run: runMerge},
+	{name: "mirror", usage: "keep a local copy of the configured feeds up to date", run: runMirror},
+	{name: "load", usage: "load feed files into an item store", run: runLoad},
+	{name: "serve", usage: "serve the items of an item store over HTTP as JSON", run: runServe},
+	{name: "publish", usage: "publish the items of a feed file to the configured sinks (files, HTTP endpoints)", run: runPublish},
+	{name: "token", usage: "mint an application access token and print it, optionally checking it against the Feed API", run: runToken},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the subcommand given in args and returns the exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:], stdout, stderr)
+		}
+	}
+
+	if args[0] == "help" || args[0] == "-h" || args[0] == "-help" {
+		usage(stdout)
+		return exitOK
+	}
+
+	fmt.Fprintf(stderr, "ebayfeed: unknown command %q\n", args[0])
+	usage(stderr)
+	return exitUsage
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: ebayfeed <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-11v %v\n", c.name, c.usage)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'ebayfeed <command> -h' for the command flags.")
+}
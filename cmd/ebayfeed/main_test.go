@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"ebay-api-client/config"
+	"ebay-api-client/ebay/ebaytest"
+	"ebay-api-client/mirror"
+	"ebay-api-client/oauth2"
+	"ebay-api-client/oauth2/oauth2test"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+// newTestEnvironment points the command to fake token and Feed API servers through the os environment variables
+func newTestEnvironment(t *testing.T) (*oauth2test.TokenServer, *ebaytest.FeedServer, string, func()) {
+	tokens := oauth2test.NewTokenServer()
+	tokens.AddClient("TEST-SBX-ID", "TEST-SBX-SECRET", oauth2.ScopeBuyFeedAPI)
+
+	feeds := ebaytest.NewFeedServer()
+	feeds.SetAuthorizer(func(r *http.Request) bool {
+		return tokens.Authorize(r, oauth2.ScopeBuyFeedAPI)
+	})
+
+	dir, err := ioutil.TempDir("", "ebayfeed")
+	assert.NilError(t, err)
+
+	vars := map[string]string{
+		config.EnvName:         config.Sandbox,
+		config.EnvFeedBaseURL:  feeds.BaseURL(),
+		config.EnvTokenURL:     tokens.TokenURL(),
+		oauth2.APIClientID:     "TEST-SBX-ID",
+		oauth2.APIClientSecret: "TEST-SBX-SECRET",
+	}
+	for name, value := range vars {
+		os.Setenv(name, value)
+	}
+
+	return tokens, feeds, dir, func() {
+		for name := range vars {
+			os.Unsetenv(name)
+		}
+		os.RemoveAll(dir)
+		feeds.Close()
+		tokens.Close()
+	}
+}
+
+func Test_IsBootstrapWritingFeedAndManifest(t *testing.T) {
+	_, feeds, dir, shutdown := newTestEnvironment(t)
+	defer shutdown()
+
+	data := ebaytest.GzipTSV([]string{"ItemId", "Title"}, []string{"v1|110000000000|0", "Item"})
+	feeds.AddFeed(&ebaytest.Feed{
+		Path:         ebaytest.PathItem,
+		Scope:        "ALL_ACTIVE",
+		LastModified: time.Date(2020, 5, 4, 7, 0, 0, 0, time.UTC),
+		Data:         data,
+	})
+
+	output := filepath.Join(dir, "bootstrap.tsv.gz")
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+
+	code := run([]string{"bootstrap", "-category", "1", "-o", output}, stdout, stderr)
+	assert.Equal(t, code, exitOK, stderr.String())
+	assert.Assert(t, stderr.Len() > 0)
+
+	b, err := ioutil.ReadFile(output)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, b, data)
+
+	b, err = ioutil.ReadFile(output + manifestSuffix)
+	assert.NilError(t, err)
+
+	m := &manifest{}
+	assert.NilError(t, json.Unmarshal(b, m))
+	assert.Equal(t, m.File, output)
+	assert.Equal(t, m.Environment, config.Sandbox)
+	assert.Equal(t, m.Type, "item")
+	assert.Equal(t, m.MarketID, "EBAY_US")
+	assert.Equal(t, m.CategoryID, "1")
+	assert.Equal(t, m.Scope, "ALL_ACTIVE")
+	assert.Equal(t, m.Size, int64(len(data)))
+	assert.Assert(t, m.LastModified.Equal(time.Date(2020, 5, 4, 7, 0, 0, 0, time.UTC)))
+	assert.Equal(t, len(m.SHA256), 64)
+}
+
+func Test_IsSnapshotRequestingDateAndHour(t *testing.T) {
+	_, feeds, dir, shutdown := newTestEnvironment(t)
+	defer shutdown()
+
+	feeds.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItemSnapshot, Date: "2020-05-03T07:00:00.000Z", Data: []byte("snapshot")})
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	output := filepath.Join(dir, "snapshot.tsv.gz")
+
+	code := run([]string{"snapshot", "-category", "1", "-date", "2020-05-03", "-hour", "7", "-o", output, "-quiet"}, stdout, stderr)
+	assert.Equal(t, code, exitOK, stderr.String())
+	assert.Equal(t, stderr.Len(), 0)
+
+	b, err := ioutil.ReadFile(output)
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "snapshot")
+}
+
+func Test_IsEnvFlagOverridingEnvName(t *testing.T) {
+	_, feeds, dir, shutdown := newTestEnvironment(t)
+	defer shutdown()
+
+	feeds.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItemSnapshot, Date: "2020-05-03T07:00:00.000Z", Data: []byte("snapshot")})
+	os.Setenv(config.EnvName, config.Production)
+
+	stderr := new(bytes.Buffer)
+	output := filepath.Join(dir, "snapshot.tsv.gz")
+	code := run([]string{"snapshot", "-env", config.Sandbox, "-category", "1", "-date", "2020-05-03", "-hour", "7", "-o", output, "-quiet"}, new(bytes.Buffer), stderr)
+	assert.Equal(t, code, exitOK, stderr.String())
+
+	// The flag is passed to the configuration, the os environment is left untouched
+	assert.Equal(t, os.Getenv(config.EnvName), config.Production)
+}
+
+func Test_IsItemGroupDownloadingWeeklyOrDaily(t *testing.T) {
+	_, feeds, dir, shutdown := newTestEnvironment(t)
+	defer shutdown()
+
+	feeds.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItemGroup, Scope: "ALL_ACTIVE", Data: []byte("weekly")})
+	feeds.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItemGroup, Scope: "NEWLY_LISTED", Date: "20200503", Data: []byte("daily")})
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"item-group", "-category", "1", "-quiet"}, want: "weekly"},
+		{args: []string{"item-group", "-category", "1", "-date", "2020-05-03", "-quiet"}, want: "daily"},
+	} {
+		output := filepath.Join(dir, tt.want+".tsv.gz")
+		code := run(append(tt.args, "-o", output), new(bytes.Buffer), new(bytes.Buffer))
+		assert.Equal(t, code, exitOK)
+
+		b, err := ioutil.ReadFile(output)
+		assert.NilError(t, err)
+		assert.Equal(t, string(b), tt.want)
+	}
+}
+
+func Test_IsExitCodeMeaningful(t *testing.T) {
+	tokens, feeds, dir, shutdown := newTestEnvironment(t)
+	defer shutdown()
+
+	feeds.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItem, CategoryID: "1", Data: []byte("feed")})
+	output := filepath.Join(dir, "feed.tsv.gz")
+
+	tests := []struct {
+		name  string
+		setup func()
+		args  []string
+		want  int
+	}{
+		{
+			name: "Is empty feed reported?",
+			args: []string{"daily", "-category", "2", "-date", "2020-05-03"},
+			want: exitEmptyFeed,
+		},
+		{
+			name:  "Is rate limit reported?",
+			setup: func() { feeds.InjectFault(ebaytest.RateLimited(1, time.Minute)) },
+			args:  []string{"bootstrap", "-category", "1"},
+			want:  exitRateLimited,
+		},
+		{
+			name: "Is token error reported?",
+			setup: func() {
+				tokens.InjectError(oauth2test.OAuthError{Status: http.StatusUnauthorized, Code: oauth2test.ErrInvalidClient})
+			},
+			args: []string{"bootstrap", "-category", "1"},
+			want: exitAuth,
+		},
+		{
+			name:  "Is missing credentials reported?",
+			setup: func() { os.Unsetenv(oauth2.APIClientSecret) },
+			args:  []string{"bootstrap", "-category", "1"},
+			want:  exitAuth,
+		},
+		{
+			name: "Is missing category reported?",
+			args: []string{"bootstrap"},
+			want: exitUsage,
+		},
+		{
+			name: "Is invalid date reported?",
+			args: []string{"daily", "-category", "1", "-date", "03/05/2020"},
+			want: exitUsage,
+		},
+		{
+			name: "Is unknown command reported?",
+			args: []string{"download"},
+			want: exitUsage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setup != nil {
+				tt.setup()
+			}
+
+			args := tt.args
+			if len(args) > 1 {
+				args = append(args, "-o", output, "-quiet")
+			}
+
+			stderr := new(bytes.Buffer)
+			assert.Equal(t, run(args, new(bytes.Buffer), stderr), tt.want, stderr.String())
+
+			_, err := os.Stat(output)
+			assert.Assert(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func Test_IsMirrorSynchronizingOnce(t *testing.T) {
+	_, feeds, dir, shutdown := newTestEnvironment(t)
+	defer shutdown()
+
+	feeds.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItem, Scope: "ALL_ACTIVE", Data: []byte("bootstrap")})
+	feeds.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItem, Scope: "NEWLY_LISTED", Data: []byte("daily")})
+
+	conf := filepath.Join(dir, "mirror.json")
+	data := `{"dir": "` + filepath.Join(dir, "feeds") + `", "feeds": [{"market_id": "EBAY_US", "category_ids": ["1"], "kinds": ["bootstrap", "daily"]}]}`
+	assert.NilError(t, ioutil.WriteFile(conf, []byte(data), 0644))
+
+	stderr := new(bytes.Buffer)
+	assert.Equal(t, run([]string{"mirror", "-once", conf}, new(bytes.Buffer), stderr), exitOK, stderr.String())
+
+	files, err := filepath.Glob(filepath.Join(dir, "feeds", "EBAY_US", "1", "*.tsv.gz"))
+	assert.NilError(t, err)
+	assert.Equal(t, len(files), 1+mirror.DefaultSchedule.DailyCatchUp)
+
+	_, err = os.Stat(filepath.Join(dir, "feeds", mirror.DefaultStateFile))
+	assert.NilError(t, err)
+
+	assert.Equal(t, run([]string{"mirror", "-once", filepath.Join(dir, "missing.json")}, new(bytes.Buffer), new(bytes.Buffer)), exitUsage)
+
+	// The errors stopping the synchronization are reported
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "feeds", mirror.DefaultStateFile), []byte("not json"), 0644))
+	stderr.Reset()
+	assert.Equal(t, run([]string{"mirror", "-once", conf}, new(bytes.Buffer), stderr), exitError)
+	assert.Assert(t, strings.Contains(stderr.String(), "ebayfeed: "), stderr.String())
+}
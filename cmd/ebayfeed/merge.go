@@ -0,0 +1,217 @@
+package main
+
+import (
+	"ebay-api-client/inventory"
+	"ebay-api-client/store"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+const (
+	snapshotHourLayout string = "2006010215"
+)
+
+// snapshotHourPattern matches the snapshot hour in the file names written by the snapshot and mirror commands
+var snapshotHourPattern = regexp.MustCompile(`(\d{10})\.tsv\.gz$`)
+
+// snapshotFile is a snapshot file with the hour it covers
+type snapshotFile struct {
+	path string
+	hour time.Time
+}
+
+func runMerge(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	bootstrap := fs.String("bootstrap", "", "weekly item bootstrap feed file (required without -db)")
+	generatedAt := fs.String("generated-at", "", "bootstrap generation time in RFC 3339 format, defaults to the last_modified of its manifest")
+	dbPath := fs.String("db", "", "item store file keeping the inventory and its watermark between the runs, in memory if not given")
+	output := fs.String("o", "inventory.tsv.gz", "output feed file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ebayfeed merge [flags] <snapshot.tsv.gz>...")
+		fmt.Fprintln(fs.Output(), "The snapshot hour is read from the yyyymmddhh suffix of the file names.")
+		fmt.Fprintln(fs.Output(), "With -db, the snapshots are applied onto the inventory of the store unless -bootstrap replaces it.")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if *bootstrap == "" && *dbPath == "" {
+		fmt.Fprintln(stderr, "merge: -bootstrap is required without -db")
+		fs.Usage()
+		return exitUsage
+	}
+
+	snapshots, err := parseSnapshotFiles(fs.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "merge: %v\n", err)
+		return exitUsage
+	}
+
+	var generated time.Time
+	if *bootstrap != "" {
+		generated, err = bootstrapGeneratedAt(*bootstrap, *generatedAt)
+		if err != nil {
+			fmt.Fprintf(stderr, "merge: %v\n", err)
+			return exitUsage
+		}
+	}
+
+	m := &merge{bootstrap: *bootstrap, generatedAt: generated, snapshots: snapshots, output: *output, stdout: stdout}
+	if *dbPath == "" {
+		err = m.run(inventory.NewMemoryStore())
+	} else {
+		// The store is updated on a copy, so that it is left unchanged if the merge fails
+		err = store.Update(*dbPath, func(db *store.DB) error {
+			return m.run(db)
+		})
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// merge applies the snapshot files onto the bootstrap file and writes the inventory into the output file
+type merge struct {
+	// bootstrap is the bootstrap file, the inventory of the store is kept if empty
+	bootstrap   string
+	generatedAt time.Time
+	snapshots   []snapshotFile
+	output      string
+	stdout      io.Writer
+}
+
+func (m *merge) run(s inventory.Store) error {
+	inv := inventory.New(s)
+
+	if m.bootstrap != "" {
+		err := applyFile(m.bootstrap, func(r io.Reader) error {
+			n, err := inv.LoadBootstrap(r, m.generatedAt)
+			fmt.Fprintf(m.stdout, "%v: %v items\n", m.bootstrap, n)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, snapshot := range m.snapshots {
+		err := applyFile(snapshot.path, func(r io.Reader) error {
+			changes, err := inv.ApplySnapshot(r, snapshot.hour)
+			if err == inventory.ErrStaleSnapshot {
+				fmt.Fprintf(m.stdout, "%v: skipped, changes already applied\n", snapshot.path)
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(m.stdout, "%v: %v added, %v updated, %v removed\n", snapshot.path, changes.Added, changes.Updated, changes.Removed)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("%v: %v", snapshot.path, err)
+		}
+	}
+
+	if err := writeInventory(inv, m.output); err != nil {
+		return err
+	}
+
+	size, _ := inv.Store.Len()
+	watermark, _ := inv.Watermark()
+	fmt.Fprintf(m.stdout, "%v: %v items, changes applied up to %v\n", m.output, size, watermark.Format(time.RFC3339))
+	return nil
+}
+
+// parseSnapshotFiles reads the hours of the snapshot files and sorts them
+func parseSnapshotFiles(paths []string) ([]snapshotFile, error) {
+	snapshots := make([]snapshotFile, 0, len(paths))
+	for _, path := range paths {
+		m := snapshotHourPattern.FindStringSubmatch(filepath.Base(path))
+		if m == nil {
+			return nil, fmt.Errorf("cannot read the snapshot hour from %v, expected a name ending with yyyymmddhh.tsv.gz", path)
+		}
+
+		hour, err := time.Parse(snapshotHourLayout, m[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid snapshot hour in %v: %v", path, err)
+		}
+		snapshots = append(snapshots, snapshotFile{path: path, hour: hour})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].hour.Before(snapshots[j].hour) })
+	return snapshots, nil
+}
+
+// bootstrapGeneratedAt returns the bootstrap generation time from the flag value, or from the bootstrap manifest if it exists
+func bootstrapGeneratedAt(path, value string) (time.Time, error) {
+	if value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid -generated-at %q, expected format is RFC 3339", value)
+		}
+		return t, nil
+	}
+
+	data, err := ioutil.ReadFile(path + manifestSuffix)
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot read bootstrap manifest: %v", err)
+	}
+
+	m := &manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return time.Time{}, fmt.Errorf("cannot decode bootstrap manifest: %v", err)
+	}
+	return m.LastModified, nil
+}
+
+// applyFile opens the file and passes it to fn
+func applyFile(path string, fn func(r io.Reader) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("applyFile(): cannot open %v: %v", path, err)
+	}
+	defer f.Close()
+
+	return fn(f)
+}
+
+// writeInventory writes the inventory feed into a temporary file renamed to output once completed
+func writeInventory(inv *inventory.Inventory, output string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(output), filepath.Base(output)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("writeInventory(): cannot create output file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := inv.WriteFeed(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writeInventory(): cannot write output file: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), output); err != nil {
+		return fmt.Errorf("writeInventory(): cannot move output file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"ebay-api-client/ebay"
+	"ebay-api-client/ebay/ebaytest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+// writeFeedFile writes a gzipped feed with the Item columns and the given rows
+func writeFeedFile(t *testing.T, path string, items ...*ebay.Item) {
+	assert.NilError(t, ioutil.WriteFile(path, ebaytest.ItemFeed(items...), 0644))
+}
+
+func Test_IsMergeApplyingSnapshots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ebayfeed")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	bootstrap := filepath.Join(dir, "bootstrap_20200505.tsv.gz")
+	writeFeedFile(t, bootstrap, testItems...)
+	assert.NilError(t, writeManifest(bootstrap+manifestSuffix, &manifest{LastModified: time.Date(2020, 5, 6, 5, 20, 0, 0, time.UTC)}))
+
+	stale := filepath.Join(dir, "snapshot_2020050604.tsv.gz")
+	writeFeedFile(t, stale, &ebay.Item{ID: "v1|1|0"})
+
+	first := filepath.Join(dir, "snapshot_2020050605.tsv.gz")
+	writeFeedFile(t, first, &ebay.Item{ID: "v1|2|0", Availability: "UNAVAILABLE"})
+
+	second := filepath.Join(dir, "snapshot_2020050606.tsv.gz")
+	writeFeedFile(t, second, &ebay.Item{ID: "v1|4|0", Title: "Tablet", PriceValue: "99"})
+
+	output := filepath.Join(dir, "inventory.tsv.gz")
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	code := run([]string{"merge", "-bootstrap", bootstrap, "-o", output, second, stale, first}, stdout, stderr)
+	assert.Equal(t, code, exitOK, stderr.String())
+	assert.Assert(t, strings.Contains(stdout.String(), stale+": skipped"), stdout.String())
+	assert.Assert(t, strings.Contains(stdout.String(), "3 items, changes applied up to 2020-05-06T07:00:00Z"), stdout.String())
+
+	convert := new(bytes.Buffer)
+	assert.Equal(t, run([]string{"convert", "-format", "tsv", "-fields", "ID", output}, convert, stderr), exitOK)
+	assert.Equal(t, convert.String(), "ID\nv1|1|0\nv1|3|0\nv1|4|0\n")
+
+	// The 2020050606 snapshot is missing
+	gap := filepath.Join(dir, "snapshot_2020050607.tsv.gz")
+	writeFeedFile(t, gap, &ebay.Item{ID: "v1|5|0", Title: "Mouse"})
+
+	stderr.Reset()
+	assert.Equal(t, run([]string{"merge", "-bootstrap", bootstrap, "-o", output, first, gap}, stdout, stderr), exitError)
+	assert.Assert(t, strings.Contains(stderr.String(), "snapshot does not follow the inventory watermark"), stderr.String())
+
+	assert.Equal(t, run([]string{"merge", "-bootstrap", bootstrap, "snapshot.tsv.gz"}, stdout, stderr), exitUsage)
+	assert.Equal(t, run([]string{"merge", first}, stdout, stderr), exitUsage)
+	assert.Assert(t, strings.Contains(stderr.String(), "-bootstrap is required without -db"), stderr.String())
+}
+
+func Test_IsMergeResumingFromStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ebayfeed")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	bootstrap := filepath.Join(dir, "bootstrap_20200505.tsv.gz")
+	writeFeedFile(t, bootstrap, testItems...)
+	first := filepath.Join(dir, "snapshot_2020050605.tsv.gz")
+	writeFeedFile(t, first, &ebay.Item{ID: "v1|2|0", Availability: "UNAVAILABLE"})
+	second := filepath.Join(dir, "snapshot_2020050606.tsv.gz")
+	writeFeedFile(t, second, &ebay.Item{ID: "v1|4|0", Title: "Tablet", PriceValue: "99"})
+
+	dbPath := filepath.Join(dir, "items.db")
+	output := filepath.Join(dir, "inventory.tsv.gz")
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	code := run([]string{"merge", "-db", dbPath, "-bootstrap", bootstrap, "-generated-at", "2020-05-06T05:20:00Z", "-o", output, first}, stdout, stderr)
+	assert.Equal(t, code, exitOK, stderr.String())
+	assert.Assert(t, strings.Contains(stdout.String(), "2 items, changes applied up to 2020-05-06T06:00:00Z"), stdout.String())
+
+	// The next run applies the new snapshots onto the inventory of the store
+	stdout.Reset()
+	code = run([]string{"merge", "-db", dbPath, "-o", output, first, second}, stdout, stderr)
+	assert.Equal(t, code, exitOK, stderr.String())
+	assert.Assert(t, strings.Contains(stdout.String(), first+": skipped"), stdout.String())
+	assert.Assert(t, strings.Contains(stdout.String(), "3 items, changes applied up to 2020-05-06T07:00:00Z"), stdout.String())
+
+	convert := new(bytes.Buffer)
+	assert.Equal(t, run([]string{"convert", "-format", "tsv", "-fields", "ID", output}, convert, stderr), exitOK)
+	assert.Equal(t, convert.String(), "ID\nv1|1|0\nv1|3|0\nv1|4|0\n")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"ebay-api-client/config"
+	"ebay-api-client/mirror"
+	"ebay-api-client/oauth2"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+)
+
+func runMirror(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("mirror", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	once := fs.Bool("once", false, "synchronize the mirror once and exit instead of running until interrupted")
+	env := fs.String("env", "", "eBay environment, sandbox or production (overrides "+config.EnvName+")")
+	configPath := fs.String("config", "", "environment configuration file (json, yaml or toml)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ebayfeed mirror [flags] <mirror.yaml>")
+		fmt.Fprintln(fs.Output(), "The mirror file (json, yaml or toml) lists the dir, the optional state_file and the feeds to mirror")
+		fmt.Fprintln(fs.Output(), "as market_id, category_ids and optional kinds (bootstrap, daily, snapshot).")
+		fs.PrintDefaults()
+	}
+
+	path, code, ok := parseFileFlags(fs, args)
+	if !ok {
+		return code
+	}
+
+	conf, err := mirror.LoadConfig(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitUsage
+	}
+
+	// An interrupt stops the synchronization in both modes, the partial downloads are removed by the mirror
+	ctx, stop := interruptContext()
+	defer stop()
+
+	e, err := loadEnvironment(*configPath, *env)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitUsage
+	}
+
+	httpClient, err := e.NewClientCredentialsClient(ctx, []string{oauth2.ScopeBuyFeedAPI})
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: cannot create the HTTP client: %v\n", err)
+		return exitAuth
+	}
+
+	service, err := e.NewFeedService(httpClient)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitAuth
+	}
+
+	m := mirror.New(service, conf)
+	m.Logger = log.New(stderr, "mirror: ", log.LstdFlags)
+
+	if *once {
+		if err := m.Sync(ctx); err != nil {
+			fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+			return exitCode(err)
+		}
+		return exitOK
+	}
+
+	if err := m.Run(ctx); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitUsage
+	}
+	return exitOK
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"ebay-api-client/export"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func runParquet(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("parquet", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	typed := fs.Bool("typed", false, "write typed columns: decimal prices, timestamp dates, repeated lists and aspects")
+	rowGroup := fs.Int64("row-group", export.DefaultRowGroupSize>>20, "MB of each row group, buffered in memory")
+	compression := fs.String("compression", export.DefaultCompression, "page compression: uncompressed, snappy, gzip or zstd")
+	output := fs.String("o", "-", "output file, - for the standard output")
+	workers := workersFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ebayfeed parquet [flags] <feed.tsv.gz>")
+		fs.PrintDefaults()
+	}
+
+	path, code, ok := parseFileFlags(fs, args)
+	if !ok {
+		return code
+	}
+
+	r, closeFeed, err := openFeed(path, *workers)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitError
+	}
+	defer closeFeed()
+
+	if strings.HasSuffix(*output, ".gz") {
+		fmt.Fprintf(stderr, "parquet: the output %v cannot be gzipped, use -compression\n", *output)
+		return exitUsage
+	}
+	out, closeOutput, err := createOutput(*output, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitError
+	}
+
+	w, err := export.NewParquetWriter(out, &export.ParquetOptions{Typed: *typed, RowGroupSize: *rowGroup << 20, Compression: *compression})
+	if err != nil {
+		closeOutput()
+		fmt.Fprintf(stderr, "parquet: %v\n", err)
+		return exitUsage
+	}
+
+	written, err := export.Copy(w, r)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		closeOutput()
+		fmt.Fprintf(stderr, "ebayfeed: cannot write output: %v\n", err)
+		return exitError
+	}
+
+	if err := closeOutput(); err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: cannot write output: %v\n", err)
+		return exitError
+	}
+
+	fmt.Fprintf(stderr, "%v items written\n", written)
+	return exitOK
+}
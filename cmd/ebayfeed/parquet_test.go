@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"ebay-api-client/export"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+	"gotest.tools/v3/assert"
+)
+
+func Test_IsParquetConvertingFeed(t *testing.T) {
+	path, cleanup := newTestFeedFile(t, testItems...)
+	defer cleanup()
+
+	output := filepath.Join(filepath.Dir(path), "items.parquet")
+	stderr := new(bytes.Buffer)
+	code := run([]string{"parquet", "-typed", "-compression", "gzip", "-o", output, path}, new(bytes.Buffer), stderr)
+	assert.Equal(t, code, exitOK, stderr.String())
+	assert.Equal(t, stderr.String(), "3 items written\n")
+
+	data, err := ioutil.ReadFile(output)
+	assert.NilError(t, err)
+	file, err := buffer.NewBufferFile(data)
+	assert.NilError(t, err)
+	pr, err := reader.NewParquetReader(file, new(export.TypedItem), 1)
+	assert.NilError(t, err)
+	defer pr.ReadStop()
+
+	items := make([]export.TypedItem, 3)
+	assert.NilError(t, pr.Read(&items))
+	assert.Equal(t, items[2].ID, "v1|3|0")
+	assert.Equal(t, *items[2].PriceValue, int64(35000))
+
+	assert.Equal(t, run([]string{"parquet", "-compression", "lzo", path}, new(bytes.Buffer), new(bytes.Buffer)), exitUsage)
+	assert.Equal(t, run([]string{"parquet", "-o", output + ".gz", path}, new(bytes.Buffer), new(bytes.Buffer)), exitUsage)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"ebay-api-client/filter"
+	"ebay-api-client/sink"
+	"flag"
+	"fmt"
+	"io"
+)
+
+func runPublish(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("publish", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	sinksPath := fs.String("sinks", "", "sinks configuration file (json, yaml or toml, required)")
+	expr := fs.String("filter", "", "filter expression, i.e. CategoryID = 9355 AND PriceValue < 100")
+	workers := workersFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ebayfeed publish [flags] <feed.tsv.gz>")
+		fmt.Fprintln(fs.Output(), "The sinks file lists the batch_size, flush_interval, buffer_size and the sinks to publish to,")
+		fmt.Fprintln(fs.Output(), "as file (path, format) or http (url, headers) types with optional fields, typed and gzip.")
+		fs.PrintDefaults()
+	}
+
+	path, code, ok := parseFileFlags(fs, args)
+	if !ok {
+		return code
+	}
+	if *sinksPath == "" {
+		fmt.Fprintln(stderr, "publish: -sinks is required")
+		fs.Usage()
+		return exitUsage
+	}
+
+	conf, err := sink.LoadConfig(*sinksPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitUsage
+	}
+	p, err := filter.Parse(*expr)
+	if err != nil {
+		fmt.Fprintf(stderr, "publish: invalid -filter: %v\n", err)
+		return exitUsage
+	}
+
+	r, closeFeed, err := openFeed(path, *workers)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitError
+	}
+	defer closeFeed()
+
+	s, err := conf.Open(stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitError
+	}
+
+	b := sink.NewBatcher(context.Background(), s, conf.Options())
+	published, err := sink.Copy(b, filter.NewReader(r, p))
+	if cerr := b.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: cannot publish: %v\n", err)
+		return exitError
+	}
+
+	fmt.Fprintf(stderr, "%v items published\n", published)
+	return exitOK
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func Test_IsPublishWritingToSinks(t *testing.T) {
+	path, cleanup := newTestFeedFile(t, testItems...)
+	defer cleanup()
+
+	dir := filepath.Dir(path)
+	output := filepath.Join(dir, "phones.csv")
+	sinks := filepath.Join(dir, "sinks.json")
+	assert.NilError(t, ioutil.WriteFile(sinks, []byte(`{"batch_size": 2, "sinks": [
+		{"type": "file", "path": "`+output+`", "format": "csv", "fields": ["ItemID", "PriceValue"]},
+		{"type": "file", "path": "-", "fields": ["ItemID"]}
+	]}`), 0644))
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	code := run([]string{"publish", "-sinks", sinks, "-filter", "CategoryID = 9355", path}, stdout, stderr)
+	assert.Equal(t, code, exitOK, stderr.String())
+	assert.Equal(t, stderr.String(), "2 items published\n")
+	assert.Equal(t, stdout.String(), "{\"itemId\":\"v1|1|0\"}\n{\"itemId\":\"v1|2|0\"}\n")
+
+	data, err := ioutil.ReadFile(output)
+	assert.NilError(t, err)
+	assert.Equal(t, string(data), "ID,PriceValue\nv1|1|0,9.5\nv1|2|0,12\n")
+
+	assert.Equal(t, run([]string{"publish", path}, new(bytes.Buffer), new(bytes.Buffer)), exitUsage)
+	assert.Equal(t, run([]string{"publish", "-sinks", sinks, "-filter", "Title =", path}, new(bytes.Buffer), new(bytes.Buffer)), exitUsage)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"ebay-api-client/ebay"
+	"ebay-api-client/server"
+	"ebay-api-client/store"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// shutdownTimeout is how long serve waits for the pending requests when interrupted
+const shutdownTimeout = 10 * time.Second
+
+func runLoad(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("load", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dbPath := fs.String("db", "items.db", "item store file")
+	upsert := fs.Bool("upsert", false, "upsert the first file too instead of replacing the content of the store")
+	workers := workersFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ebayfeed load [flags] <bootstrap.tsv.gz> [<snapshot.tsv.gz>...]")
+		fmt.Fprintln(fs.Output(), "The first file replaces the content of the store, unless -upsert is given, the next ones are upserted.")
+		fmt.Fprintln(fs.Output(), "The feed type, market and category are read from the manifests of the files if they exist.")
+		fmt.Fprintln(fs.Output(), "The store is replaced once all the files are loaded, it can be served meanwhile.")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	// The store is updated on a copy, so that a serve process reading it is not blocked
+	err := store.Update(*dbPath, func(db *store.DB) error {
+		for n, path := range fs.Args() {
+			info, err := manifestFeedInfo(path)
+			if err != nil {
+				return fmt.Errorf("%v: %v", path, err)
+			}
+
+			r, closeFeed, err := openFeed(path, *workers)
+			if err != nil {
+				return err
+			}
+
+			var batch *store.Batch
+			if n == 0 && !*upsert {
+				batch, err = db.Load(r, info)
+			} else {
+				batch, err = db.Upsert(r, info)
+			}
+			closeFeed()
+			if err != nil {
+				return fmt.Errorf("%v: %v", path, err)
+			}
+			fmt.Fprintf(stdout, "%v: %v items put, %v deleted\n", path, batch.Put, batch.Deleted)
+		}
+
+		size, err := db.Len()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%v: %v items\n", *dbPath, size)
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// manifestFeedInfo returns the FeedInfo of the feed file from its manifest, nil if the manifest does not exist
+func manifestFeedInfo(path string) (*ebay.FeedInfo, error) {
+	data, err := ioutil.ReadFile(path + manifestSuffix)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read manifest: %v", err)
+	}
+
+	m := &manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("cannot decode manifest of %v: %v", path, err)
+	}
+	return &ebay.FeedInfo{
+		Type:         m.Type,
+		CategoryID:   m.CategoryID,
+		MarketID:     m.MarketID,
+		Scope:        m.Scope,
+		LastModified: m.LastModified,
+		Size:         m.Size,
+	}, nil
+}
+
+func runServe(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dbPath := fs.String("db", "items.db", "item store file, filled with the load command")
+	addr := fs.String("addr", ":8080", "listening address")
+	reload := fs.Duration("reload", 10*time.Second, "interval of the checks for a store replaced by the load command")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ebayfeed serve [flags]")
+		fmt.Fprintln(fs.Output(), "Serves the items of the store as JSON: GET /items/{id}, GET /items and GET /categories (see package server).")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	db, err := store.OpenReloader(*dbPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitError
+	}
+	defer db.Close()
+
+	logger := log.New(stderr, "serve: ", log.LstdFlags)
+	done := make(chan struct{})
+	defer close(done)
+	go reloadStore(db, *reload, logger, done)
+	handler := server.New(db)
+	handler.Logger = logger
+	srv := &http.Server{Addr: *addr, Handler: handler, ErrorLog: logger}
+
+	interrupted, stop := interruptContext()
+	defer stop()
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-interrupted.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			logger.Printf("cannot shut down: %v", err)
+		}
+	}()
+
+	logger.Printf("listening on %v", *addr)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitError
+	}
+	<-stopped
+	return exitOK
+}
+
+// reloadStore reopens the store when it is replaced, checking every interval until done is closed
+func reloadStore(db *store.Reloader, interval time.Duration, logger *log.Logger, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			reloaded, err := db.Reload()
+			if err != nil {
+				logger.Printf("cannot reload store: %v", err)
+			} else if reloaded {
+				logger.Printf("store reloaded")
+			}
+		case <-done:
+			return
+		}
+	}
+}
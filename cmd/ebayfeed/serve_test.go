@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"ebay-api-client/ebay"
+	"ebay-api-client/store"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func Test_IsLoadFillingStore(t *testing.T) {
+	bootstrap, cleanup := newTestFeedFile(t, testItems...)
+	defer cleanup()
+
+	dir := filepath.Dir(bootstrap)
+	snapshot := filepath.Join(dir, "snapshot.tsv.gz")
+	writeFeedFile(t, snapshot, &ebay.Item{ID: "v1|2|0", Availability: "UNAVAILABLE"}, &ebay.Item{ID: "v1|4|0", CategoryID: "177"})
+	lastModified := time.Date(2020, 5, 6, 5, 0, 0, 0, time.UTC)
+	assert.NilError(t, writeManifest(snapshot+manifestSuffix, &manifest{Type: "snapshot", MarketID: "EBAY_US", CategoryID: "9355", LastModified: lastModified}))
+
+	dbPath := filepath.Join(dir, "items.db")
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	code := run([]string{"load", "-db", dbPath, bootstrap, snapshot}, stdout, stderr)
+	assert.Equal(t, code, exitOK, stderr.String())
+	assert.Equal(t, stdout.String(), bootstrap+": 3 items put, 0 deleted\n"+snapshot+": 1 items put, 1 deleted\n"+dbPath+": 3 items\n")
+
+	db, err := store.Open(dbPath)
+	assert.NilError(t, err)
+	defer db.Close()
+
+	batches, err := db.Batches()
+	assert.NilError(t, err)
+	assert.Equal(t, len(batches), 2)
+	assert.Equal(t, batches[0].Mode, store.ModeLoad)
+	assert.DeepEqual(t, batches[1].Info, ebay.FeedInfo{Type: "snapshot", MarketID: "EBAY_US", CategoryID: "9355", LastModified: lastModified})
+
+	counts, err := db.Counts("CategoryID")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, counts, map[string]int{"9355": 1, "177": 2})
+
+	// The store is locked while used
+	assert.Equal(t, run([]string{"load", "-db", dbPath, bootstrap}, new(bytes.Buffer), new(bytes.Buffer)), exitError)
+	assert.Equal(t, run([]string{"serve", "-db", dbPath}, new(bytes.Buffer), new(bytes.Buffer)), exitError)
+	assert.Equal(t, run([]string{"load", "-db", dbPath}, new(bytes.Buffer), new(bytes.Buffer)), exitUsage)
+	assert.Equal(t, run([]string{"serve", "extra"}, new(bytes.Buffer), new(bytes.Buffer)), exitUsage)
+}
+
+func Test_IsLoadUpdatingServedStore(t *testing.T) {
+	bootstrap, cleanup := newTestFeedFile(t, testItems...)
+	defer cleanup()
+
+	dbPath := filepath.Join(filepath.Dir(bootstrap), "items.db")
+	assert.Equal(t, run([]string{"load", "-db", dbPath, bootstrap}, new(bytes.Buffer), new(bytes.Buffer)), exitOK)
+
+	// The served store is read only, the load command replaces it
+	served, err := store.OpenReloader(dbPath)
+	assert.NilError(t, err)
+	defer served.Close()
+
+	snapshot := filepath.Join(filepath.Dir(bootstrap), "snapshot.tsv.gz")
+	writeFeedFile(t, snapshot, &ebay.Item{ID: "v1|4|0", Title: "Tablet", CategoryID: "177"})
+	stderr := new(bytes.Buffer)
+	assert.Equal(t, run([]string{"load", "-db", dbPath, "-upsert", snapshot}, new(bytes.Buffer), stderr), exitOK, stderr.String())
+
+	reloaded, err := served.Reload()
+	assert.NilError(t, err)
+	assert.Assert(t, reloaded)
+	_, ok, err := served.Item("v1|4|0")
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+}
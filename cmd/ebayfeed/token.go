@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"ebay-api-client/config"
+	"ebay-api-client/ebay"
+	"ebay-api-client/oauth2"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	xoauth2 "golang.org/x/oauth2"
+)
+
+const (
+	// envAccessToken is the os variable name the token is exported to with -format env
+	envAccessToken string = "EBAY_ACCESS_TOKEN"
+	// envAccessTokenExpiry is the os variable name the token expiry is exported to with -format env
+	envAccessTokenExpiry string = "EBAY_ACCESS_TOKEN_EXPIRY"
+)
+
+// errCheckDone stops the check download once the first byte of the feed is received
+var errCheckDone = errors.New("check done")
+
+// tokenOutput is the token printed by the token command
+type tokenOutput struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type"`
+	Expiry      time.Time `json:"expiry"`
+	Scopes      []string  `json:"scopes"`
+}
+
+func runToken(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("token", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var scopes stringsFlag
+	fs.Var(&scopes, "scope", "oAuth2 scope to request (repeatable), defaults to "+oauth2.ScopeBuyFeedAPI)
+	format := fs.String("format", "text", "output format: text, env or json")
+	check := fs.Bool("check", false, "verify the token downloading the first byte of a Feed API feed")
+	market := fs.String("market", "EBAY_US", "eBay marketplace id of the check feed")
+	category := fs.String("category", "1", "eBay category id of the check feed")
+	env := fs.String("env", "", "eBay environment, sandbox or production (overrides "+config.EnvName+")")
+	configPath := fs.String("config", "", "environment configuration file (json, yaml or toml)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ebayfeed token [flags]")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "token: unexpected arguments %v\n", fs.Args())
+		return exitUsage
+	}
+	if *format != "text" && *format != "env" && *format != "json" {
+		fmt.Fprintf(stderr, "token: unsupported format %q\n", *format)
+		return exitUsage
+	}
+	if len(scopes) == 0 {
+		scopes = stringsFlag{oauth2.ScopeBuyFeedAPI}
+	}
+
+	ctx := context.Background()
+
+	e, err := loadEnvironment(*configPath, *env)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitUsage
+	}
+
+	httpClient, err := e.NewClientCredentialsClient(ctx, scopes)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: cannot create the HTTP client: %v\n", err)
+		return exitAuth
+	}
+
+	transport, ok := httpClient.Transport.(*oauth2.Transport)
+	if !ok {
+		fmt.Fprintf(stderr, "ebayfeed: unexpected HTTP client transport %T\n", httpClient.Transport)
+		return exitError
+	}
+
+	tk, err := transport.Source.Token()
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", describeTokenError(err))
+		return exitAuth
+	}
+
+	out := &tokenOutput{
+		AccessToken: tk.AccessToken,
+		TokenType:   tk.TokenType,
+		Expiry:      tk.Expiry.UTC(),
+		Scopes:      scopes,
+	}
+	if err := printToken(stdout, *format, out); err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: cannot write token: %v\n", err)
+		return exitError
+	}
+
+	if !*check {
+		return exitOK
+	}
+
+	service, err := e.NewFeedService(httpClient)
+	if err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: %v\n", err)
+		return exitAuth
+	}
+
+	if err := checkToken(ctx, service, *market, *category); err != nil {
+		fmt.Fprintf(stderr, "ebayfeed: token check failed: %v\n", err)
+		return exitCode(err)
+	}
+
+	fmt.Fprintln(stderr, "token check: ok")
+	return exitOK
+}
+
+func printToken(w io.Writer, format string, t *tokenOutput) error {
+	var err error
+	switch format {
+	case "env":
+		_, err = fmt.Fprintf(w, "export %v=%v\nexport %v=%v\n", envAccessToken, shellQuote(t.AccessToken), envAccessTokenExpiry, shellQuote(t.Expiry.Format(time.RFC3339)))
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(t)
+	default:
+		_, err = fmt.Fprintf(w, "access token: %v\ntoken type:   %v\nexpiry:       %v (in %v)\nscopes:       %v\n",
+			t.AccessToken, t.TokenType, t.Expiry.Format(time.RFC3339), time.Until(t.Expiry).Round(time.Second), strings.Join(t.Scopes, " "))
+	}
+	return err
+}
+
+// shellQuote single-quotes the value for a POSIX shell, the single quotes of the value are closed, escaped and reopened
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// checkToken calls the Feed API downloading only the first byte of the daily newly listed items feed of yesterday.
+// An empty feed is fine, what matters is that the token is accepted.
+func checkToken(ctx context.Context, service *ebay.FeedService, market, category string) error {
+	s := *service
+	s.ChunkSize = 1
+
+	w := &abortWriter{}
+	_, err := s.DailyNewlyItems(ctx, market, category, time.Now().UTC().AddDate(0, 0, -1), w)
+	if err == nil || w.aborted || ebay.IsNoContent(err) {
+		return nil
+	}
+	return err
+}
+
+// abortWriter fails the first write, stopping the download after the first chunk
+type abortWriter struct {
+	aborted bool
+}
+
+func (a *abortWriter) Write(b []byte) (int, error) {
+	a.aborted = true
+	return 0, errCheckDone
+}
+
+// describeTokenError formats the oAuth2 error payload returned by eBay when the token cannot be minted
+func describeTokenError(err error) string {
+	var retrieveErr *xoauth2.RetrieveError
+	if !errors.As(err, &retrieveErr) || retrieveErr.Response == nil {
+		return fmt.Sprintf("cannot get token: %v", err)
+	}
+
+	payload := struct {
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}{}
+	if json.Unmarshal(retrieveErr.Body, &payload) != nil || payload.Error == "" {
+		return fmt.Sprintf("cannot get token: %v %v", retrieveErr.Response.Status, strings.TrimSpace(string(retrieveErr.Body)))
+	}
+
+	msg := fmt.Sprintf("cannot get token: %v: %v", retrieveErr.Response.Status, payload.Error)
+	if payload.Description != "" {
+		msg += ": " + payload.Description
+	}
+	if retrieveErr.Response.StatusCode == http.StatusUnauthorized {
+		msg += " (check " + oauth2.APIClientID + " and " + oauth2.APIClientSecret + ")"
+	}
+	return msg
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"ebay-api-client/ebay/ebaytest"
+	"ebay-api-client/oauth2"
+	"ebay-api-client/oauth2/oauth2test"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func Test_IsTokenPrinted(t *testing.T) {
+	tokens, _, _, shutdown := newTestEnvironment(t)
+	defer shutdown()
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	assert.Equal(t, run([]string{"token", "-format", "json"}, stdout, stderr), exitOK, stderr.String())
+
+	out := &tokenOutput{}
+	assert.NilError(t, json.Unmarshal(stdout.Bytes(), out))
+	assert.DeepEqual(t, out.Scopes, []string{oauth2.ScopeBuyFeedAPI})
+	assert.Assert(t, out.Expiry.After(time.Now()))
+
+	tk, ok := tokens.Lookup(out.AccessToken)
+	assert.Assert(t, ok)
+	assert.Equal(t, tk.ClientID, "TEST-SBX-ID")
+
+	stdout.Reset()
+	assert.Equal(t, run([]string{"token", "-format", "env"}, stdout, stderr), exitOK, stderr.String())
+	assert.Assert(t, strings.HasPrefix(stdout.String(), "export "+envAccessToken+"='v^1.1#"), stdout.String())
+	assert.Assert(t, strings.HasSuffix(stdout.String(), "'\n"), stdout.String())
+
+	stdout.Reset()
+	assert.Equal(t, run([]string{"token"}, stdout, stderr), exitOK, stderr.String())
+	assert.Assert(t, strings.Contains(stdout.String(), "scopes:       "+oauth2.ScopeBuyFeedAPI), stdout.String())
+
+	assert.Equal(t, run([]string{"token", "-format", "xml"}, stdout, stderr), exitUsage)
+}
+
+func Test_IsShellQuoteEscapingSingleQuotes(t *testing.T) {
+	assert.Equal(t, shellQuote("v^1.1#i^1"), "'v^1.1#i^1'")
+	assert.Equal(t, shellQuote("it's"), `'it'\''s'`)
+	assert.Equal(t, shellQuote(""), "''")
+}
+
+func Test_IsTokenErrorReported(t *testing.T) {
+	tokens, _, _, shutdown := newTestEnvironment(t)
+	defer shutdown()
+
+	tokens.InjectError(oauth2test.OAuthError{
+		Status:      http.StatusUnauthorized,
+		Code:        oauth2test.ErrInvalidClient,
+		Description: "client authentication failed",
+	})
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	assert.Equal(t, run([]string{"token"}, stdout, stderr), exitAuth)
+	assert.Equal(t, stdout.Len(), 0)
+	assert.Assert(t, strings.Contains(stderr.String(), "401 Unauthorized: invalid_client: client authentication failed"), stderr.String())
+
+	stderr.Reset()
+	assert.Equal(t, run([]string{"token", "-scope", "https://api.ebay.com/oauth/api_scope/unknown"}, stdout, stderr), exitAuth)
+	assert.Assert(t, strings.Contains(stderr.String(), oauth2test.ErrInvalidScope), stderr.String())
+}
+
+func Test_IsTokenChecked(t *testing.T) {
+	_, feeds, _, shutdown := newTestEnvironment(t)
+	defer shutdown()
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	assert.Equal(t, run([]string{"token", "-check"}, stdout, stderr), exitOK, stderr.String())
+	assert.Assert(t, strings.Contains(stderr.String(), "token check: ok"))
+
+	feeds.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItem, Data: ebaytest.GzipTSV([]string{"ItemId"}, []string{"v1|1|0"})})
+	stderr.Reset()
+	assert.Equal(t, run([]string{"token", "-check"}, stdout, stderr), exitOK, stderr.String())
+	assert.Assert(t, strings.Contains(stderr.String(), "token check: ok"))
+
+	feeds.SetAuthorizer(func(r *http.Request) bool { return false })
+	stderr.Reset()
+	assert.Equal(t, run([]string{"token", "-check"}, stdout, stderr), exitAuth)
+	assert.Assert(t, strings.Contains(stderr.String(), "token check failed"), stderr.String())
+}
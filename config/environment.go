@@ -0,0 +1,321 @@
+// Package config provides the configuration of the eBay environment (sandbox or production) the clients point to
+package config
+
+import (
+	"context"
+	"ebay-api-client/ebay"
+	"ebay-api-client/oauth2"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Sandbox is the name of eBay sandbox environment
+	Sandbox string = "sandbox"
+	// Production is the name of eBay production environment
+	Production string = "production"
+)
+
+const (
+	// EnvName is the os variable name overriding the environment name
+	EnvName string = "EBAY_ENVIRONMENT"
+	// EnvFeedBaseURL is the os variable name overriding the Feed API base URL
+	EnvFeedBaseURL string = "EBAY_FEED_BASE_URL"
+	// EnvFeedChunkSize is the os variable name overriding the Feed API download chunk size
+	EnvFeedChunkSize string = "EBAY_FEED_CHUNK_SIZE"
+	// EnvAnalyticsBaseURL is the os variable name overriding the Developer Analytics API base URL
+	EnvAnalyticsBaseURL string = "EBAY_ANALYTICS_BASE_URL"
+	// EnvAuthURL is the os variable name overriding the oAuth2 authorization URL
+	EnvAuthURL string = "EBAY_OAUTH_AUTH_URL"
+	// EnvTokenURL is the os variable name overriding the oAuth2 token URL
+	EnvTokenURL string = "EBAY_OAUTH_TOKEN_URL"
+)
+
+const (
+	sandboxHostSuffix string = ".sandbox.ebay.com"
+	ebayHostSuffix    string = ".ebay.com"
+
+	// eBay application keys embed the environment they belong to
+	sandboxKeyMarker string = "-SBX-"
+	prodKeyMarker    string = "-PRD-"
+)
+
+// Environment bundles everything needed to talk to one eBay environment:
+// API base URLs, oAuth2 endpoints, download limits and application credentials.
+type Environment struct {
+	// Name is the environment name, either Sandbox or Production
+	Name string `json:"name" yaml:"name" toml:"name"`
+	// FeedBaseURL is the Feed API base URL
+	FeedBaseURL string `json:"feed_base_url" yaml:"feed_base_url" toml:"feed_base_url"`
+	// FeedVersion is the Feed API version
+	FeedVersion string `json:"feed_version" yaml:"feed_version" toml:"feed_version"`
+	// FeedChunkSize is the size of the chunk used to download the feeds
+	FeedChunkSize int64 `json:"feed_chunk_size" yaml:"feed_chunk_size" toml:"feed_chunk_size"`
+	// AnalyticsBaseURL is the Developer Analytics API base URL
+	AnalyticsBaseURL string `json:"analytics_base_url" yaml:"analytics_base_url" toml:"analytics_base_url"`
+	// AnalyticsVersion is the Developer Analytics API version
+	AnalyticsVersion string `json:"analytics_version" yaml:"analytics_version" toml:"analytics_version"`
+	// AuthURL is the oAuth2 authorization URL
+	AuthURL string `json:"auth_url" yaml:"auth_url" toml:"auth_url"`
+	// TokenURL is the oAuth2 token URL
+	TokenURL string `json:"token_url" yaml:"token_url" toml:"token_url"`
+	// ClientID is the eBay application client id
+	ClientID string `json:"client_id" yaml:"client_id" toml:"client_id"`
+	// ClientSecret is the eBay application client secret
+	ClientSecret string `json:"client_secret" yaml:"client_secret" toml:"client_secret"`
+}
+
+// NewSandboxEnvironment creates a new Environment with the default eBay sandbox settings.
+func NewSandboxEnvironment() *Environment {
+	return &Environment{
+		Name:             Sandbox,
+		FeedBaseURL:      ebay.DefaultSandboxBaseURL,
+		FeedVersion:      ebay.DefaultAPIVersion,
+		FeedChunkSize:    ebay.DefaultSandboxMaxChunkSize,
+		AnalyticsBaseURL: ebay.DefaultSandboxAnalyticsBaseURL,
+		AnalyticsVersion: ebay.DefaultAnalyticsAPIVersion,
+		AuthURL:          oauth2.SandBoxEndpoint.AuthURL,
+		TokenURL:         oauth2.SandBoxEndpoint.TokenURL,
+	}
+}
+
+// NewProdEnvironment creates a new Environment with the default eBay production settings.
+func NewProdEnvironment() *Environment {
+	return &Environment{
+		Name:             Production,
+		FeedBaseURL:      ebay.DefaultProdBaseURL,
+		FeedVersion:      ebay.DefaultAPIVersion,
+		FeedChunkSize:    ebay.DefaultProdMaxChunkSize,
+		AnalyticsBaseURL: ebay.DefaultProdAnalyticsBaseURL,
+		AnalyticsVersion: ebay.DefaultAnalyticsAPIVersion,
+		AuthURL:          oauth2.ProdEndpoint.AuthURL,
+		TokenURL:         oauth2.ProdEndpoint.TokenURL,
+	}
+}
+
+// NewEnvironment creates a new Environment with the default settings of the environment with the given name.
+func NewEnvironment(name string) (*Environment, error) {
+	switch strings.ToLower(name) {
+	case Sandbox:
+		return NewSandboxEnvironment(), nil
+	case Production:
+		return NewProdEnvironment(), nil
+	}
+	return nil, fmt.Errorf("NewEnvironment(): unknown environment %q", name)
+}
+
+// FromEnv creates a new Environment from the os environment variables only.
+// The environment is the given name if not empty, otherwise EnvName, and defaults to Sandbox when neither is set.
+func FromEnv(name string) (*Environment, error) {
+	name = environmentName(name, "")
+
+	env, err := NewEnvironment(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := env.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := env.Validate(); err != nil {
+		return nil, err
+	}
+
+	return env, nil
+}
+
+// environmentName returns the first name set among the given one, EnvName and the one of the configuration file,
+// Sandbox if none is set
+func environmentName(name, fileName string) string {
+	if name != "" {
+		return name
+	}
+	if name := os.Getenv(EnvName); name != "" {
+		return name
+	}
+	if fileName != "" {
+		return fileName
+	}
+	return Sandbox
+}
+
+// Load creates a new Environment from the given configuration file.
+// The file format is detected from its extension: .json, .yaml, .yml and .toml are supported.
+// The environment is the given name if not empty, otherwise EnvName, otherwise the name in the file (Sandbox if missing).
+// Settings not given in the file take the default values of the environment, then the os environment variables
+// override the file settings. The result is validated before being returned.
+func Load(path, name string) (*Environment, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Load(): cannot read configuration file: %v", err)
+	}
+
+	// The environment name decides the defaults, so it is read before the rest of the file
+	named := &Environment{}
+	if err := Unmarshal(path, data, named); err != nil {
+		return nil, fmt.Errorf("Load(): %v", err)
+	}
+
+	name = environmentName(name, named.Name)
+
+	env, err := NewEnvironment(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Unmarshal(path, data, env); err != nil {
+		return nil, fmt.Errorf("Load(): %v", err)
+	}
+	env.Name = strings.ToLower(name)
+
+	if err := env.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := env.Validate(); err != nil {
+		return nil, err
+	}
+
+	return env, nil
+}
+
+// applyEnv overrides the environment settings with the ones set in the os environment variables
+func (e *Environment) applyEnv() error {
+	overrides := map[string]*string{
+		EnvFeedBaseURL:         &e.FeedBaseURL,
+		EnvAnalyticsBaseURL:    &e.AnalyticsBaseURL,
+		EnvAuthURL:             &e.AuthURL,
+		EnvTokenURL:            &e.TokenURL,
+		oauth2.APIClientID:     &e.ClientID,
+		oauth2.APIClientSecret: &e.ClientSecret,
+	}
+
+	for name, field := range overrides {
+		if value := os.Getenv(name); value != "" {
+			*field = value
+		}
+	}
+
+	if value := os.Getenv(EnvFeedChunkSize); value != "" {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("applyEnv(): %v is not a valid number: %v", EnvFeedChunkSize, value)
+		}
+		e.FeedChunkSize = size
+	}
+
+	return nil
+}
+
+// Validate checks that the environment settings are consistent.
+// URLs pointing to eBay hosts and application keys must all belong to the named environment;
+// URLs pointing to other hosts (i.e. proxies or local test servers) are accepted as they are.
+func (e *Environment) Validate() error {
+
+	var maxChunkSize int64
+	switch e.Name {
+	case Sandbox:
+		maxChunkSize = ebay.DefaultSandboxMaxChunkSize
+	case Production:
+		maxChunkSize = ebay.DefaultProdMaxChunkSize
+	default:
+		return fmt.Errorf("Validate(): unknown environment %q", e.Name)
+	}
+
+	urls := []struct {
+		name  string
+		value string
+	}{
+		{"feed_base_url", e.FeedBaseURL},
+		{"analytics_base_url", e.AnalyticsBaseURL},
+		{"auth_url", e.AuthURL},
+		{"token_url", e.TokenURL},
+	}
+
+	for _, u := range urls {
+		if err := e.validateURL(u.name, u.value); err != nil {
+			return err
+		}
+	}
+
+	if e.FeedChunkSize <= 0 || e.FeedChunkSize > maxChunkSize {
+		return fmt.Errorf("Validate(): feed_chunk_size %v is out of range (1-%v) for %v", e.FeedChunkSize, maxChunkSize, e.Name)
+	}
+
+	if (e.Name == Sandbox && strings.Contains(e.ClientID, prodKeyMarker)) ||
+		(e.Name == Production && strings.Contains(e.ClientID, sandboxKeyMarker)) {
+		return fmt.Errorf("Validate(): client_id %v does not belong to %v", e.ClientID, e.Name)
+	}
+
+	return nil
+}
+
+// validateURL checks that the given URL is well formed and, if it is an eBay URL, that it belongs to the environment
+func (e *Environment) validateURL(name, value string) error {
+	if value == "" {
+		return fmt.Errorf("Validate(): %v is not set", name)
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("Validate(): %v is not a valid URL: %v", name, value)
+	}
+
+	host := "." + strings.ToLower(u.Hostname())
+	if !strings.HasSuffix(host, ebayHostSuffix) {
+		return nil
+	}
+
+	isSandbox := strings.HasSuffix(host, sandboxHostSuffix)
+	if isSandbox != (e.Name == Sandbox) {
+		return fmt.Errorf("Validate(): %v %v does not belong to %v", name, value, e.Name)
+	}
+
+	return nil
+}
+
+// NewFeedService creates a new FeedService pointing to the environment.
+// An error is returned if the HTTP client is not granted the Feed API scopes.
+func (e *Environment) NewFeedService(httpClient ebay.HTTPClient) (*ebay.FeedService, error) {
+	s := &ebay.FeedService{
+		HTTPClient: httpClient,
+		BaseURL:    e.FeedBaseURL,
+		Version:    e.FeedVersion,
+		ChunkSize:  e.FeedChunkSize,
+	}
+	if err := ebay.CheckScopes(httpClient, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewAnalyticsService creates a new AnalyticsService pointing to the environment.
+// An error is returned if the HTTP client is not granted the Developer Analytics API scopes.
+func (e *Environment) NewAnalyticsService(httpClient ebay.HTTPClient) (*ebay.AnalyticsService, error) {
+	s := &ebay.AnalyticsService{
+		HTTPClient: httpClient,
+		BaseURL:    e.AnalyticsBaseURL,
+		Version:    e.AnalyticsVersion,
+	}
+	if err := ebay.CheckScopes(httpClient, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Credentials returns a provider supplying the environment credentials
+func (e *Environment) Credentials() oauth2.CredentialsProvider {
+	return oauth2.NewStaticCredentials(e.ClientID, e.ClientSecret)
+}
+
+// NewClientCredentialsClient creates a new HTTP client using the oAuth2 client credential token flow
+// against the environment token URL and with the environment credentials.
+func (e *Environment) NewClientCredentialsClient(ctx context.Context, scopes []string) (*http.Client, error) {
+	return oauth2.NewClientCredentialsClient(ctx, e.Credentials(), e.TokenURL, scopes)
+}
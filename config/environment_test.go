@@ -0,0 +1,248 @@
+package config
+
+import (
+	"context"
+	"ebay-api-client/ebay"
+	"ebay-api-client/oauth2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	dir, err := ioutil.TempDir("", "config")
+	assert.NilError(t, err)
+
+	path := filepath.Join(dir, name)
+	assert.NilError(t, ioutil.WriteFile(path, []byte(content), 0600))
+
+	return path
+}
+
+func unsetEnv() {
+	for _, name := range []string{EnvName, EnvFeedBaseURL, EnvFeedChunkSize, EnvAnalyticsBaseURL, EnvAuthURL, EnvTokenURL, oauth2.APIClientID, oauth2.APIClientSecret} {
+		os.Unsetenv(name)
+	}
+}
+
+func Test_IsNewEnvironmentReturningDefaults(t *testing.T) {
+	env, err := NewEnvironment("PRODUCTION")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, env, NewProdEnvironment())
+	assert.NilError(t, env.Validate())
+
+	env, err = NewEnvironment(Sandbox)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, env, NewSandboxEnvironment())
+	assert.NilError(t, env.Validate())
+
+	_, err = NewEnvironment("staging")
+	assert.Error(t, err, `NewEnvironment(): unknown environment "staging"`)
+}
+
+func Test_IsLoadParsingAllFormats(t *testing.T) {
+	defer unsetEnv()
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name: "Is YAML file loaded?",
+			file: "ebay.yaml",
+			content: `
+name: production
+feed_chunk_size: 2048
+client_id: MyApp-1234-PRD-abcdef-12345678
+client_secret: PRD-secret
+`,
+		},
+		{
+			name:    "Is JSON file loaded?",
+			file:    "ebay.json",
+			content: `{"name": "production", "feed_chunk_size": 2048, "client_id": "MyApp-1234-PRD-abcdef-12345678", "client_secret": "PRD-secret"}`,
+		},
+		{
+			name: "Is TOML file loaded?",
+			file: "ebay.toml",
+			content: `
+name = "production"
+feed_chunk_size = 2048
+client_id = "MyApp-1234-PRD-abcdef-12345678"
+client_secret = "PRD-secret"
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfigFile(t, tt.file, tt.content)
+			defer os.RemoveAll(filepath.Dir(path))
+
+			env, err := Load(path, "")
+			assert.NilError(t, err)
+
+			assert.Equal(t, env.Name, Production)
+			assert.Equal(t, env.FeedBaseURL, ebay.DefaultProdBaseURL)
+			assert.Equal(t, env.FeedChunkSize, int64(2048))
+			assert.Equal(t, env.TokenURL, oauth2.ProdEndpoint.TokenURL)
+			assert.Equal(t, env.ClientID, "MyApp-1234-PRD-abcdef-12345678")
+			assert.Equal(t, env.ClientSecret, "PRD-secret")
+		})
+	}
+}
+
+func Test_IsLoadOverriddenByEnv(t *testing.T) {
+	defer unsetEnv()
+
+	path := writeConfigFile(t, "ebay.yml", "client_id: MyApp-1234-SBX-abcdef-12345678\nfeed_base_url: http://localhost:8080/buy/feed/\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	os.Setenv(oauth2.APIClientSecret, "SBX-secret")
+	os.Setenv(EnvFeedChunkSize, "1024")
+
+	env, err := Load(path, "")
+	assert.NilError(t, err)
+
+	assert.Equal(t, env.Name, Sandbox)
+	assert.Equal(t, env.FeedBaseURL, "http://localhost:8080/buy/feed/")
+	assert.Equal(t, env.FeedChunkSize, int64(1024))
+	assert.Equal(t, env.ClientID, "MyApp-1234-SBX-abcdef-12345678")
+	assert.Equal(t, env.ClientSecret, "SBX-secret")
+
+	os.Setenv(EnvFeedChunkSize, "big")
+	_, err = Load(path, "")
+	assert.Error(t, err, "applyEnv(): EBAY_FEED_CHUNK_SIZE is not a valid number: big")
+}
+
+func Test_IsLoadReturningErrorIfUnsupportedFormat(t *testing.T) {
+	path := writeConfigFile(t, "ebay.ini", "name=sandbox")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	_, err := Load(path, "")
+	assert.ErrorContains(t, err, "unsupported configuration file format")
+}
+
+func Test_IsFromEnvSelectingEnvironment(t *testing.T) {
+	defer unsetEnv()
+
+	env, err := FromEnv("")
+	assert.NilError(t, err)
+	assert.Equal(t, env.Name, Sandbox)
+
+	os.Setenv(EnvName, Production)
+	os.Setenv(EnvTokenURL, "https://api.sandbox.ebay.com/identity/v1/oauth2/token")
+
+	_, err = FromEnv("")
+	assert.ErrorContains(t, err, "token_url https://api.sandbox.ebay.com/identity/v1/oauth2/token does not belong to production")
+
+	// The given name overrides EnvName
+	env, err = FromEnv(Sandbox)
+	assert.NilError(t, err)
+	assert.Equal(t, env.Name, Sandbox)
+}
+
+func Test_Validate(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		env     func() *Environment
+		wantErr string
+	}{
+		{
+			name: "Is sandbox feed URL rejected in production?",
+			env: func() *Environment {
+				env := NewProdEnvironment()
+				env.FeedBaseURL = ebay.DefaultSandboxBaseURL
+				return env
+			},
+			wantErr: "Validate(): feed_base_url https://api.sandbox.ebay.com/buy/feed/ does not belong to production",
+		},
+		{
+			name: "Is production token URL rejected in sandbox?",
+			env: func() *Environment {
+				env := NewSandboxEnvironment()
+				env.TokenURL = oauth2.ProdEndpoint.TokenURL
+				return env
+			},
+			wantErr: "Validate(): token_url https://api.ebay.com/identity/v1/oauth2/token does not belong to sandbox",
+		},
+		{
+			name: "Is production key rejected in sandbox?",
+			env: func() *Environment {
+				env := NewSandboxEnvironment()
+				env.ClientID = "MyApp-1234-PRD-abcdef-12345678"
+				return env
+			},
+			wantErr: "Validate(): client_id MyApp-1234-PRD-abcdef-12345678 does not belong to sandbox",
+		},
+		{
+			name: "Is chunk size over the sandbox limit rejected?",
+			env: func() *Environment {
+				env := NewSandboxEnvironment()
+				env.FeedChunkSize = ebay.DefaultProdMaxChunkSize
+				return env
+			},
+			wantErr: "Validate(): feed_chunk_size 10485760 is out of range (1-1048576) for sandbox",
+		},
+		{
+			name: "Is missing URL rejected?",
+			env: func() *Environment {
+				env := NewSandboxEnvironment()
+				env.AnalyticsBaseURL = ""
+				return env
+			},
+			wantErr: "Validate(): analytics_base_url is not set",
+		},
+		{
+			name: "Is non eBay URL accepted?",
+			env: func() *Environment {
+				env := NewProdEnvironment()
+				env.TokenURL = "http://127.0.0.1:9999/token"
+				return env
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.env().Validate()
+			if tt.wantErr == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.Error(t, err, tt.wantErr)
+		})
+	}
+}
+
+func Test_IsNewFeedServiceUsingEnvironment(t *testing.T) {
+	env := NewProdEnvironment()
+
+	s, err := env.NewFeedService(nil)
+	assert.NilError(t, err)
+	assert.Equal(t, s.BaseURL, ebay.DefaultProdBaseURL)
+	assert.Equal(t, s.Version, ebay.DefaultAPIVersion)
+	assert.Equal(t, s.ChunkSize, ebay.DefaultProdMaxChunkSize)
+
+	a, err := env.NewAnalyticsService(nil)
+	assert.NilError(t, err)
+	assert.Equal(t, a.BaseURL, ebay.DefaultProdAnalyticsBaseURL)
+	assert.Equal(t, a.Version, ebay.DefaultAnalyticsAPIVersion)
+}
+
+func Test_IsNewFeedServiceCheckingScopes(t *testing.T) {
+	env := NewSandboxEnvironment()
+	env.ClientID, env.ClientSecret = "ID", "SECRET"
+
+	c, err := env.NewClientCredentialsClient(context.Background(), []string{oauth2.ScopeAPI})
+	assert.NilError(t, err)
+
+	_, err = env.NewFeedService(c)
+	assert.ErrorContains(t, err, "missing the required scopes: "+oauth2.ScopeBuyFeedAPI)
+
+	_, err = env.NewAnalyticsService(c)
+	assert.NilError(t, err)
+}
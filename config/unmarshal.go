@@ -0,0 +1,33 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Unmarshal decodes the content of a configuration file into v, in the format detected from the file extension:
+// .json, .yaml, .yml and .toml are supported. It is shared by the configuration files of the other packages,
+// i.e. the mirror and sink configurations.
+func Unmarshal(path string, data []byte, v interface{}) error {
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, v)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, v)
+	case ".toml":
+		_, err = toml.DecodeReader(bytes.NewReader(data), v)
+	default:
+		return fmt.Errorf("Unmarshal(): unsupported configuration file format: %v", path)
+	}
+	if err != nil {
+		return fmt.Errorf("Unmarshal(): cannot parse %v: %v", path, err)
+	}
+	return nil
+}
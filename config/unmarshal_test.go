@@ -0,0 +1,35 @@
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func Test_IsUnmarshalDecodingFileFormats(t *testing.T) {
+	type settings struct {
+		Dir   string   `json:"dir" yaml:"dir" toml:"dir"`
+		Feeds []string `json:"feeds" yaml:"feeds" toml:"feeds"`
+	}
+
+	tests := []struct {
+		path string
+		data string
+	}{
+		{path: "mirror.json", data: `{"dir": "feeds", "feeds": ["EBAY_US"]}`},
+		{path: "mirror.YAML", data: "dir: feeds\nfeeds: [EBAY_US]\n"},
+		{path: "mirror.yml", data: "dir: feeds\nfeeds:\n  - EBAY_US\n"},
+		{path: "mirror.toml", data: "dir = \"feeds\"\nfeeds = [\"EBAY_US\"]\n"},
+	}
+	for _, tt := range tests {
+		got := &settings{}
+		assert.NilError(t, Unmarshal(tt.path, []byte(tt.data), got), tt.path)
+		assert.DeepEqual(t, got, &settings{Dir: "feeds", Feeds: []string{"EBAY_US"}})
+	}
+
+	err := Unmarshal("mirror.json", []byte("{"), &settings{})
+	assert.ErrorContains(t, err, "Unmarshal(): cannot parse mirror.json")
+
+	err = Unmarshal("mirror.ini", []byte("dir=feeds"), &settings{})
+	assert.Error(t, err, "Unmarshal(): unsupported configuration file format: mirror.ini")
+}
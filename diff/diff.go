@@ -0,0 +1,232 @@
+// Package diff compares two item feeds, i.e. this week and last week bootstraps, by item id.
+// The feeds are sorted with an external merge sort so that files bigger than the memory can be compared,
+// then they are walked side by side emitting the items added, removed and changed with the changed fields.
+package diff
+
+import (
+	"ebay-api-client/ebay"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	// DefaultMemoryLimit is the default size of the feed rows sorted in memory, for each feed
+	DefaultMemoryLimit int64 = 64 * 1024 * 1024
+)
+
+// EventType is the type of a diff Event
+type EventType string
+
+const (
+	// Added is the event of an item found only in the new feed
+	Added EventType = "added"
+	// Removed is the event of an item found only in the old feed
+	Removed EventType = "removed"
+	// Changed is the event of an item found in both feeds with different values
+	Changed EventType = "changed"
+)
+
+// Event is a difference between the two feeds
+type Event struct {
+	Type EventType
+	// ID is the item id
+	ID string
+	// Old is the item in the old feed, nil if Added
+	Old *ebay.Item
+	// New is the item in the new feed, nil if Removed
+	New *ebay.Item
+	// Fields are the names of the changed Item fields (see ebay.ItemFields), set if Changed
+	Fields []string
+}
+
+// Stats counts the differences between the two feeds
+type Stats struct {
+	Added     int
+	Removed   int
+	Changed   int
+	Unchanged int
+}
+
+// Options tunes the comparison
+type Options struct {
+	// MemoryLimit is the size of the feed rows sorted in memory for each feed, defaults to DefaultMemoryLimit
+	MemoryLimit int64
+	// TempDir is the directory of the temporary sorted runs, defaults to the os temporary directory
+	TempDir string
+	// IgnoreFields are the Item fields not compared, i.e. the ones changing at every feed generation
+	IgnoreFields []string
+}
+
+// Feeds compares the gzipped old and new feeds and calls fn for each difference, ordered by item id.
+// When an id is repeated in a feed, its last row is compared. The comparison stops at the first error returned by fn.
+func Feeds(old, new io.Reader, opts *Options, fn func(*Event) error) (*Stats, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	limit := opts.MemoryLimit
+	if limit <= 0 {
+		limit = DefaultMemoryLimit
+	}
+
+	dir := opts.TempDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	compared, err := comparedFields(opts.IgnoreFields)
+	if err != nil {
+		return nil, err
+	}
+
+	_, oldRows, err := sortFeed(old, limit, dir)
+	if err != nil {
+		return nil, fmt.Errorf("Feeds(): cannot sort old feed: %v", err)
+	}
+	defer oldRows.close()
+
+	_, newRows, err := sortFeed(new, limit, dir)
+	if err != nil {
+		return nil, fmt.Errorf("Feeds(): cannot sort new feed: %v", err)
+	}
+	defer newRows.close()
+
+	oldFeed, newFeed := &uniqueRows{rows: oldRows}, &uniqueRows{rows: newRows}
+	if err := oldFeed.advance(); err != nil {
+		return nil, fmt.Errorf("Feeds(): cannot read old feed: %v", err)
+	}
+	if err := newFeed.advance(); err != nil {
+		return nil, fmt.Errorf("Feeds(): cannot read new feed: %v", err)
+	}
+
+	stats := &Stats{}
+	for oldFeed.ok || newFeed.ok {
+		var e *Event
+
+		switch {
+		case !newFeed.ok || (oldFeed.ok && oldFeed.id < newFeed.id):
+			e = &Event{Type: Removed, ID: oldFeed.id, Old: ebay.NewItemFromTSV(oldFeed.row)}
+			stats.Removed++
+			err = oldFeed.advance()
+		case !oldFeed.ok || newFeed.id < oldFeed.id:
+			e = &Event{Type: Added, ID: newFeed.id, New: ebay.NewItemFromTSV(newFeed.row)}
+			stats.Added++
+			err = newFeed.advance()
+		default:
+			e = compare(oldFeed.id, oldFeed.row, newFeed.row, compared)
+			if e == nil {
+				stats.Unchanged++
+			} else {
+				stats.Changed++
+			}
+			err = oldFeed.advance()
+			if err == nil {
+				err = newFeed.advance()
+			}
+		}
+		if err != nil {
+			return stats, fmt.Errorf("Feeds(): cannot read feed: %v", err)
+		}
+
+		if e != nil {
+			if err := fn(e); err != nil {
+				return stats, err
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// compare returns the Changed event if the rows differ in the compared fields, nil otherwise
+func compare(id, oldRow, newRow string, compared []int) *Event {
+	if oldRow == newRow {
+		return nil
+	}
+
+	oldItem, newItem := ebay.NewItemFromTSV(oldRow), ebay.NewItemFromTSV(newRow)
+	oldValues, newValues := oldItem.Values(), newItem.Values()
+
+	var fields []string
+	for _, i := range compared {
+		if oldValues[i] != newValues[i] {
+			fields = append(fields, ebay.ItemFields[i])
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return &Event{Type: Changed, ID: id, Old: oldItem, New: newItem, Fields: fields}
+}
+
+// comparedFields returns the indexes of the Item fields to compare
+func comparedFields(ignore []string) ([]int, error) {
+	ignored := make(map[string]bool)
+	for _, name := range ignore {
+		found := false
+		for _, field := range ebay.ItemFields {
+			if field == name {
+				ignored[field], found = true, true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("comparedFields(): unknown field %q", name)
+		}
+	}
+
+	var compared []int
+	for i, field := range ebay.ItemFields {
+		if !ignored[field] {
+			compared = append(compared, i)
+		}
+	}
+	return compared, nil
+}
+
+// uniqueRows returns the last row of each item id of a sorted feed
+type uniqueRows struct {
+	rows rowIterator
+
+	ok  bool
+	id  string
+	row string
+
+	// pending is the row read ahead, belonging to the next id
+	pending    string
+	hasPending bool
+}
+
+// advance moves to the next item id
+func (u *uniqueRows) advance() error {
+	row, ok := u.pending, u.hasPending
+	if !ok {
+		var err error
+		if row, ok, err = u.rows.next(); err != nil {
+			return err
+		}
+	}
+	u.hasPending = false
+
+	if !ok {
+		u.ok, u.id, u.row = false, "", ""
+		return nil
+	}
+
+	u.ok, u.id, u.row = true, rowID(row), row
+	for {
+		next, ok, err := u.rows.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		if rowID(next) != u.id {
+			u.pending, u.hasPending = next, true
+			return nil
+		}
+		u.row = next
+	}
+}
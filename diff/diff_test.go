@@ -0,0 +1,130 @@
+package diff
+
+import (
+	"bytes"
+	"ebay-api-client/ebay"
+	"ebay-api-client/ebay/ebaytest"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+var (
+	oldItems = []*ebay.Item{
+		{ID: "v1|4|0", Title: "Item 4", PriceValue: "4"},
+		{ID: "v1|1|0", Title: "Item 1", PriceValue: "1"},
+		{ID: "v1|2|0", Title: "Item 2", PriceValue: "2"},
+		{ID: "v1|3|0", Title: "Item 3", PriceValue: "3", EstimatedAvailableQuantity: "10"},
+	}
+	newItems = []*ebay.Item{
+		{ID: "v1|5|0", Title: "Item 5", PriceValue: "5"},
+		{ID: "v1|3|0", Title: "Item 3", PriceValue: "3", EstimatedAvailableQuantity: "9"},
+		{ID: "v1|2|0", Title: "Item 2", PriceValue: "2"},
+		{ID: "v1|1|0", Title: "Item 1", PriceValue: "1"},
+		{ID: "v1|1|0", Title: "Item one", PriceValue: "1.5"},
+	}
+)
+
+func Test_IsDiffEmittingEvents(t *testing.T) {
+	for _, limit := range []int64{0, 1, 600} {
+		dir, err := ioutil.TempDir("", "diff")
+		assert.NilError(t, err)
+		defer os.RemoveAll(dir)
+
+		var events []*Event
+		stats, err := Feeds(bytes.NewReader(ebaytest.ItemFeed(oldItems...)), bytes.NewReader(ebaytest.ItemFeed(newItems...)), &Options{MemoryLimit: limit, TempDir: dir}, func(e *Event) error {
+			events = append(events, e)
+			return nil
+		})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, stats, &Stats{Added: 1, Removed: 1, Changed: 2, Unchanged: 1})
+
+		assert.Equal(t, len(events), 4)
+
+		assert.Equal(t, events[0].Type, Changed)
+		assert.Equal(t, events[0].ID, "v1|1|0")
+		assert.DeepEqual(t, events[0].Fields, []string{"Title", "PriceValue"})
+		assert.Equal(t, events[0].Old.Title, "Item 1")
+		assert.Equal(t, events[0].New.Title, "Item one")
+
+		assert.Equal(t, events[1].Type, Changed)
+		assert.DeepEqual(t, events[1].Fields, []string{"EstimatedAvailableQuantity"})
+
+		assert.Equal(t, events[2].Type, Removed)
+		assert.Equal(t, events[2].ID, "v1|4|0")
+		assert.Assert(t, events[2].New == nil)
+
+		assert.Equal(t, events[3].Type, Added)
+		assert.Equal(t, events[3].ID, "v1|5|0")
+		assert.Assert(t, events[3].Old == nil)
+
+		// The run files are removed
+		entries, err := ioutil.ReadDir(dir)
+		assert.NilError(t, err)
+		assert.Equal(t, len(entries), 0)
+	}
+}
+
+func Test_IsDiffIgnoringFields(t *testing.T) {
+	var events []*Event
+	stats, err := Feeds(bytes.NewReader(ebaytest.ItemFeed(oldItems...)), bytes.NewReader(ebaytest.ItemFeed(newItems...)), &Options{IgnoreFields: []string{"EstimatedAvailableQuantity"}}, func(e *Event) error {
+		events = append(events, e)
+		return nil
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, stats, &Stats{Added: 1, Removed: 1, Changed: 1, Unchanged: 2})
+
+	_, err = Feeds(bytes.NewReader(ebaytest.ItemFeed(oldItems...)), bytes.NewReader(ebaytest.ItemFeed(newItems...)), &Options{IgnoreFields: []string{"Quantity"}}, func(e *Event) error { return nil })
+	assert.ErrorContains(t, err, "unknown field")
+}
+
+func Test_IsDiffStoppingOnError(t *testing.T) {
+	stop := errors.New("stop")
+
+	calls := 0
+	_, err := Feeds(bytes.NewReader(ebaytest.ItemFeed(oldItems...)), bytes.NewReader(ebaytest.ItemFeed(newItems...)), &Options{MemoryLimit: 1}, func(e *Event) error {
+		calls++
+		return stop
+	})
+	assert.Equal(t, err, stop)
+	assert.Equal(t, calls, 1)
+
+	_, err = Feeds(bytes.NewReader([]byte("not gzipped")), bytes.NewReader(ebaytest.ItemFeed(newItems...)), nil, func(e *Event) error { return nil })
+	assert.ErrorContains(t, err, "cannot sort old feed")
+}
+
+func Test_IsExternalSortKeepingFeedOrderOfSameID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diff")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	data := ebaytest.GzipTSV([]string{"ItemId", "Title"},
+		[]string{"c", "1"}, []string{"a", "2"}, []string{"c", "3"}, []string{"b", "4"}, []string{"a", "5"},
+	)
+
+	_, it, err := sortFeed(bytes.NewReader(data), 5, dir)
+	assert.NilError(t, err)
+
+	var rows []string
+	for {
+		row, ok, err := it.next()
+		assert.NilError(t, err)
+		if !ok {
+			break
+		}
+		rows = append(rows, row)
+	}
+	assert.DeepEqual(t, rows, []string{"a\t2", "a\t5", "b\t4", "c\t1", "c\t3"})
+
+	entries, err := ioutil.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Assert(t, len(entries) > 1)
+
+	assert.NilError(t, it.close())
+	entries, err = ioutil.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 0)
+}
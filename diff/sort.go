@@ -0,0 +1,271 @@
+package diff
+
+import (
+	"bufio"
+	"container/heap"
+	"ebay-api-client/ebay"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxRowSize is the maximum size of a feed row read back from a run file
+	maxRowSize int = 16 * 1024 * 1024
+)
+
+// rowIterator returns the rows of a feed ordered by item id
+type rowIterator interface {
+	// next returns the next row, false when there are no more rows
+	next() (string, bool, error)
+	close() error
+}
+
+// rowID returns the item id of a feed row, the first column
+func rowID(row string) string {
+	if i := strings.IndexByte(row, '\t'); i >= 0 {
+		row = row[:i]
+	}
+	return strings.TrimSpace(row)
+}
+
+// sortFeed sorts the rows of the gzipped feed read from r by item id. The rows are sorted in memory in chunks of
+// at most memoryLimit bytes; when the feed is bigger, each chunk is written into a temporary run file in dir
+// and the runs are merged while iterating. Rows with the same id keep the feed order.
+func sortFeed(r io.Reader, memoryLimit int64, dir string) ([]string, rowIterator, error) {
+	ir, err := ebay.NewItemReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer ir.Close()
+
+	var (
+		rows []string
+		size int64
+		runs []string
+	)
+
+	removeRuns := func() {
+		for _, run := range runs {
+			os.Remove(run)
+		}
+	}
+
+	for {
+		_, err := ir.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			removeRuns()
+			return nil, nil, err
+		}
+
+		row := ir.Text()
+		if rowID(row) == "" {
+			continue
+		}
+
+		rows = append(rows, row)
+		size += int64(len(row))
+		if size < memoryLimit {
+			continue
+		}
+
+		run, err := writeRun(rows, dir)
+		if err != nil {
+			removeRuns()
+			return nil, nil, err
+		}
+		runs = append(runs, run)
+		rows, size = nil, 0
+	}
+
+	sortRows(rows)
+	if len(runs) == 0 {
+		return ir.Header(), &sliceIterator{rows: rows}, nil
+	}
+
+	if len(rows) > 0 {
+		run, err := writeRun(rows, dir)
+		if err != nil {
+			removeRuns()
+			return nil, nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	it, err := newMergeIterator(runs)
+	if err != nil {
+		removeRuns()
+		return nil, nil, err
+	}
+	return ir.Header(), it, nil
+}
+
+func sortRows(rows []string) {
+	sort.SliceStable(rows, func(i, j int) bool { return rowID(rows[i]) < rowID(rows[j]) })
+}
+
+// writeRun sorts the rows and writes them into a temporary run file, returning its path
+func writeRun(rows []string, dir string) (string, error) {
+	sortRows(rows)
+
+	f, err := ioutil.TempFile(dir, "feed-diff-*.run")
+	if err != nil {
+		return "", fmt.Errorf("writeRun(): cannot create run file: %v", err)
+	}
+
+	w := bufio.NewWriter(f)
+	for _, row := range rows {
+		if _, err := w.WriteString(row + "\n"); err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return "", fmt.Errorf("writeRun(): cannot write run file: %v", err)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", fmt.Errorf("writeRun(): cannot write run file: %v", err)
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("writeRun(): cannot write run file: %v", err)
+	}
+	return f.Name(), nil
+}
+
+// sliceIterator iterates over rows sorted in memory
+type sliceIterator struct {
+	rows []string
+}
+
+func (s *sliceIterator) next() (string, bool, error) {
+	if len(s.rows) == 0 {
+		return "", false, nil
+	}
+	row := s.rows[0]
+	s.rows = s.rows[1:]
+	return row, true, nil
+}
+
+func (s *sliceIterator) close() error {
+	return nil
+}
+
+// run is a sorted run file being merged
+type run struct {
+	path    string
+	index   int
+	file    *os.File
+	scanner *bufio.Scanner
+	row     string
+	id      string
+}
+
+func (r *run) advance() (bool, error) {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return false, fmt.Errorf("advance(): cannot read run file: %v", err)
+		}
+		return false, nil
+	}
+	r.row = r.scanner.Text()
+	r.id = rowID(r.row)
+	return true, nil
+}
+
+// runHeap orders the runs by their current row id, then by run index so that rows with the same id keep the feed order
+type runHeap []*run
+
+func (h runHeap) Len() int { return len(h) }
+func (h runHeap) Less(i, j int) bool {
+	if h[i].id != h[j].id {
+		return h[i].id < h[j].id
+	}
+	return h[i].index < h[j].index
+}
+func (h runHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(*run)) }
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+// mergeIterator merges the sorted run files
+type mergeIterator struct {
+	runs []*run
+	heap runHeap
+}
+
+func newMergeIterator(paths []string) (*mergeIterator, error) {
+	m := &mergeIterator{}
+
+	for i, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			m.close()
+			return nil, fmt.Errorf("newMergeIterator(): cannot open run file: %v", err)
+		}
+
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), maxRowSize)
+
+		r := &run{path: path, index: i, file: f, scanner: scanner}
+		m.runs = append(m.runs, r)
+
+		ok, err := r.advance()
+		if err != nil {
+			m.close()
+			return nil, err
+		}
+		if ok {
+			m.heap = append(m.heap, r)
+		}
+	}
+
+	heap.Init(&m.heap)
+	return m, nil
+}
+
+func (m *mergeIterator) next() (string, bool, error) {
+	if len(m.heap) == 0 {
+		return "", false, nil
+	}
+
+	r := m.heap[0]
+	row := r.row
+
+	ok, err := r.advance()
+	if err != nil {
+		return "", false, err
+	}
+	if ok {
+		heap.Fix(&m.heap, 0)
+	} else {
+		heap.Pop(&m.heap)
+	}
+	return row, true, nil
+}
+
+// close closes and removes the run files
+func (m *mergeIterator) close() error {
+	var first error
+	for _, r := range m.runs {
+		if err := r.file.Close(); err != nil && first == nil {
+			first = err
+		}
+		if err := os.Remove(r.path); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,175 @@
+package ebay
+
+import (
+	"context"
+	"ebay-api-client/oauth2"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/go-querystring/query"
+)
+
+const (
+	// DefaultAnalyticsAPIVersion is the default Developer Analytics API version
+	DefaultAnalyticsAPIVersion string = "v1_beta"
+
+	// DefaultSandboxAnalyticsBaseURL is the default base url for the Developer Analytics API in the sandbox environment
+	DefaultSandboxAnalyticsBaseURL string = "https://api.sandbox.ebay.com/developer/analytics/"
+
+	// DefaultProdAnalyticsBaseURL is the default base url for the Developer Analytics API in the production environment
+	DefaultProdAnalyticsBaseURL string = "https://api.ebay.com/developer/analytics/"
+
+	// Developer Analytics API Path
+	pathGetRateLimits     string = "rate_limit/"
+	pathGetUserRateLimits string = "user_rate_limit/"
+)
+
+// AnalyticsService handles the communication with eBay Developer Analytics API
+// https://developer.ebay.com/api-docs/developer/analytics/overview.html
+type AnalyticsService struct {
+	// HTTPClient is the HTTP client instance
+	HTTPClient HTTPClient
+	// BaseURL is the Developer Analytics API base URL
+	BaseURL string
+	// Version is the API version to use
+	Version string
+}
+
+// NewSandboxAnalyticsService creates a new AnalyticsService client pointing to eBay Sandbox environment.
+func NewSandboxAnalyticsService(httpClient HTTPClient) *AnalyticsService {
+	return &AnalyticsService{
+		HTTPClient: httpClient,
+		BaseURL:    DefaultSandboxAnalyticsBaseURL,
+		Version:    DefaultAnalyticsAPIVersion,
+	}
+}
+
+// NewProdAnalyticsService creates a new AnalyticsService client pointing to eBay Production environment.
+func NewProdAnalyticsService(httpClient HTTPClient) *AnalyticsService {
+	return &AnalyticsService{
+		HTTPClient: httpClient,
+		BaseURL:    DefaultProdAnalyticsBaseURL,
+		Version:    DefaultAnalyticsAPIVersion,
+	}
+}
+
+// RateLimit reports the call limits of an API identified by its context and name.
+type RateLimit struct {
+	// APIContext is the context of the API (i.e. buy, sell, commerce, developer, tradingapi)
+	APIContext string `json:"apiContext"`
+	// APIName is the name of the API (i.e. Browse, Feed, TradingAPI)
+	APIName string `json:"apiName"`
+	// APIVersion is the version of the API
+	APIVersion string `json:"apiVersion"`
+	// Resources lists the call limits of each resource of the API
+	Resources []RateLimitResource `json:"resources"`
+}
+
+// RateLimitResource reports the call limits of a single API resource.
+type RateLimitResource struct {
+	// Name is the name of the resource (i.e. buy.feed.item)
+	Name string `json:"name"`
+	// Rates lists the call limits of the resource, one for each time window
+	Rates []Rate `json:"rates"`
+}
+
+// Rate reports the call limit of a resource within a time window.
+type Rate struct {
+	// Count is the number of calls made within the current time window
+	Count int64 `json:"count"`
+	// Limit is the number of calls allowed within the time window
+	Limit int64 `json:"limit"`
+	// Remaining is the number of calls left within the current time window
+	Remaining int64 `json:"remaining"`
+	// Reset is the time when the current time window ends and the calls count is reset
+	Reset time.Time `json:"reset"`
+	// TimeWindow is the length of the time window in seconds
+	TimeWindow int64 `json:"timeWindow"`
+}
+
+// Window returns the length of the time window as a time.Duration.
+func (r Rate) Window() time.Duration {
+	return time.Duration(r.TimeWindow) * time.Second
+}
+
+// Resource returns the call limits of the resource with the given name or nil if the resource is not listed.
+func (r *RateLimit) Resource(name string) *RateLimitResource {
+	for i := range r.Resources {
+		if r.Resources[i].Name == name {
+			return &r.Resources[i]
+		}
+	}
+	return nil
+}
+
+// rateLimitsResponse is the Developer Analytics API response payload
+type rateLimitsResponse struct {
+	RateLimits []RateLimit `json:"rateLimits"`
+}
+
+// RequiredScopes returns the oAuth2 scopes the HTTP client tokens must be granted to call the Developer Analytics API
+func (a *AnalyticsService) RequiredScopes() []string {
+	return []string{oauth2.ScopeAPI}
+}
+
+// rateLimitsParams is Developer Analytics API query parameters
+type rateLimitsParams struct {
+	APIContext string `url:"api_context,omitempty"`
+	APIName    string `url:"api_name,omitempty"`
+}
+
+// GetRateLimits retrieves the application call limits and usage for the given API context and API name.
+// Both apiContext and apiName are optional: when empty, the limits of all the APIs are returned.
+// https://developer.ebay.com/api-docs/developer/analytics/resources/rate_limit/methods/getRateLimits
+func (a *AnalyticsService) GetRateLimits(ctx context.Context, apiContext, apiName string) ([]RateLimit, error) {
+	return a.rateLimits(ctx, pathGetRateLimits, &rateLimitsParams{APIContext: apiContext, APIName: apiName})
+}
+
+// GetUserRateLimits retrieves the user call limits and usage for the given API context and API name.
+// The HTTP client has to be authorized with a user access token.
+// Both apiContext and apiName are optional: when empty, the limits of all the APIs are returned.
+// https://developer.ebay.com/api-docs/developer/analytics/resources/user_rate_limit/methods/getUserRateLimits
+func (a *AnalyticsService) GetUserRateLimits(ctx context.Context, apiContext, apiName string) ([]RateLimit, error) {
+	return a.rateLimits(ctx, pathGetUserRateLimits, &rateLimitsParams{APIContext: apiContext, APIName: apiName})
+}
+
+// rateLimits is an helper function which implements the logic to retrieve the call limits from the given API path
+func (a *AnalyticsService) rateLimits(ctx context.Context, apiPath string, params *rateLimitsParams) ([]RateLimit, error) {
+
+	endpointURL, err := url.Parse(a.BaseURL + a.Version + "/" + apiPath)
+	if err != nil {
+		return nil, fmt.Errorf("rateLimits(): cannot create endpoint URL: %v", err)
+	}
+
+	qs, err := query.Values(params)
+	if err != nil {
+		return nil, fmt.Errorf("rateLimits(): cannot parse query parameters: %v", err)
+	}
+
+	endpointURL.RawQuery = qs.Encode()
+
+	rq, err := http.NewRequest("GET", endpointURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rs, err := a.HTTPClient.Do(rq.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Body.Close()
+
+	if errorResponse := NewErrorResponse(rs); errorResponse != nil {
+		return nil, errorResponse
+	}
+
+	payload := &rateLimitsResponse{}
+	if err := json.NewDecoder(rs.Body).Decode(payload); err != nil {
+		return nil, fmt.Errorf("rateLimits(): cannot decode response body: %v", err)
+	}
+
+	return payload.RateLimits, nil
+}
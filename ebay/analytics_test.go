@@ -0,0 +1,139 @@
+package ebay
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+// newTestAnalyticsServer starts a server answering the requests with the status code and body,
+// after checking them with the optional check function
+func newTestAnalyticsServer(statusCode int, body string, check func(r *http.Request)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if check != nil {
+			check(r)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		io.WriteString(w, body)
+	}))
+}
+
+func Test_NewSandboxAnalyticsService(t *testing.T) {
+	expHTTPClient := http.DefaultClient
+	s := NewSandboxAnalyticsService(expHTTPClient)
+
+	assert.Assert(t, s.HTTPClient == expHTTPClient)
+	assert.Assert(t, s.BaseURL == DefaultSandboxAnalyticsBaseURL)
+	assert.Assert(t, s.Version == DefaultAnalyticsAPIVersion)
+}
+
+func Test_NewProdAnalyticsService(t *testing.T) {
+	expHTTPClient := http.DefaultClient
+	s := NewProdAnalyticsService(expHTTPClient)
+
+	assert.Assert(t, s.HTTPClient == expHTTPClient)
+	assert.Assert(t, s.BaseURL == DefaultProdAnalyticsBaseURL)
+	assert.Assert(t, s.Version == DefaultAnalyticsAPIVersion)
+}
+
+func Test_IsGetRateLimitsDecodingResponse(t *testing.T) {
+
+	var (
+		expURL  string = "/developer/analytics/" + DefaultAnalyticsAPIVersion + "/" + pathGetRateLimits + "?api_context=buy&api_name=Feed"
+		expBody string = `{
+			"rateLimits": [{
+				"apiContext": "buy",
+				"apiName": "Feed",
+				"apiVersion": "v1",
+				"resources": [{
+					"name": "buy.feed.item",
+					"rates": [{
+						"count": 12,
+						"limit": 10000,
+						"remaining": 9988,
+						"reset": "2020-05-04T07:00:00.000Z",
+						"timeWindow": 86400
+					}]
+				}]
+			}]
+		}`
+	)
+
+	server := newTestAnalyticsServer(http.StatusOK, expBody, func(r *http.Request) {
+		assert.Equal(t, r.URL.String(), expURL)
+	})
+	defer server.Close()
+
+	client := NewSandboxAnalyticsService(server.Client())
+	client.BaseURL = server.URL + "/developer/analytics/"
+
+	limits, err := client.GetRateLimits(context.Background(), "buy", "Feed")
+	assert.NilError(t, err)
+
+	assert.Equal(t, len(limits), 1)
+	assert.Equal(t, limits[0].APIContext, "buy")
+	assert.Equal(t, limits[0].APIName, "Feed")
+	assert.Equal(t, limits[0].APIVersion, "v1")
+
+	resource := limits[0].Resource("buy.feed.item")
+	assert.Assert(t, resource != nil)
+	assert.Equal(t, len(resource.Rates), 1)
+
+	rate := resource.Rates[0]
+	assert.Equal(t, rate.Count, int64(12))
+	assert.Equal(t, rate.Limit, int64(10000))
+	assert.Equal(t, rate.Remaining, int64(9988))
+	assert.Equal(t, rate.Window(), 24*time.Hour)
+	assert.Assert(t, rate.Reset.Equal(time.Date(2020, 5, 4, 7, 0, 0, 0, time.UTC)))
+
+	assert.Assert(t, limits[0].Resource("buy.feed.item_snapshot") == nil)
+}
+
+func Test_IsGetUserRateLimitsOmittingEmptyParams(t *testing.T) {
+
+	expURL := "/developer/analytics/" + DefaultAnalyticsAPIVersion + "/" + pathGetUserRateLimits
+
+	server := newTestAnalyticsServer(http.StatusOK, `{"rateLimits": []}`, func(r *http.Request) {
+		assert.Equal(t, r.URL.String(), expURL)
+	})
+	defer server.Close()
+
+	client := NewProdAnalyticsService(server.Client())
+	client.BaseURL = server.URL + "/developer/analytics/"
+
+	limits, err := client.GetUserRateLimits(context.Background(), "", "")
+	assert.NilError(t, err)
+	assert.Equal(t, len(limits), 0)
+}
+
+func Test_IsGetRateLimitsReturningErrorResponse(t *testing.T) {
+
+	expBody := `{
+		"errors": [{
+			"errorId": 1001,
+			"domain": "OAuth",
+			"category": "REQUEST",
+			"message": "Invalid access token",
+			"longMessage": "Invalid access token. Check the value of the Authorization HTTP request header."
+		}]
+	}`
+
+	server := newTestAnalyticsServer(http.StatusUnauthorized, expBody, nil)
+	defer server.Close()
+
+	client := NewSandboxAnalyticsService(server.Client())
+	client.BaseURL = server.URL + "/developer/analytics/"
+
+	_, err := client.GetRateLimits(context.Background(), "buy", "")
+	assert.Assert(t, err != nil)
+
+	errorResponse, ok := err.(*ErrorResponse)
+	assert.Assert(t, ok)
+	assert.Equal(t, errorResponse.Errors[0].ErrorID, 1001)
+}
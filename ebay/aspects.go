@@ -0,0 +1,49 @@
+package ebay
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// Aspect is a localized aspect of an item, i.e. Color: Red
+type Aspect struct {
+	Name  string
+	Value string
+}
+
+// ParseAspects parses the aspects of the feed, separated by ';', whose name and value are base64 encoded and separated
+// by ':'. An error is returned if an aspect has no value or is not base64 encoded.
+func ParseAspects(s string) ([]Aspect, error) {
+	var aspects []Aspect
+	for _, pair := range strings.Split(s, ";") {
+		if pair == "" {
+			continue
+		}
+		i := strings.Index(pair, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("ParseAspects(): aspect %q has no value", pair)
+		}
+
+		name, err := base64.StdEncoding.DecodeString(pair[:i])
+		if err != nil {
+			return nil, fmt.Errorf("ParseAspects(): cannot decode aspect name %q: %v", pair[:i], err)
+		}
+		value, err := base64.StdEncoding.DecodeString(pair[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("ParseAspects(): cannot decode aspect value %q: %v", pair[i+1:], err)
+		}
+		aspects = append(aspects, Aspect{Name: string(name), Value: string(value)})
+	}
+	return aspects, nil
+}
+
+// Aspects returns the decoded LocalizedAspects of the item
+func (i *Item) Aspects() ([]Aspect, error) {
+	return ParseAspects(i.LocalizedAspects)
+}
+
+// InferredAspects returns the decoded InferredLocalizedAspects of the item
+func (i *Item) InferredAspects() ([]Aspect, error) {
+	return ParseAspects(i.InferredLocalizedAspects)
+}
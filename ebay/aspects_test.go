@@ -0,0 +1,41 @@
+package ebay
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func Test_IsItemAspectsDecoded(t *testing.T) {
+	item := &Item{
+		LocalizedAspects:         "Q29sb3I=:UmVk;U2l6ZQ==:NjQgR0I=;",
+		InferredLocalizedAspects: "VHlwZQ==:VHJhdmVsIEJhZw==",
+	}
+
+	aspects, err := item.Aspects()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, aspects, []Aspect{{Name: "Color", Value: "Red"}, {Name: "Size", Value: "64 GB"}})
+
+	aspects, err = item.InferredAspects()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, aspects, []Aspect{{Name: "Type", Value: "Travel Bag"}})
+
+	aspects, err = (&Item{}).Aspects()
+	assert.NilError(t, err)
+	assert.Assert(t, aspects == nil)
+}
+
+func Test_IsParseAspectsRejectingInvalidAspects(t *testing.T) {
+	tests := []struct {
+		aspects string
+		err     string
+	}{
+		{aspects: "Color:UmVk", err: `cannot decode aspect name "Color"`},
+		{aspects: "Q29sb3I=:Red", err: `cannot decode aspect value "Red"`},
+		{aspects: "Q29sb3I=", err: `aspect "Q29sb3I=" has no value`},
+	}
+	for _, tt := range tests {
+		_, err := ParseAspects(tt.aspects)
+		assert.ErrorContains(t, err, tt.err)
+	}
+}
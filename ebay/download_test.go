@@ -0,0 +1,206 @@
+package ebay_test
+
+import (
+	"bytes"
+	"context"
+	"ebay-api-client/ebay"
+	"ebay-api-client/ebay/ebaytest"
+	"net/http"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func newTestFeedServer(data []byte) (*ebaytest.FeedServer, *ebay.FeedService) {
+	server := ebaytest.NewFeedServer()
+	server.AddFeed(&ebaytest.Feed{
+		Path:         ebaytest.PathItem,
+		MarketID:     "EBAY_US",
+		CategoryID:   "1",
+		Scope:        "ALL_ACTIVE",
+		LastModified: time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC),
+		Data:         data,
+	})
+
+	service := ebay.NewSandboxFeedService(server.Client())
+	service.BaseURL = server.BaseURL()
+	service.ChunkSize = 10
+
+	return server, service
+}
+
+func Test_IsWeeklyItemBoostrapDownloadingFromFeedServer(t *testing.T) {
+	data := ebaytest.GzipTSV([]string{"ItemId", "Title"}, []string{"1", "Item 1"}, []string{"2", "Item 2"})
+
+	server, service := newTestFeedServer(data)
+	defer server.Close()
+
+	buffer := new(bytes.Buffer)
+	info, err := service.WeeklyItemBoostrap(context.Background(), "EBAY_US", "1", buffer)
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, buffer.Bytes(), data)
+	assert.Equal(t, info.Size, int64(len(data)))
+	assert.Equal(t, info.LastModified.Format(time.RFC1123), "Wed, 21 Oct 2015 07:28:00 GMT")
+
+	expChunks := (len(data) + 10) / 11
+	requests := server.Requests()
+	assert.Equal(t, len(requests), expChunks)
+	assert.Equal(t, requests[1].Header.Get("Range"), "bytes=11-20")
+}
+
+func Test_IsDownloadReturningErrorIfRateLimited(t *testing.T) {
+	server, service := newTestFeedServer([]byte("Hello World!"))
+	defer server.Close()
+
+	server.InjectFault(ebaytest.RateLimited(1, time.Minute))
+
+	_, err := service.WeeklyItemBoostrap(context.Background(), "EBAY_US", "1", new(bytes.Buffer))
+	assert.Assert(t, err != nil)
+
+	errorResponse, ok := err.(*ebay.ErrorResponse)
+	assert.Assert(t, ok)
+	assert.Equal(t, errorResponse.Response.StatusCode, http.StatusTooManyRequests)
+	assert.Equal(t, errorResponse.Errors[0].ErrorID, 2001)
+}
+
+func Test_IsDownloadReturningErrorIfFeedServerHasNoContent(t *testing.T) {
+	server, service := newTestFeedServer([]byte("Hello World!"))
+	defer server.Close()
+
+	_, err := service.WeeklyItemBoostrap(context.Background(), "EBAY_US", "2", new(bytes.Buffer))
+	assert.ErrorContains(t, err, "API No Content")
+}
+
+func Test_IsDownloadReturningErrorIfBodyTruncated(t *testing.T) {
+	server, service := newTestFeedServer([]byte("Hello World!"))
+	defer server.Close()
+
+	server.InjectFault(ebaytest.Truncated(1, 5))
+
+	_, err := service.WeeklyItemBoostrap(context.Background(), "EBAY_US", "1", new(bytes.Buffer))
+	assert.ErrorContains(t, err, "impossible to copy response body")
+}
+
+func Test_IsItemGroupFeedDownloadedFromFeedServer(t *testing.T) {
+	server := ebaytest.NewFeedServer()
+	defer server.Close()
+
+	date := time.Date(2020, 5, 3, 0, 0, 0, 0, time.UTC)
+	server.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItemGroup, Scope: "ALL_ACTIVE", Data: []byte("weekly")})
+	server.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItemGroup, Scope: "NEWLY_LISTED", Date: "20200503", Data: []byte("daily")})
+
+	service := ebay.NewSandboxFeedService(server.Client())
+	service.BaseURL = server.BaseURL()
+
+	buffer := new(bytes.Buffer)
+	info, err := service.WeeklyItemGroupBoostrap(context.Background(), "EBAY_US", "1", buffer)
+	assert.NilError(t, err)
+	assert.Equal(t, buffer.String(), "weekly")
+	assert.Equal(t, info.Type, "item_group")
+	assert.Equal(t, info.Scope, "ALL_ACTIVE")
+
+	buffer.Reset()
+	info, err = service.DailyNewlyItemGroups(context.Background(), "EBAY_US", "1", date, buffer)
+	assert.NilError(t, err)
+	assert.Equal(t, buffer.String(), "daily")
+	assert.Equal(t, info.Scope, "NEWLY_LISTED")
+}
+
+func Test_IsDownloadRequestingThreeChunks(t *testing.T) {
+	server, service := newTestFeedServer([]byte("Hello World!Hello World!Hello World!"))
+	defer server.Close()
+
+	service.ChunkSize = 12
+
+	buffer := new(bytes.Buffer)
+	info, err := service.WeeklyItemBoostrap(context.Background(), "EBAY_US", "1", buffer)
+	assert.NilError(t, err)
+
+	assert.Equal(t, buffer.String(), "Hello World!Hello World!Hello World!")
+	assert.Equal(t, info.CategoryID, "1")
+	assert.Equal(t, info.MarketID, "EBAY_US")
+	assert.Equal(t, info.Scope, "ALL_ACTIVE")
+	assert.Equal(t, info.Size, int64(36))
+
+	requests := server.Requests()
+	assert.Equal(t, len(requests), 3)
+	for n, expRange := range []string{"bytes=0-12", "bytes=13-24", "bytes=25-36"} {
+		assert.Equal(t, requests[n].URL.Path, "/buy/feed/v1_beta/item")
+		assert.Equal(t, requests[n].URL.Query().Get("category_id"), "1")
+		assert.Equal(t, requests[n].URL.Query().Get("feed_scope"), "ALL_ACTIVE")
+		assert.Equal(t, requests[n].Header.Get("X-EBAY-C-MARKETPLACE-ID"), "EBAY_US")
+		assert.Equal(t, requests[n].Header.Get("Range"), expRange)
+	}
+}
+
+func Test_IsDownloadRequestingOneChunk(t *testing.T) {
+	server, service := newTestFeedServer([]byte("Hello World!"))
+	defer server.Close()
+
+	service.ChunkSize = ebay.DefaultSandboxMaxChunkSize
+
+	buffer := new(bytes.Buffer)
+	info, err := service.WeeklyItemBoostrap(context.Background(), "EBAY_US", "1", buffer)
+	assert.NilError(t, err)
+
+	assert.Equal(t, buffer.String(), "Hello World!")
+	assert.Equal(t, info.Size, int64(12))
+	assert.Equal(t, len(server.Requests()), 1)
+}
+
+func Test_IsDownloadReturningErrorResponse(t *testing.T) {
+	server, service := newTestFeedServer([]byte("Hello World!"))
+	defer server.Close()
+
+	_, err := service.WeeklyItemBoostrap(context.Background(), "EBAY_US", "", new(bytes.Buffer))
+	assert.Assert(t, err != nil)
+
+	errorResponse, ok := err.(*ebay.ErrorResponse)
+	assert.Assert(t, ok)
+	assert.Equal(t, errorResponse.Response.StatusCode, http.StatusBadRequest)
+	assert.Equal(t, errorResponse.Errors[0].ErrorID, 13000)
+	assert.Equal(t, errorResponse.Errors[0].Message, "The 'category_id' is invalid or missing.")
+}
+
+func Test_IsDownloadReturningErrorIfHTTPError(t *testing.T) {
+	server, service := newTestFeedServer([]byte("Hello World!"))
+	server.Close()
+
+	_, err := service.WeeklyItemBoostrap(context.Background(), "EBAY_US", "1", new(bytes.Buffer))
+	assert.ErrorContains(t, err, "connection refused")
+}
+
+func Test_IsDailyNewlyItemsRequestingDate(t *testing.T) {
+	server, service := newTestFeedServer([]byte("weekly"))
+	defer server.Close()
+
+	server.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItem, Scope: "NEWLY_LISTED", Date: "20200517", Data: []byte("daily")})
+
+	buffer := new(bytes.Buffer)
+	info, err := service.DailyNewlyItems(context.Background(), "EBAY_US", "1", time.Date(2020, time.May, 17, 0, 0, 0, 0, time.UTC), buffer)
+	assert.NilError(t, err)
+
+	assert.Equal(t, buffer.String(), "daily")
+	assert.Equal(t, info.CategoryID, "1")
+	assert.Equal(t, info.MarketID, "EBAY_US")
+	assert.Equal(t, info.Scope, "NEWLY_LISTED")
+	assert.Equal(t, server.Requests()[0].URL.Query().Get("date"), "20200517")
+}
+
+func Test_IsItemSnapshotRequestingSnapshotDate(t *testing.T) {
+	server, service := newTestFeedServer([]byte("weekly"))
+	defer server.Close()
+
+	server.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItemSnapshot, Date: "2020-05-17T16:00:00.000Z", Data: []byte("snapshot")})
+
+	buffer := new(bytes.Buffer)
+	info, err := service.ItemShapshot(context.Background(), "EBAY_US", "1", time.Date(2020, time.May, 17, 16, 0, 0, 0, time.UTC), buffer)
+	assert.NilError(t, err)
+
+	assert.Equal(t, buffer.String(), "snapshot")
+	assert.Equal(t, info.CategoryID, "1")
+	assert.Equal(t, info.MarketID, "EBAY_US")
+	assert.Equal(t, server.Requests()[0].URL.Path, "/buy/feed/v1_beta/item_snapshot")
+}
@@ -0,0 +1,257 @@
+package ebaytest
+
+import (
+	"bytes"
+	"ebay-api-client/ebay"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// redacted replaces the secret header values in the cassettes
+const redacted string = "REDACTED"
+
+// RecordedRequest is a request saved in a Cassette
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+}
+
+// RecordedResponse is a response saved in a Cassette
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// Interaction is a request/response pair saved in a Cassette
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// Cassette is the list of the interactions recorded by a Recorder and served by a Replayer
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// LoadCassette reads a cassette file
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("LoadCassette(): cannot read cassette: %v", err)
+	}
+
+	c := &Cassette{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("LoadCassette(): cannot decode cassette %v: %v", path, err)
+	}
+	return c, nil
+}
+
+// Save writes the cassette file, creating its directory if needed
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Save(): cannot encode cassette: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("Save(): cannot create cassette directory: %v", err)
+	}
+
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("Save(): cannot write cassette: %v", err)
+	}
+	return nil
+}
+
+// Recorder is an ebay.HTTPClient wrapper saving the request/response pairs in a Cassette.
+// The Authorization header and the headers listed in Redact are not saved.
+type Recorder struct {
+	// HTTPClient is the client sending the requests
+	HTTPClient ebay.HTTPClient
+	// Redact are the additional headers whose values are not saved
+	Redact []string
+
+	mu       sync.Mutex
+	cassette *Cassette
+}
+
+// NewRecorder creates a new Recorder sending the requests through the given client
+func NewRecorder(httpClient ebay.HTTPClient) *Recorder {
+	return &Recorder{HTTPClient: httpClient, cassette: &Cassette{}}
+}
+
+// Do sends the request and records the interaction
+func (r *Recorder) Do(req *http.Request) (*http.Response, error) {
+	rs, err := r.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(rs.Body)
+	rs.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("Do(): cannot read response body: %v", err)
+	}
+	rs.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	i := &Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: r.redact(req.Header),
+		},
+		Response: RecordedResponse{
+			StatusCode: rs.StatusCode,
+			Header:     rs.Header.Clone(),
+			Body:       body,
+		},
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, i)
+	r.mu.Unlock()
+
+	return rs, nil
+}
+
+// Cassette returns the interactions recorded so far
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return &Cassette{Interactions: append([]*Interaction{}, r.cassette.Interactions...)}
+}
+
+// Save writes the interactions recorded so far to the cassette file
+func (r *Recorder) Save(path string) error {
+	return r.Cassette().Save(path)
+}
+
+// redact returns a copy of the header without the secret values
+func (r *Recorder) redact(h http.Header) http.Header {
+	h = h.Clone()
+	if h == nil {
+		h = make(http.Header)
+	}
+
+	for _, name := range append([]string{"Authorization"}, r.Redact...) {
+		if h.Get(name) != "" {
+			h.Set(name, redacted)
+		}
+	}
+	return h
+}
+
+// Matcher reports whether the request matches the recorded one
+type Matcher func(req *http.Request, recorded *RecordedRequest) bool
+
+// MatchMethod matches the requests with the same method
+func MatchMethod(req *http.Request, recorded *RecordedRequest) bool {
+	return req.Method == recorded.Method
+}
+
+// MatchPath matches the requests with the same URL path, regardless of the host
+func MatchPath(req *http.Request, recorded *RecordedRequest) bool {
+	u, err := req.URL.Parse(recorded.URL)
+	return err == nil && req.URL.Path == u.Path
+}
+
+// MatchQuery matches the requests with the same query parameters, regardless of their order
+func MatchQuery(req *http.Request, recorded *RecordedRequest) bool {
+	u, err := req.URL.Parse(recorded.URL)
+	return err == nil && req.URL.Query().Encode() == u.Query().Encode()
+}
+
+// MatchRange matches the requests with the same Range header
+func MatchRange(req *http.Request, recorded *RecordedRequest) bool {
+	return req.Header.Get(headerRange) == recorded.Header.Get(headerRange)
+}
+
+// MatchHeader returns a Matcher matching the requests with the same value of the given header
+func MatchHeader(name string) Matcher {
+	return func(req *http.Request, recorded *RecordedRequest) bool {
+		return req.Header.Get(name) == recorded.Header.Get(name)
+	}
+}
+
+// DefaultMatchers are the rules used by the Replayer when none is given
+var DefaultMatchers = []Matcher{MatchMethod, MatchPath, MatchQuery, MatchRange}
+
+// Replayer is an ebay.HTTPClient serving the interactions of a Cassette without network.
+// Each request is answered with the first interaction not replayed yet matching all the rules,
+// or with the last matching one if they were all replayed.
+type Replayer struct {
+	cassette *Cassette
+	matchers []Matcher
+
+	mu       sync.Mutex
+	replayed map[*Interaction]bool
+}
+
+// NewReplayer creates a new Replayer of the given cassette. DefaultMatchers are used if no matcher is given.
+func NewReplayer(cassette *Cassette, matchers ...Matcher) *Replayer {
+	if len(matchers) == 0 {
+		matchers = DefaultMatchers
+	}
+	return &Replayer{cassette: cassette, matchers: matchers, replayed: make(map[*Interaction]bool)}
+}
+
+// Do returns the recorded response matching the request
+func (r *Replayer) Do(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	i := r.match(req)
+	if i == nil {
+		return nil, fmt.Errorf("Do(): no recorded interaction matching %v %v", req.Method, req.URL)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %v", i.Response.StatusCode, http.StatusText(i.Response.StatusCode)),
+		StatusCode:    i.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        i.Response.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(i.Response.Body)),
+		ContentLength: int64(len(i.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// match returns the interaction to replay for the request
+func (r *Replayer) match(req *http.Request) *Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var last *Interaction
+	for _, i := range r.cassette.Interactions {
+		if !r.matches(req, &i.Request) {
+			continue
+		}
+		if !r.replayed[i] {
+			r.replayed[i] = true
+			return i
+		}
+		last = i
+	}
+	return last
+}
+
+func (r *Replayer) matches(req *http.Request, recorded *RecordedRequest) bool {
+	for _, m := range r.matchers {
+		if !m(req, recorded) {
+			return false
+		}
+	}
+	return true
+}
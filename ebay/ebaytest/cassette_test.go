@@ -0,0 +1,107 @@
+package ebaytest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func readBody(t *testing.T, rs *http.Response) string {
+	defer rs.Body.Close()
+
+	body, err := ioutil.ReadAll(rs.Body)
+	assert.NilError(t, err)
+	return string(body)
+}
+
+func Test_IsRecorderRedactingAuthorization(t *testing.T) {
+	s := NewFeedServer()
+	defer s.Close()
+	s.AddFeed(&Feed{Path: PathItem, Data: []byte("0123456789")})
+
+	r := NewRecorder(s.Client())
+	r.Redact = []string{"X-Api-Key"}
+
+	rq := newFeedRequest(t, s, PathItem, "category_id=1", "bytes=0-3")
+	rq.Header.Set("Authorization", "Bearer SECRET")
+	rq.Header.Set("X-Api-Key", "KEY")
+
+	rs, err := r.Do(rq)
+	assert.NilError(t, err)
+	assert.Equal(t, readBody(t, rs), "0123")
+
+	c := r.Cassette()
+	assert.Equal(t, len(c.Interactions), 1)
+
+	i := c.Interactions[0]
+	assert.Equal(t, i.Request.Header.Get("Authorization"), redacted)
+	assert.Equal(t, i.Request.Header.Get("X-Api-Key"), redacted)
+	assert.Equal(t, i.Request.Header.Get(headerRange), "bytes=0-3")
+	assert.Equal(t, i.Response.StatusCode, http.StatusPartialContent)
+	assert.Equal(t, string(i.Response.Body), "0123")
+
+	// The original request is not modified
+	assert.Equal(t, rq.Header.Get("Authorization"), "Bearer SECRET")
+}
+
+func Test_IsReplayerServingRecordedCassette(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cassette")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "testdata", "feed.json")
+
+	s := NewFeedServer()
+	s.AddFeed(&Feed{Path: PathItem, Data: []byte("0123456789")})
+
+	r := NewRecorder(s.Client())
+	for _, byteRange := range []string{"bytes=0-4", "bytes=5-9"} {
+		rs, err := r.Do(newFeedRequest(t, s, PathItem, "category_id=1&feed_scope=ALL_ACTIVE", byteRange))
+		assert.NilError(t, err)
+		readBody(t, rs)
+	}
+	assert.NilError(t, r.Save(path))
+
+	// The server is gone: the responses come from the cassette
+	s.Close()
+
+	c, err := LoadCassette(path)
+	assert.NilError(t, err)
+	replayer := NewReplayer(c)
+
+	rs, err := replayer.Do(newFeedRequest(t, s, PathItem, "feed_scope=ALL_ACTIVE&category_id=1", "bytes=5-9"))
+	assert.NilError(t, err)
+	assert.Equal(t, rs.StatusCode, http.StatusPartialContent)
+	assert.Equal(t, rs.Header.Get(headerContentRange), "5-9/10")
+	assert.Equal(t, readBody(t, rs), "56789")
+
+	_, err = replayer.Do(newFeedRequest(t, s, PathItem, "category_id=2&feed_scope=ALL_ACTIVE", "bytes=5-9"))
+	assert.ErrorContains(t, err, "no recorded interaction matching")
+
+	// Ignoring the Range header, the interactions are replayed in order
+	replayer = NewReplayer(c, MatchMethod, MatchPath, MatchQuery)
+	for _, want := range []string{"01234", "56789", "56789"} {
+		rs, err = replayer.Do(newFeedRequest(t, s, PathItem, "category_id=1&feed_scope=ALL_ACTIVE", ""))
+		assert.NilError(t, err)
+		assert.Equal(t, readBody(t, rs), want)
+	}
+}
+
+func Test_IsLoadCassetteReturningErrorIfInvalid(t *testing.T) {
+	_, err := LoadCassette("does-not-exist.json")
+	assert.ErrorContains(t, err, "cannot read cassette")
+
+	dir, err := ioutil.TempDir("", "cassette")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "invalid.json")
+	assert.NilError(t, ioutil.WriteFile(path, []byte("{"), 0600))
+
+	_, err = LoadCassette(path)
+	assert.ErrorContains(t, err, "cannot decode cassette")
+}
@@ -0,0 +1,424 @@
+// Package ebaytest provides an in-process fake of the eBay Feed API and feed fixtures to test the feed consumers offline
+package ebaytest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// PathItem is the Feed API path of the item feeds
+	PathItem string = "item"
+	// PathItemSnapshot is the Feed API path of the hourly item snapshot feeds
+	PathItemSnapshot string = "item_snapshot"
+	// PathItemGroup is the Feed API path of the item group feeds
+	PathItemGroup string = "item_group"
+
+	// basePath is the Feed API base path, followed by the API version and the feed path
+	basePath string = "/buy/feed/"
+
+	headerRange         string = "Range"
+	headerContentRange  string = "Content-Range"
+	headerLastModified  string = "Last-Modified"
+	headerMarketplaceID string = "X-EBAY-C-MARKETPLACE-ID"
+	headerRetryAfter    string = "Retry-After"
+)
+
+// Feed is a feed file served by the FeedServer. The empty criteria match any request value.
+type Feed struct {
+	// Path is the Feed API path, one of PathItem, PathItemSnapshot and PathItemGroup
+	Path string
+	// MarketID is the eBay marketplace id given in the X-EBAY-C-MARKETPLACE-ID header
+	MarketID string
+	// CategoryID is the category_id query parameter
+	CategoryID string
+	// Scope is the feed_scope query parameter (ALL_ACTIVE or NEWLY_LISTED)
+	Scope string
+	// Date is the date query parameter (yyyyMMdd) or, for the snapshot feeds, the snapshot_date one
+	Date string
+	// LastModified is the generation time of the feed
+	LastModified time.Time
+	// Data is the gzipped TSV content of the feed. It is ignored if File is set.
+	Data []byte
+	// File is the path of a gzipped TSV fixture on disk
+	File string
+}
+
+// matches reports whether the feed is the one requested
+func (f *Feed) matches(path string, r *http.Request) bool {
+	q := r.URL.Query()
+	date := q.Get("date")
+	if path == PathItemSnapshot {
+		date = q.Get("snapshot_date")
+	}
+
+	return f.Path == path &&
+		matchesValue(f.MarketID, r.Header.Get(headerMarketplaceID)) &&
+		matchesValue(f.CategoryID, q.Get("category_id")) &&
+		matchesValue(f.Scope, q.Get("feed_scope")) &&
+		matchesValue(f.Date, date)
+}
+
+func matchesValue(want, got string) bool {
+	return want == "" || want == got
+}
+
+// content returns the feed content and its size
+func (f *Feed) content() (io.ReadSeeker, int64, func(), error) {
+	if f.File == "" {
+		return bytes.NewReader(f.Data), int64(len(f.Data)), func() {}, nil
+	}
+
+	file, err := os.Open(f.File)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("content(): cannot open feed file: %v", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, nil, fmt.Errorf("content(): cannot read feed file: %v", err)
+	}
+
+	return file, info.Size(), func() { file.Close() }, nil
+}
+
+// Fault is an error injected in the FeedServer responses
+type Fault struct {
+	// Path restricts the fault to the given feed path. Empty means any path.
+	Path string
+	// Times is the number of requests affected by the fault. Zero means every request.
+	Times int
+	// Delay is how long the response is delayed
+	Delay time.Duration
+	// Status, if set, is the status code of the error response sent instead of the feed
+	Status int
+	// RetryAfter is the Retry-After header value of the error response, optional
+	RetryAfter string
+	// Truncate, if greater than zero, is the number of bytes of the chunk sent before the connection is closed
+	Truncate int64
+}
+
+// RateLimited returns a Fault answering the next requests with 429 Too Many Requests
+func RateLimited(times int, retryAfter time.Duration) Fault {
+	return Fault{Times: times, Status: http.StatusTooManyRequests, RetryAfter: strconv.Itoa(int(retryAfter.Seconds()))}
+}
+
+// ServerError returns a Fault answering the next requests with the given 5xx status code
+func ServerError(times, status int) Fault {
+	return Fault{Times: times, Status: status}
+}
+
+// Truncated returns a Fault closing the connection after the given number of bytes of the next chunks
+func Truncated(times int, size int64) Fault {
+	return Fault{Times: times, Truncate: size}
+}
+
+// Slow returns a Fault delaying the next responses
+func Slow(times int, delay time.Duration) Fault {
+	return Fault{Times: times, Delay: delay}
+}
+
+// fault is an injected Fault with its remaining number of requests
+type fault struct {
+	Fault
+	remaining int
+}
+
+// FeedServer is an in-process fake of the eBay Feed API. It serves the registered feeds in chunks
+// honoring the Range header and returning the Content-Range and Last-Modified headers as eBay does.
+// The feeds not found and the empty ones are answered with 204 No Content.
+type FeedServer struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	feeds      []*Feed
+	faults     []*fault
+	requests   []*http.Request
+	authorizer func(r *http.Request) bool
+}
+
+// NewFeedServer starts a new FeedServer. The caller has to call Close when done.
+func NewFeedServer() *FeedServer {
+	s := &FeedServer{}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// BaseURL returns the Feed API base URL of the server, to be set as FeedService BaseURL
+func (s *FeedServer) BaseURL() string {
+	return s.URL + basePath
+}
+
+// AddFeed registers a feed to be served. Later feeds take precedence over earlier ones.
+func (s *FeedServer) AddFeed(f *Feed) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.feeds = append([]*Feed{f}, s.feeds...)
+}
+
+// InjectFault registers a fault for the next requests. Faults are applied in order, one per request.
+func (s *FeedServer) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = append(s.faults, &fault{Fault: f, remaining: f.Times})
+}
+
+// SetAuthorizer sets the function checking the requests access token. The requests not authorized are
+// answered with 401 and the eBay invalid access token error. By default all the requests are authorized.
+func (s *FeedServer) SetAuthorizer(authorize func(r *http.Request) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.authorizer = authorize
+}
+
+// Requests returns the requests received so far
+func (s *FeedServer) Requests() []*http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*http.Request{}, s.requests...)
+}
+
+// ServeHTTP serves the Feed API requests
+func (s *FeedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Clone(r.Context()))
+	authorize := s.authorizer
+	s.mu.Unlock()
+
+	if authorize != nil && !authorize(r) {
+		writeErrorData(w, http.StatusUnauthorized, 1001, "OAuth", "Invalid access token")
+		return
+	}
+
+	path, ok := feedPath(r.URL.Path)
+	if !ok {
+		writeError(w, http.StatusNotFound, 2002, "Resource not found")
+		return
+	}
+
+	f := s.nextFault(path)
+	if f.Delay > 0 {
+		select {
+		case <-time.After(f.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if f.Status != 0 {
+		if f.RetryAfter != "" {
+			w.Header().Set(headerRetryAfter, f.RetryAfter)
+		}
+		writeStatusError(w, f.Status)
+		return
+	}
+
+	if r.Header.Get(headerMarketplaceID) == "" {
+		writeError(w, http.StatusBadRequest, 13022, "The 'X-EBAY-C-MARKETPLACE-ID' header is missing or invalid.")
+		return
+	}
+	if r.URL.Query().Get("category_id") == "" {
+		writeError(w, http.StatusBadRequest, 13000, "The 'category_id' is invalid or missing.")
+		return
+	}
+
+	feed := s.feed(path, r)
+	if feed == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	s.serveFeed(w, r, feed, f.Truncate)
+}
+
+// serveFeed writes the requested range of the feed
+func (s *FeedServer) serveFeed(w http.ResponseWriter, r *http.Request, feed *Feed, truncate int64) {
+	content, size, closeContent, err := feed.content()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, 10001, err.Error())
+		return
+	}
+	defer closeContent()
+
+	// An empty feed has no range to serve, eBay answers it like a missing feed
+	if size == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	status := http.StatusPartialContent
+	lower, upper, ok := parseRange(r.Header.Get(headerRange))
+	if !ok {
+		status, lower, upper = http.StatusOK, 0, size-1
+	}
+	if upper >= size {
+		upper = size - 1
+	}
+	if lower > upper {
+		w.Header().Set(headerContentRange, fmt.Sprintf("*/%v", size))
+		writeError(w, http.StatusRequestedRangeNotSatisfiable, 13019, "The 'Range' header is invalid.")
+		return
+	}
+
+	if _, err := content.Seek(lower, io.SeekStart); err != nil {
+		writeError(w, http.StatusInternalServerError, 10001, err.Error())
+		return
+	}
+
+	length := upper - lower + 1
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+	w.Header().Set(headerContentRange, fmt.Sprintf("%v-%v/%v", lower, upper, size))
+	w.Header().Set(headerLastModified, feed.LastModified.UTC().Format(http.TimeFormat))
+	w.WriteHeader(status)
+
+	if truncate > 0 && truncate < length {
+		// Writing less than Content-Length makes the server close the connection
+		length = truncate
+	}
+	io.CopyN(w, content, length)
+}
+
+// nextFault returns the fault to apply to the request and consumes it
+func (s *FeedServer) nextFault(path string) Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, f := range s.faults {
+		if f.Path != "" && f.Path != path {
+			continue
+		}
+
+		if f.Times > 0 {
+			f.remaining--
+			if f.remaining <= 0 {
+				s.faults = append(s.faults[:i], s.faults[i+1:]...)
+			}
+		}
+		return f.Fault
+	}
+	return Fault{}
+}
+
+// feed returns the registered feed matching the request
+func (s *FeedServer) feed(path string, r *http.Request) *Feed {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, f := range s.feeds {
+		if f.matches(path, r) {
+			return f
+		}
+	}
+	return nil
+}
+
+// feedPath extracts the feed path from the URL path /buy/feed/{version}/{path}
+func feedPath(urlPath string) (string, bool) {
+	if !strings.HasPrefix(urlPath, basePath) {
+		return "", false
+	}
+
+	parts := strings.Split(strings.TrimPrefix(urlPath, basePath), "/")
+	if len(parts) != 2 {
+		return "", false
+	}
+
+	switch parts[1] {
+	case PathItem, PathItemSnapshot, PathItemGroup:
+		return parts[1], true
+	}
+	return "", false
+}
+
+// parseRange parses the Range header bytes=lower-upper
+func parseRange(value string) (int64, int64, bool) {
+	if !strings.HasPrefix(value, "bytes=") {
+		return 0, 0, false
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, "bytes="), "-")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+
+	lower, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	upper, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || upper < lower {
+		return 0, 0, false
+	}
+
+	return lower, upper, true
+}
+
+// writeStatusError writes the eBay error payload matching the status code
+func writeStatusError(w http.ResponseWriter, status int) {
+	switch {
+	case status == http.StatusTooManyRequests:
+		writeError(w, status, 2001, "Too many requests. The request limit has been reached for the resource.")
+	case status >= http.StatusInternalServerError:
+		writeError(w, status, 10001, "There was a problem with an eBay internal system or process. Contact eBay developer support for assistance.")
+	default:
+		writeError(w, status, 0, http.StatusText(status))
+	}
+}
+
+// writeError writes an error response with the eBay error payload
+// Check for details https://developer.ebay.com/api-docs/static/handling-error-messages.html
+func writeError(w http.ResponseWriter, status, errorID int, message string) {
+	writeErrorData(w, status, errorID, "API_FEED", message)
+}
+
+// writeErrorData writes an error response with the eBay error payload of the given domain
+func writeErrorData(w http.ResponseWriter, status, errorID int, domain, message string) {
+	category := "REQUEST"
+	if status >= http.StatusInternalServerError {
+		category = "APPLICATION"
+	}
+
+	payload := map[string]interface{}{
+		"errors": []map[string]interface{}{{
+			"errorId":  errorID,
+			"domain":   domain,
+			"category": category,
+			"message":  message,
+		}},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
+
+// GzipTSV builds an in-memory feed fixture: the gzipped TSV of the given header and rows
+func GzipTSV(header []string, rows ...[]string) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+
+	fmt.Fprintln(zw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(zw, strings.Join(row, "\t"))
+	}
+
+	zw.Close()
+	return buf.Bytes()
+}
@@ -0,0 +1,181 @@
+package ebaytest
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+var testLastModified = time.Date(2020, 5, 4, 7, 0, 0, 0, time.UTC)
+
+func newFeedRequest(t *testing.T, s *FeedServer, path, query, byteRange string) *http.Request {
+	rq, err := http.NewRequest("GET", s.BaseURL()+"v1_beta/"+path+"?"+query, nil)
+	assert.NilError(t, err)
+
+	rq.Header.Set(headerMarketplaceID, "EBAY_US")
+	if byteRange != "" {
+		rq.Header.Set(headerRange, byteRange)
+	}
+	return rq
+}
+
+func doFeedRequest(t *testing.T, rq *http.Request) (*http.Response, string) {
+	rs, err := http.DefaultClient.Do(rq)
+	assert.NilError(t, err)
+	defer rs.Body.Close()
+
+	body, err := ioutil.ReadAll(rs.Body)
+	assert.NilError(t, err)
+	return rs, string(body)
+}
+
+func Test_IsFeedServerHonoringRange(t *testing.T) {
+	s := NewFeedServer()
+	defer s.Close()
+
+	s.AddFeed(&Feed{Path: PathItem, CategoryID: "1", Scope: "ALL_ACTIVE", LastModified: testLastModified, Data: []byte("0123456789")})
+
+	rs, body := doFeedRequest(t, newFeedRequest(t, s, PathItem, "category_id=1&feed_scope=ALL_ACTIVE", "bytes=0-3"))
+	assert.Equal(t, rs.StatusCode, http.StatusPartialContent)
+	assert.Equal(t, body, "0123")
+	assert.Equal(t, rs.Header.Get(headerContentRange), "0-3/10")
+	assert.Equal(t, rs.Header.Get(headerLastModified), "Mon, 04 May 2020 07:00:00 GMT")
+
+	rs, body = doFeedRequest(t, newFeedRequest(t, s, PathItem, "category_id=1&feed_scope=ALL_ACTIVE", "bytes=8-20"))
+	assert.Equal(t, rs.StatusCode, http.StatusPartialContent)
+	assert.Equal(t, body, "89")
+	assert.Equal(t, rs.Header.Get(headerContentRange), "8-9/10")
+
+	rs, body = doFeedRequest(t, newFeedRequest(t, s, PathItem, "category_id=1&feed_scope=ALL_ACTIVE", ""))
+	assert.Equal(t, rs.StatusCode, http.StatusOK)
+	assert.Equal(t, body, "0123456789")
+
+	rs, _ = doFeedRequest(t, newFeedRequest(t, s, PathItem, "category_id=1&feed_scope=ALL_ACTIVE", "bytes=10-20"))
+	assert.Equal(t, rs.StatusCode, http.StatusRequestedRangeNotSatisfiable)
+
+	s.AddFeed(&Feed{Path: PathItem, CategoryID: "2", LastModified: testLastModified})
+	rs, body = doFeedRequest(t, newFeedRequest(t, s, PathItem, "category_id=2&feed_scope=ALL_ACTIVE", "bytes=0-3"))
+	assert.Equal(t, rs.StatusCode, http.StatusNoContent)
+	assert.Equal(t, body, "")
+}
+
+func Test_IsFeedServerMatchingFeeds(t *testing.T) {
+	s := NewFeedServer()
+	defer s.Close()
+
+	s.AddFeed(&Feed{Path: PathItem, CategoryID: "1", Scope: "NEWLY_LISTED", Date: "20200503", Data: []byte("daily")})
+	s.AddFeed(&Feed{Path: PathItemSnapshot, CategoryID: "1", Date: "2020-05-03T07:00:00.000Z", Data: []byte("snapshot")})
+	s.AddFeed(&Feed{Path: PathItemGroup, MarketID: "EBAY_DE", Data: []byte("group")})
+
+	_, body := doFeedRequest(t, newFeedRequest(t, s, PathItem, "category_id=1&feed_scope=NEWLY_LISTED&date=20200503", ""))
+	assert.Equal(t, body, "daily")
+
+	_, body = doFeedRequest(t, newFeedRequest(t, s, PathItemSnapshot, "category_id=1&snapshot_date=2020-05-03T07:00:00.000Z", ""))
+	assert.Equal(t, body, "snapshot")
+
+	rs, _ := doFeedRequest(t, newFeedRequest(t, s, PathItem, "category_id=1&feed_scope=NEWLY_LISTED&date=20200504", ""))
+	assert.Equal(t, rs.StatusCode, http.StatusNoContent)
+
+	rs, _ = doFeedRequest(t, newFeedRequest(t, s, PathItemGroup, "category_id=1&feed_scope=ALL_ACTIVE", ""))
+	assert.Equal(t, rs.StatusCode, http.StatusNoContent)
+
+	rq := newFeedRequest(t, s, PathItemGroup, "category_id=1&feed_scope=ALL_ACTIVE", "")
+	rq.Header.Set(headerMarketplaceID, "EBAY_DE")
+	_, body = doFeedRequest(t, rq)
+	assert.Equal(t, body, "group")
+
+	rq = newFeedRequest(t, s, PathItem, "category_id=1", "")
+	rq.Header.Del(headerMarketplaceID)
+	rs, body = doFeedRequest(t, rq)
+	assert.Equal(t, rs.StatusCode, http.StatusBadRequest)
+	assert.Assert(t, len(body) > 0)
+
+	rs, _ = doFeedRequest(t, newFeedRequest(t, s, "item_unknown", "category_id=1", ""))
+	assert.Equal(t, rs.StatusCode, http.StatusNotFound)
+
+	assert.Equal(t, len(s.Requests()), 7)
+}
+
+func Test_IsFeedServerServingFileFixture(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ebaytest")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "feed.tsv.gz")
+	assert.NilError(t, ioutil.WriteFile(path, GzipTSV([]string{"ItemId", "Title"}, []string{"1", "Item 1"}), 0600))
+
+	s := NewFeedServer()
+	defer s.Close()
+	s.AddFeed(&Feed{Path: PathItem, File: path})
+
+	rs, err := http.DefaultClient.Do(newFeedRequest(t, s, PathItem, "category_id=1", "bytes=0-1000"))
+	assert.NilError(t, err)
+	defer rs.Body.Close()
+
+	zr, err := gzip.NewReader(rs.Body)
+	assert.NilError(t, err)
+	data, err := ioutil.ReadAll(zr)
+	assert.NilError(t, err)
+	assert.Equal(t, string(data), "ItemId\tTitle\n1\tItem 1\n")
+}
+
+func Test_IsFeedServerInjectingFaults(t *testing.T) {
+	s := NewFeedServer()
+	defer s.Close()
+
+	s.AddFeed(&Feed{Path: PathItem, Data: []byte("0123456789")})
+	s.InjectFault(RateLimited(1, time.Minute))
+	s.InjectFault(ServerError(1, http.StatusServiceUnavailable))
+	s.InjectFault(Truncated(1, 2))
+	s.InjectFault(Slow(1, 50*time.Millisecond))
+
+	rs, _ := doFeedRequest(t, newFeedRequest(t, s, PathItem, "category_id=1", "bytes=0-9"))
+	assert.Equal(t, rs.StatusCode, http.StatusTooManyRequests)
+	assert.Equal(t, rs.Header.Get(headerRetryAfter), "60")
+
+	rs, _ = doFeedRequest(t, newFeedRequest(t, s, PathItem, "category_id=1", "bytes=0-9"))
+	assert.Equal(t, rs.StatusCode, http.StatusServiceUnavailable)
+
+	rs, err := http.DefaultClient.Do(newFeedRequest(t, s, PathItem, "category_id=1", "bytes=0-9"))
+	assert.NilError(t, err)
+	data, err := ioutil.ReadAll(rs.Body)
+	rs.Body.Close()
+	assert.Assert(t, err != nil)
+	assert.Equal(t, string(data), "01")
+
+	start := time.Now()
+	rs, body := doFeedRequest(t, newFeedRequest(t, s, PathItem, "category_id=1", "bytes=0-9"))
+	assert.Assert(t, time.Since(start) >= 50*time.Millisecond)
+	assert.Equal(t, rs.StatusCode, http.StatusPartialContent)
+	assert.Equal(t, body, "0123456789")
+
+	// Faults are consumed
+	rs, _ = doFeedRequest(t, newFeedRequest(t, s, PathItem, "category_id=1", "bytes=0-9"))
+	assert.Equal(t, rs.StatusCode, http.StatusPartialContent)
+}
+
+func Test_IsFeedServerRejectingUnauthorizedRequests(t *testing.T) {
+	s := NewFeedServer()
+	defer s.Close()
+
+	s.AddFeed(&Feed{Path: PathItem, Data: []byte("0123456789")})
+	s.SetAuthorizer(func(r *http.Request) bool {
+		return r.Header.Get("Authorization") == "Bearer TOKEN"
+	})
+
+	rs, body := doFeedRequest(t, newFeedRequest(t, s, PathItem, "category_id=1", "bytes=0-9"))
+	assert.Equal(t, rs.StatusCode, http.StatusUnauthorized)
+	assert.Assert(t, strings.Contains(body, `"errorId":1001`))
+
+	rq := newFeedRequest(t, s, PathItem, "category_id=1", "bytes=0-9")
+	rq.Header.Set("Authorization", "Bearer TOKEN")
+	rs, _ = doFeedRequest(t, rq)
+	assert.Equal(t, rs.StatusCode, http.StatusPartialContent)
+}
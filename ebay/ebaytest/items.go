@@ -0,0 +1,26 @@
+package ebaytest
+
+import (
+	"bytes"
+	"ebay-api-client/ebay"
+)
+
+// ItemFeed builds the in-memory feed fixture of the items: the gzipped TSV of the ebay.ItemFields header and of
+// the item values
+func ItemFeed(items ...*ebay.Item) []byte {
+	rows := make([][]string, len(items))
+	for n, item := range items {
+		rows[n] = item.Values()
+	}
+	return GzipTSV(ebay.ItemFields, rows...)
+}
+
+// ItemReader returns an ebay.ItemReader of the feed fixture of the items, see ItemFeed
+func ItemReader(items ...*ebay.Item) *ebay.ItemReader {
+	r, err := ebay.NewItemReader(bytes.NewReader(ItemFeed(items...)))
+	if err != nil {
+		// The fixture is always a valid feed
+		panic(err)
+	}
+	return r
+}
@@ -1,7 +1,9 @@
 package ebay
 
 import (
+	"ebay-api-client/oauth2"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -85,6 +87,41 @@ func NewErrorResponse(rs *http.Response) *ErrorResponse {
 	return errorResponse
 }
 
+// IsNoContent reports whether the error is, or wraps, the ErrorResponse returned when no feed is available for the given criteria
+func IsNoContent(err error) bool {
+	var e *ErrorResponse
+	return errors.As(err, &e) && e.Response != nil && e.Response.StatusCode == http.StatusNoContent
+}
+
+// IsRateLimited reports whether the error is, or wraps, the ErrorResponse returned when the call limit has been exceeded
+func IsRateLimited(err error) bool {
+	var e *ErrorResponse
+	if !errors.As(err, &e) || e.Response == nil {
+		return false
+	}
+	return e.Response.StatusCode == http.StatusTooManyRequests || e.hasError(oauth2.RateLimitErrorID)
+}
+
+// IsAuthError reports whether the error is, or wraps, the ErrorResponse returned when the access token is not valid
+// or is not granted to call the API
+func IsAuthError(err error) bool {
+	var e *ErrorResponse
+	if !errors.As(err, &e) || e.Response == nil {
+		return false
+	}
+	return e.Response.StatusCode == http.StatusUnauthorized || e.Response.StatusCode == http.StatusForbidden
+}
+
+// hasError reports whether the response contains the error with the given id
+func (e *ErrorResponse) hasError(errorID int) bool {
+	for _, d := range e.Errors {
+		if d.ErrorID == errorID {
+			return true
+		}
+	}
+	return false
+}
+
 // HTTPRequestToString transforms the given HTTP Request in a string
 func HTTPRequestToString(rq *http.Request) string {
 	if rq == nil {
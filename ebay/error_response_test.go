@@ -1,6 +1,7 @@
 package ebay
 
 import (
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -183,3 +184,39 @@ func Test_IsErrorResponseToStringWhenHTTPRequestIsNil(t *testing.T) {
 			"Erros: [{ErrorID:13022 Domain:API_BROWSE Category:REQUEST Message:The 'category_id' 200 submitted is not supported. "+
 			"LongMessage:The 'category_id' 200 submitted is not supported. Parameters:[{Name:categoryId Value:200}]}]\nWarnings: []")
 }
+
+func Test_IsErrorResponseClassified(t *testing.T) {
+	newErrorResponse := func(status int, body string) error {
+		return NewErrorResponse(&http.Response{StatusCode: status, Body: ioutil.NopCloser(strings.NewReader(body))})
+	}
+
+	noContent := newErrorResponse(http.StatusNoContent, "")
+	assert.Assert(t, IsNoContent(noContent))
+	assert.Assert(t, !IsRateLimited(noContent))
+	assert.Assert(t, !IsAuthError(noContent))
+
+	assert.Assert(t, IsRateLimited(newErrorResponse(http.StatusTooManyRequests, "")))
+	assert.Assert(t, IsRateLimited(newErrorResponse(http.StatusInternalServerError, `{"errors": [{"errorId": 2001}]}`)))
+
+	assert.Assert(t, IsAuthError(newErrorResponse(http.StatusUnauthorized, `{"errors": [{"errorId": 1001}]}`)))
+	assert.Assert(t, IsAuthError(newErrorResponse(http.StatusForbidden, "")))
+	assert.Assert(t, !IsAuthError(newErrorResponse(http.StatusBadRequest, "")))
+
+	assert.Assert(t, !IsNoContent(fmt.Errorf("other")))
+	assert.Assert(t, !IsRateLimited(nil))
+
+	// The wrapped error responses are classified too
+	assert.Assert(t, IsNoContent(&wrappedError{noContent}))
+	assert.Assert(t, IsRateLimited(&wrappedError{newErrorResponse(http.StatusTooManyRequests, "")}))
+	assert.Assert(t, IsAuthError(&wrappedError{newErrorResponse(http.StatusUnauthorized, "")}))
+	assert.Assert(t, !IsAuthError(&wrappedError{fmt.Errorf("other")}))
+}
+
+// wrappedError wraps an error as the callers adding context do
+type wrappedError struct {
+	err error
+}
+
+func (w *wrappedError) Error() string { return "context: " + w.err.Error() }
+
+func (w *wrappedError) Unwrap() error { return w.err }
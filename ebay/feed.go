@@ -2,6 +2,7 @@ package ebay
 
 import (
 	"context"
+	"ebay-api-client/oauth2"
 	"fmt"
 	"io"
 	"net/http"
@@ -60,6 +61,7 @@ type FeedService struct {
 }
 
 // NewSandboxFeedService creates a new FeedService client pointing to eBay Sandbox environment.
+// The scopes of the HTTP client are not checked, see NewCheckedSandboxFeedService.
 func NewSandboxFeedService(httpClient HTTPClient) *FeedService {
 	return &FeedService{
 		HTTPClient: httpClient,
@@ -70,6 +72,7 @@ func NewSandboxFeedService(httpClient HTTPClient) *FeedService {
 }
 
 // NewProdFeedService creates a new FeedService client pointing to eBay Production environment.
+// The scopes of the HTTP client are not checked, see NewCheckedProdFeedService.
 func NewProdFeedService(httpClient HTTPClient) *FeedService {
 	return &FeedService{
 		HTTPClient: httpClient,
@@ -79,6 +82,30 @@ func NewProdFeedService(httpClient HTTPClient) *FeedService {
 	}
 }
 
+// NewCheckedSandboxFeedService creates a new FeedService client pointing to eBay Sandbox environment.
+// An error is returned if the HTTP client is not granted the Feed API scopes (see CheckScopes).
+func NewCheckedSandboxFeedService(httpClient HTTPClient) (*FeedService, error) {
+	return checkedFeedService(NewSandboxFeedService(httpClient))
+}
+
+// NewCheckedProdFeedService creates a new FeedService client pointing to eBay Production environment.
+// An error is returned if the HTTP client is not granted the Feed API scopes (see CheckScopes).
+func NewCheckedProdFeedService(httpClient HTTPClient) (*FeedService, error) {
+	return checkedFeedService(NewProdFeedService(httpClient))
+}
+
+func checkedFeedService(s *FeedService) (*FeedService, error) {
+	if err := CheckScopes(s.HTTPClient, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// RequiredScopes returns the oAuth2 scopes the HTTP client tokens must be granted to call the Feed API
+func (f *FeedService) RequiredScopes() []string {
+	return []string{oauth2.ScopeBuyFeedAPI}
+}
+
 // FeedInfo containts information about the feed when the download is successful
 // In case not content is found for the given feed criteria, the size will be zero
 type FeedInfo struct {
@@ -126,6 +153,27 @@ func (f *FeedService) ItemShapshot(ctx context.Context, marketID, categoryID str
 	return f.download(ctx, params, dst)
 }
 
+// WeeklyItemGroupBoostrap downloads the latest weekly item group boostrap feed for the given eBay market id and category id.
+// Item groups are the items with variations (i.e. color or size): the feed lists the group attributes shared by the variations.
+// The feed is written into the given destination which has to implement the io.Writer interface. The feed is encodedd in
+// Tab Separated Value (TSV) format and gzip compressed: it is required to gunzip the feed before reading it.
+// The function returns a FeedInfo object encoding the information abouth the downloaded file.
+// https://developer.ebay.com/api-docs/buy/feed/resources/item_group/methods/getItemGroupFeed
+func (f *FeedService) WeeklyItemGroupBoostrap(ctx context.Context, marketID, categoryID string, dst io.Writer) (*FeedInfo, error) {
+	params := &feedParams{Scope: scopeAllActive, CategoryID: categoryID, marketID: marketID, apiPath: pathGetItemGroup}
+	return f.download(ctx, params, dst)
+}
+
+// DailyNewlyItemGroups downloads the feed containing all the newly listed item groups for the given eBay market id, category id and date.
+// The feed is written into the given destination which has to implement the io.Writer interface. The feed is encodedd in
+// Tab Separated Value (TSV) format and gzip compressed: it is required to gunzip the feed before reading it.
+// The function returns a FeedInfo object encoding the information abouth the downloaded file.
+// https://developer.ebay.com/api-docs/buy/feed/resources/item_group/methods/getItemGroupFeed
+func (f *FeedService) DailyNewlyItemGroups(ctx context.Context, marketID, categoryID string, date time.Time, dst io.Writer) (*FeedInfo, error) {
+	params := &feedParams{Scope: scopeNewlyListed, CategoryID: categoryID, marketID: marketID, Date: date.Format(dateFormat), apiPath: pathGetItemGroup}
+	return f.download(ctx, params, dst)
+}
+
 // feedParams is Feed API query parameters
 type feedParams struct {
 	CategoryID   string `url:"category_id"`
@@ -147,6 +195,7 @@ func (f *FeedService) download(ctx context.Context, params *feedParams, dst io.W
 	)
 
 	info := &FeedInfo{
+		Type:       params.apiPath,
 		CategoryID: params.CategoryID,
 		Scope:      params.Scope,
 		MarketID:   params.marketID,
@@ -167,7 +216,7 @@ func (f *FeedService) download(ctx context.Context, params *feedParams, dst io.W
 			return nil, err
 		}
 
-		rq.WithContext(ctx)
+		rq = rq.WithContext(ctx)
 
 		rs, err := f.HTTPClient.Do(rq)
 		if err != nil {
@@ -80,77 +80,78 @@ const (
 // Item represents an eBay Listing Item from the Feed.
 // Note that no manipulation is done here: the values are extracted directly from the Feed file.
 // Check https://developer.ebay.com/api-docs/buy/feed/resources/item/methods/getItemFeed for details.
+// The json tags are the camel case names of the Buy APIs and the csv tags are the field names (see ItemFields).
 type Item struct {
-	ID                            string
-	Title                         string
-	ImageURL                      string
-	Category                      string
-	CategoryID                    string
-	BuyingOptions                 string
-	SellerUsername                string
-	SellerFeedbackPercentage      string
-	SellerFeedbackScore           string
-	GTIN                          string
-	Brand                         string
-	MPN                           string
-	EPID                          string
-	ConditionID                   string
-	Condition                     string
-	PriceValue                    string
-	PriceCurrency                 string
-	PrimaryItemGroupID            string
-	PrimaryItemGroupType          string
-	EndDate                       string
-	SellerItemRevision            string
-	LocationCountry               string
-	LocalizedAspects              string
-	SellerTrustLevel              string
-	Availability                  string
-	ImageAlteringProhibited       string
-	EstimatedAvailableQuantity    string
-	AvailabilityThresholdType     string
-	AvailabilityThreshold         string
-	ReturnsAccepted               string
-	ReturnPeriodValue             string
-	ReturnPeriodUnit              string
-	RefundMethod                  string
-	ReturnMethod                  string
-	ReturnShippingCostPayer       string
-	RestockingFeePercentage       string
-	AcceptedPaymentMethods        string
-	DeliveryOptions               string
-	ShipToIncludedRegions         string
-	ShipToExcludedRegions         string
-	InferredEPID                  string
-	InferredGTIN                  string
-	InferredBrand                 string
-	InferredMPN                   string
-	InferredLocalizedAspects      string
-	AdditionalImages              string
-	OriginalPriceValue            string
-	OriginalPriceCurrency         string
-	DiscountAmount                string
-	DiscountPercentage            string
-	EnergyEfficiencyClass         string
-	QualifiedPrograms             string
-	LotSize                       string
-	LengthUnitOfMeasure           string
-	PackageWidth                  string
-	PackageHeight                 string
-	PackageLength                 string
-	WeightUnitOfMeasure           string
-	PackageWeight                 string
-	ShippingCarrierCode           string
-	ShippingServiceCode           string
-	ShippingType                  string
-	ShippingCost                  string
-	ShippingCostType              string
-	AdditionalShippingCostPerUnit string
-	QuantityUsedForEstimate       string
-	UnitPrice                     string
-	UnitPricingMeasure            string
-	LegacyItemID                  string
-	Alerts                        string
+	ID                            string `json:"itemId" csv:"ID"`
+	Title                         string `json:"title" csv:"Title"`
+	ImageURL                      string `json:"imageUrl" csv:"ImageURL"`
+	Category                      string `json:"category" csv:"Category"`
+	CategoryID                    string `json:"categoryId" csv:"CategoryID"`
+	BuyingOptions                 string `json:"buyingOptions" csv:"BuyingOptions"`
+	SellerUsername                string `json:"sellerUsername" csv:"SellerUsername"`
+	SellerFeedbackPercentage      string `json:"sellerFeedbackPercentage" csv:"SellerFeedbackPercentage"`
+	SellerFeedbackScore           string `json:"sellerFeedbackScore" csv:"SellerFeedbackScore"`
+	GTIN                          string `json:"gtin" csv:"GTIN"`
+	Brand                         string `json:"brand" csv:"Brand"`
+	MPN                           string `json:"mpn" csv:"MPN"`
+	EPID                          string `json:"epid" csv:"EPID"`
+	ConditionID                   string `json:"conditionId" csv:"ConditionID"`
+	Condition                     string `json:"condition" csv:"Condition"`
+	PriceValue                    string `json:"priceValue" csv:"PriceValue"`
+	PriceCurrency                 string `json:"priceCurrency" csv:"PriceCurrency"`
+	PrimaryItemGroupID            string `json:"primaryItemGroupId" csv:"PrimaryItemGroupID"`
+	PrimaryItemGroupType          string `json:"primaryItemGroupType" csv:"PrimaryItemGroupType"`
+	EndDate                       string `json:"endDate" csv:"EndDate"`
+	SellerItemRevision            string `json:"sellerItemRevision" csv:"SellerItemRevision"`
+	LocationCountry               string `json:"locationCountry" csv:"LocationCountry"`
+	LocalizedAspects              string `json:"localizedAspects" csv:"LocalizedAspects"`
+	SellerTrustLevel              string `json:"sellerTrustLevel" csv:"SellerTrustLevel"`
+	Availability                  string `json:"availability" csv:"Availability"`
+	ImageAlteringProhibited       string `json:"imageAlteringProhibited" csv:"ImageAlteringProhibited"`
+	EstimatedAvailableQuantity    string `json:"estimatedAvailableQuantity" csv:"EstimatedAvailableQuantity"`
+	AvailabilityThresholdType     string `json:"availabilityThresholdType" csv:"AvailabilityThresholdType"`
+	AvailabilityThreshold         string `json:"availabilityThreshold" csv:"AvailabilityThreshold"`
+	ReturnsAccepted               string `json:"returnsAccepted" csv:"ReturnsAccepted"`
+	ReturnPeriodValue             string `json:"returnPeriodValue" csv:"ReturnPeriodValue"`
+	ReturnPeriodUnit              string `json:"returnPeriodUnit" csv:"ReturnPeriodUnit"`
+	RefundMethod                  string `json:"refundMethod" csv:"RefundMethod"`
+	ReturnMethod                  string `json:"returnMethod" csv:"ReturnMethod"`
+	ReturnShippingCostPayer       string `json:"returnShippingCostPayer" csv:"ReturnShippingCostPayer"`
+	RestockingFeePercentage       string `json:"restockingFeePercentage" csv:"RestockingFeePercentage"`
+	AcceptedPaymentMethods        string `json:"acceptedPaymentMethods" csv:"AcceptedPaymentMethods"`
+	DeliveryOptions               string `json:"deliveryOptions" csv:"DeliveryOptions"`
+	ShipToIncludedRegions         string `json:"shipToIncludedRegions" csv:"ShipToIncludedRegions"`
+	ShipToExcludedRegions         string `json:"shipToExcludedRegions" csv:"ShipToExcludedRegions"`
+	InferredEPID                  string `json:"inferredEpid" csv:"InferredEPID"`
+	InferredGTIN                  string `json:"inferredGtin" csv:"InferredGTIN"`
+	InferredBrand                 string `json:"inferredBrand" csv:"InferredBrand"`
+	InferredMPN                   string `json:"inferredMpn" csv:"InferredMPN"`
+	InferredLocalizedAspects      string `json:"inferredLocalizedAspects" csv:"InferredLocalizedAspects"`
+	AdditionalImages              string `json:"additionalImages" csv:"AdditionalImages"`
+	OriginalPriceValue            string `json:"originalPriceValue" csv:"OriginalPriceValue"`
+	OriginalPriceCurrency         string `json:"originalPriceCurrency" csv:"OriginalPriceCurrency"`
+	DiscountAmount                string `json:"discountAmount" csv:"DiscountAmount"`
+	DiscountPercentage            string `json:"discountPercentage" csv:"DiscountPercentage"`
+	EnergyEfficiencyClass         string `json:"energyEfficiencyClass" csv:"EnergyEfficiencyClass"`
+	QualifiedPrograms             string `json:"qualifiedPrograms" csv:"QualifiedPrograms"`
+	LotSize                       string `json:"lotSize" csv:"LotSize"`
+	LengthUnitOfMeasure           string `json:"lengthUnitOfMeasure" csv:"LengthUnitOfMeasure"`
+	PackageWidth                  string `json:"packageWidth" csv:"PackageWidth"`
+	PackageHeight                 string `json:"packageHeight" csv:"PackageHeight"`
+	PackageLength                 string `json:"packageLength" csv:"PackageLength"`
+	WeightUnitOfMeasure           string `json:"weightUnitOfMeasure" csv:"WeightUnitOfMeasure"`
+	PackageWeight                 string `json:"packageWeight" csv:"PackageWeight"`
+	ShippingCarrierCode           string `json:"shippingCarrierCode" csv:"ShippingCarrierCode"`
+	ShippingServiceCode           string `json:"shippingServiceCode" csv:"ShippingServiceCode"`
+	ShippingType                  string `json:"shippingType" csv:"ShippingType"`
+	ShippingCost                  string `json:"shippingCost" csv:"ShippingCost"`
+	ShippingCostType              string `json:"shippingCostType" csv:"ShippingCostType"`
+	AdditionalShippingCostPerUnit string `json:"additionalShippingCostPerUnit" csv:"AdditionalShippingCostPerUnit"`
+	QuantityUsedForEstimate       string `json:"quantityUsedForEstimate" csv:"QuantityUsedForEstimate"`
+	UnitPrice                     string `json:"unitPrice" csv:"UnitPrice"`
+	UnitPricingMeasure            string `json:"unitPricingMeasure" csv:"UnitPricingMeasure"`
+	LegacyItemID                  string `json:"legacyItemId" csv:"LegacyItemID"`
+	Alerts                        string `json:"alerts" csv:"Alerts"`
 }
 
 // NewItemFromTSV creates a new Item from its TSV definition as given in Feed file.
@@ -85,3 +85,20 @@ func Test_IsNewItemFromTSV(t *testing.T) {
 	item := NewItemFromTSV(tsv)
 	assert.DeepEqual(t, expItem, *item)
 }
+
+func Test_IsItemFieldReadByName(t *testing.T) {
+	item := &Item{ID: "v1|1|0", PriceValue: "10.5", Alerts: "ALERT"}
+
+	value, ok := item.Field("pricevalue")
+	assert.Assert(t, ok)
+	assert.Equal(t, value, "10.5")
+
+	_, ok = item.Field("Price")
+	assert.Assert(t, !ok)
+
+	values := item.Values()
+	assert.Equal(t, len(values), len(ItemFields))
+	assert.Equal(t, values[indexID], "v1|1|0")
+	assert.Equal(t, values[indexAlerts], "ALERT")
+	assert.Equal(t, ItemFields[indexPriceValue], "PriceValue")
+}
@@ -0,0 +1,134 @@
+package ebay
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+const (
+	// maxItemLineSize is the maximum size of a feed row, the items with many aspects and images have long rows
+	maxItemLineSize int = 16 * 1024 * 1024
+)
+
+// ItemFields are the names of the Item fields, in the order of the feed columns
+var ItemFields = itemFields()
+
+func itemFields() []string {
+	t := reflect.TypeOf(Item{})
+	fields := make([]string, t.NumField())
+	for i := range fields {
+		fields[i] = t.Field(i).Name
+	}
+	return fields
+}
+
+// Field returns the value of the Item field with the given name (see ItemFields).
+// The name is case insensitive. False is returned if the field does not exist.
+func (i *Item) Field(name string) (string, bool) {
+	v := reflect.ValueOf(i).Elem()
+	for n, field := range ItemFields {
+		if strings.EqualFold(field, name) {
+			return v.Field(n).String(), true
+		}
+	}
+	return "", false
+}
+
+// Values returns the values of the Item fields, in the order of ItemFields
+func (i *Item) Values() []string {
+	v := reflect.ValueOf(i).Elem()
+	values := make([]string, v.NumField())
+	for n := range values {
+		values[n] = v.Field(n).String()
+	}
+	return values
+}
+
+// ItemIterator is a stream of items, i.e. ItemReader, ParallelItemReader or filter.Reader.
+// Next returns io.EOF when there are no more items.
+type ItemIterator interface {
+	Next() (*Item, error)
+}
+
+// ItemReader reads the Items of a feed file: a gzip compressed TSV whose first row is the columns header.
+// Next returns the items one at a time, so that feeds bigger than the memory can be processed.
+type ItemReader struct {
+	gz      *gzip.Reader
+	scanner *bufio.Scanner
+	header  []string
+	line    int
+	text    string
+}
+
+// NewItemReader creates a new ItemReader reading the gzipped feed from r and reads the columns header
+func NewItemReader(r io.Reader) (*ItemReader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("NewItemReader(): cannot gunzip feed: %v", err)
+	}
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), maxItemLineSize)
+
+	ir := &ItemReader{gz: gz, scanner: scanner}
+
+	if !scanner.Scan() {
+		gz.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("NewItemReader(): cannot read feed header: %v", err)
+		}
+		return nil, fmt.Errorf("NewItemReader(): feed is empty")
+	}
+
+	ir.line = 1
+	ir.header = strings.Split(strings.TrimRight(scanner.Text(), "\r"), "\t")
+
+	return ir, nil
+}
+
+// Header returns the columns header of the feed
+func (r *ItemReader) Header() []string {
+	return r.header
+}
+
+// Next returns the next Item of the feed. The error is io.EOF when there are no more items.
+// Blank rows are skipped.
+func (r *ItemReader) Next() (*Item, error) {
+	for r.scanner.Scan() {
+		r.line++
+		r.text = strings.TrimRight(r.scanner.Text(), "\r")
+		if strings.TrimSpace(r.text) == "" {
+			continue
+		}
+		return NewItemFromTSV(r.text), nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Next(): cannot read feed at line %v: %v", r.line+1, err)
+	}
+	return nil, io.EOF
+}
+
+// Text returns the TSV row of the last Item returned by Next
+func (r *ItemReader) Text() string {
+	return r.text
+}
+
+// Columns returns the number of columns of the last Item returned by Next
+func (r *ItemReader) Columns() int {
+	return strings.Count(r.text, "\t") + 1
+}
+
+// Line returns the line number in the feed of the last Item returned by Next, the header being line 1
+func (r *ItemReader) Line() int {
+	return r.line
+}
+
+// Close releases the gzip reader. It does not close the underlying reader.
+func (r *ItemReader) Close() error {
+	return r.gz.Close()
+}
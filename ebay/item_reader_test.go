@@ -0,0 +1,50 @@
+package ebay_test
+
+import (
+	"bytes"
+	"ebay-api-client/ebay"
+	"ebay-api-client/ebay/ebaytest"
+	"io"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func Test_IsItemReaderReadingFeed(t *testing.T) {
+	data := ebaytest.GzipTSV([]string{"ItemId", "Title", "ImageUrl"},
+		[]string{"v1|1|0", "Item 1", "http://image/1"},
+		[]string{""},
+		[]string{"v1|2|0", "Item 2"},
+	)
+
+	r, err := ebay.NewItemReader(bytes.NewReader(data))
+	assert.NilError(t, err)
+	defer r.Close()
+
+	assert.DeepEqual(t, r.Header(), []string{"ItemId", "Title", "ImageUrl"})
+
+	item, err := r.Next()
+	assert.NilError(t, err)
+	assert.Equal(t, item.ID, "v1|1|0")
+	assert.Equal(t, item.ImageURL, "http://image/1")
+	assert.Equal(t, r.Line(), 2)
+	assert.Equal(t, r.Columns(), 3)
+
+	item, err = r.Next()
+	assert.NilError(t, err)
+	assert.Equal(t, item.ID, "v1|2|0")
+	assert.Equal(t, r.Text(), "v1|2|0\tItem 2")
+	assert.Equal(t, r.Line(), 4)
+	assert.Equal(t, r.Columns(), 2)
+
+	_, err = r.Next()
+	assert.Equal(t, err, io.EOF)
+}
+
+func Test_IsNewItemReaderReturningErrorIfInvalidFeed(t *testing.T) {
+	_, err := ebay.NewItemReader(bytes.NewReader([]byte("not gzipped")))
+	assert.ErrorContains(t, err, "cannot gunzip feed")
+
+	_, err = ebay.NewItemReader(bytes.NewReader(ebaytest.GzipTSV(nil)[:0]))
+	assert.ErrorContains(t, err, "cannot gunzip feed")
+}
@@ -1,50 +0,0 @@
-// Code generated by MockGen. DO NOT EDIT.
-// Source: types.go
-
-// Package mock_ebay is a generated GoMock package.
-package mock_ebay
-
-import (
-	http "net/http"
-	reflect "reflect"
-
-	gomock "github.com/golang/mock/gomock"
-)
-
-// MockHTTPClient is a mock of HTTPClient interface
-type MockHTTPClient struct {
-	ctrl     *gomock.Controller
-	recorder *MockHTTPClientMockRecorder
-}
-
-// MockHTTPClientMockRecorder is the mock recorder for MockHTTPClient
-type MockHTTPClientMockRecorder struct {
-	mock *MockHTTPClient
-}
-
-// NewMockHTTPClient creates a new mock instance
-func NewMockHTTPClient(ctrl *gomock.Controller) *MockHTTPClient {
-	mock := &MockHTTPClient{ctrl: ctrl}
-	mock.recorder = &MockHTTPClientMockRecorder{mock}
-	return mock
-}
-
-// EXPECT returns an object that allows the caller to indicate expected use
-func (m *MockHTTPClient) EXPECT() *MockHTTPClientMockRecorder {
-	return m.recorder
-}
-
-// Do mocks base method
-func (m *MockHTTPClient) Do(req *http.Request) (*http.Response, error) {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Do", req)
-	ret0, _ := ret[0].(*http.Response)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
-}
-
-// Do indicates an expected call of Do
-func (mr *MockHTTPClientMockRecorder) Do(req interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Do", reflect.TypeOf((*MockHTTPClient)(nil).Do), req)
-}
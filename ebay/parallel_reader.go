@@ -0,0 +1,259 @@
+package ebay
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+const (
+	// defaultChunkSize is the number of rows parsed at once by a worker of a ParallelItemReader
+	defaultChunkSize int = 512
+)
+
+// ParallelOptions tunes a ParallelItemReader
+type ParallelOptions struct {
+	// Workers is the number of goroutines parsing the rows, defaults to the number of CPUs
+	Workers int
+	// Ordered returns the items in the order of the feed, at the cost of buffering the chunks parsed out of order
+	Ordered bool
+	// ChunkSize is the number of rows handed to a worker at once, defaults to 512
+	ChunkSize int
+}
+
+// chunk is a group of consecutive feed rows and their line numbers, parsed by a worker
+type chunk struct {
+	seq   int
+	rows  []string
+	lines []int
+	items []*Item
+	err   error
+}
+
+// ParallelItemReader reads the Items of a feed file like ItemReader, parsing the rows concurrently: one goroutine
+// gunzips the feed and splits the rows in chunks, the workers parse the chunks with NewItemFromTSV.
+// The items are returned in the order of the feed if ParallelOptions.Ordered is set, otherwise in the order the
+// chunks are parsed. Next must be called from a single goroutine, and Close releases the goroutines.
+type ParallelItemReader struct {
+	gz      *gzip.Reader
+	header  []string
+	ordered bool
+
+	results chan *chunk
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	once    sync.Once
+
+	// next is the sequence number of the next chunk to return, pending are the chunks parsed ahead of it
+	next    int
+	pending map[int]*chunk
+	current *chunk
+	pos     int
+	failed  error
+	err     error
+}
+
+// NewParallelItemReader creates a new ParallelItemReader reading the gzipped feed from r, reads the columns header
+// and starts the goroutines
+func NewParallelItemReader(r io.Reader, opts *ParallelOptions) (*ParallelItemReader, error) {
+	if opts == nil {
+		opts = &ParallelOptions{}
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	chunkSize := opts.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("NewParallelItemReader(): cannot gunzip feed: %v", err)
+	}
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), maxItemLineSize)
+	if !scanner.Scan() {
+		gz.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("NewParallelItemReader(): cannot read feed header: %v", err)
+		}
+		return nil, fmt.Errorf("NewParallelItemReader(): feed is empty")
+	}
+
+	pr := &ParallelItemReader{
+		gz:      gz,
+		header:  strings.Split(strings.TrimRight(scanner.Text(), "\r"), "\t"),
+		ordered: opts.Ordered,
+		results: make(chan *chunk, workers),
+		stop:    make(chan struct{}),
+		pending: make(map[int]*chunk),
+	}
+
+	// The bounded channels keep at most 2 * workers chunks in memory besides the chunks buffered for the order
+	jobs := make(chan *chunk, workers)
+	pr.wg.Add(1)
+	go pr.split(scanner, chunkSize, jobs)
+
+	var parsers sync.WaitGroup
+	for n := 0; n < workers; n++ {
+		parsers.Add(1)
+		go pr.parse(jobs, &parsers)
+	}
+	go func() {
+		parsers.Wait()
+		close(pr.results)
+	}()
+
+	return pr, nil
+}
+
+// split reads the rows in chunks until the end of the feed. A read error is sent as the last chunk.
+func (pr *ParallelItemReader) split(scanner *bufio.Scanner, chunkSize int, jobs chan<- *chunk) {
+	defer pr.wg.Done()
+	defer close(jobs)
+
+	line := 1
+	c := &chunk{rows: make([]string, 0, chunkSize), lines: make([]int, 0, chunkSize)}
+	send := func() bool {
+		select {
+		case jobs <- c:
+			c = &chunk{seq: c.seq + 1, rows: make([]string, 0, chunkSize), lines: make([]int, 0, chunkSize)}
+			return true
+		case <-pr.stop:
+			return false
+		}
+	}
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		c.rows = append(c.rows, text)
+		c.lines = append(c.lines, line)
+		if len(c.rows) == chunkSize && !send() {
+			return
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		c.err = fmt.Errorf("Next(): cannot read feed at line %v: %v", line+1, err)
+	}
+	if len(c.rows) > 0 || c.err != nil {
+		send()
+	}
+}
+
+// parse parses the rows of the chunks and sends the chunks to the results
+func (pr *ParallelItemReader) parse(jobs <-chan *chunk, parsers *sync.WaitGroup) {
+	defer parsers.Done()
+
+	for c := range jobs {
+		c.items = make([]*Item, len(c.rows))
+		for n, row := range c.rows {
+			c.items[n] = NewItemFromTSV(row)
+		}
+
+		select {
+		case pr.results <- c:
+		case <-pr.stop:
+			return
+		}
+	}
+}
+
+// Header returns the columns header of the feed
+func (pr *ParallelItemReader) Header() []string {
+	return pr.header
+}
+
+// Next returns the next Item of the feed. The error is io.EOF when there are no more items.
+// Blank rows are skipped.
+func (pr *ParallelItemReader) Next() (*Item, error) {
+	for pr.current == nil || pr.pos == len(pr.current.items) {
+		if pr.err != nil {
+			return nil, pr.err
+		}
+
+		c, ok := pr.receive()
+		if !ok {
+			pr.err = io.EOF
+			if pr.failed != nil {
+				pr.err = pr.failed
+			}
+			continue
+		}
+		// The read error is returned after the items of all the chunks
+		if c.err != nil {
+			pr.failed = c.err
+		}
+		pr.current, pr.pos = c, 0
+	}
+
+	// The row of the previous item is released, the row of the returned one is kept for Text
+	if pr.pos > 0 {
+		pr.current.rows[pr.pos-1] = ""
+	}
+	item := pr.current.items[pr.pos]
+	pr.current.items[pr.pos] = nil
+	pr.pos++
+	return item, nil
+}
+
+// Text returns the TSV row of the last Item returned by Next
+func (pr *ParallelItemReader) Text() string {
+	if pr.current == nil || pr.pos == 0 {
+		return ""
+	}
+	return pr.current.rows[pr.pos-1]
+}
+
+// Columns returns the number of columns of the last Item returned by Next
+func (pr *ParallelItemReader) Columns() int {
+	return strings.Count(pr.Text(), "\t") + 1
+}
+
+// Line returns the line number in the feed of the last Item returned by Next, the header being line 1
+func (pr *ParallelItemReader) Line() int {
+	if pr.current == nil || pr.pos == 0 {
+		return 0
+	}
+	return pr.current.lines[pr.pos-1]
+}
+
+// receive returns the next chunk to read, false when all the chunks have been read
+func (pr *ParallelItemReader) receive() (*chunk, bool) {
+	if !pr.ordered {
+		c, ok := <-pr.results
+		return c, ok
+	}
+
+	for {
+		if c, ok := pr.pending[pr.next]; ok {
+			delete(pr.pending, pr.next)
+			pr.next++
+			return c, true
+		}
+
+		c, ok := <-pr.results
+		if !ok {
+			return nil, false
+		}
+		pr.pending[c.seq] = c
+	}
+}
+
+// Close stops the goroutines and releases the gzip reader. It does not close the underlying reader.
+func (pr *ParallelItemReader) Close() error {
+	pr.once.Do(func() { close(pr.stop) })
+	pr.wg.Wait()
+	return pr.gz.Close()
+}
@@ -0,0 +1,219 @@
+package ebay_test
+
+import (
+	"bytes"
+	"ebay-api-client/ebay"
+	"ebay-api-client/ebay/ebaytest"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+// benchmarkRows is the number of rows of the benchmarked feed
+const benchmarkRows = 20000
+
+// column returns the index of the Item field in the feed rows
+func column(name string) int {
+	for n, field := range ebay.ItemFields {
+		if field == name {
+			return n
+		}
+	}
+	panic("unknown field " + name)
+}
+
+// testFeed returns a gzipped feed of n rows with all the Item columns filled, like the rows of a bootstrap
+func testFeed(n int) []byte {
+	rows := make([][]string, n)
+	aspects := base64.StdEncoding.EncodeToString([]byte("Color")) + ":" + base64.StdEncoding.EncodeToString([]byte("Red")) + ";" +
+		base64.StdEncoding.EncodeToString([]byte("Storage Capacity")) + ":" + base64.StdEncoding.EncodeToString([]byte("64 GB"))
+	for i := range rows {
+		row := make([]string, len(ebay.ItemFields))
+		for f := range row {
+			row[f] = fmt.Sprintf(" %v %v ", ebay.ItemFields[f], i)
+		}
+		row[column("ID")] = fmt.Sprintf("v1|%v|0", i+1)
+		row[column("Title")] = fmt.Sprintf("Apple iPhone 8 %v GB Red Unlocked Smartphone", i%256)
+		row[column("LocalizedAspects")] = aspects
+		row[column("AdditionalImages")] = strings.Repeat("https://i.ebayimg.com/images/g/abc/s-l1600.jpg|", 8)
+		rows[i] = row
+	}
+	return ebaytest.GzipTSV(ebay.ItemFields, rows...)
+}
+
+// readAll returns the ids of the items and the error ending the feed
+func readAll(next func() (*ebay.Item, error)) ([]string, error) {
+	var ids []string
+	for {
+		item, err := next()
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, item.ID)
+	}
+}
+
+func Test_IsParallelItemReaderReadingFeed(t *testing.T) {
+	data := testFeed(1000)
+
+	r, err := ebay.NewItemReader(bytes.NewReader(data))
+	assert.NilError(t, err)
+	want, err := readAll(r.Next)
+	assert.Equal(t, err, io.EOF)
+
+	pr, err := ebay.NewParallelItemReader(bytes.NewReader(data), &ebay.ParallelOptions{Workers: 4, ChunkSize: 7, Ordered: true})
+	assert.NilError(t, err)
+	defer pr.Close()
+	assert.DeepEqual(t, pr.Header(), ebay.ItemFields)
+
+	got, err := readAll(pr.Next)
+	assert.Equal(t, err, io.EOF)
+	assert.DeepEqual(t, got, want)
+	assert.Equal(t, pr.Line(), r.Line())
+	assert.Equal(t, pr.Text(), r.Text())
+	_, err = pr.Next()
+	assert.Equal(t, err, io.EOF)
+
+	// Unordered, the chunks are returned as they are parsed
+	pr, err = ebay.NewParallelItemReader(bytes.NewReader(data), &ebay.ParallelOptions{Workers: 4, ChunkSize: 7})
+	assert.NilError(t, err)
+	defer pr.Close()
+
+	got, err = readAll(pr.Next)
+	assert.Equal(t, err, io.EOF)
+	sort.Strings(got)
+	sort.Strings(want)
+	assert.DeepEqual(t, got, want)
+}
+
+func Test_IsParallelItemReaderParsingLikeItemReader(t *testing.T) {
+	data := ebaytest.GzipTSV([]string{"ItemId", "Title", "ImageUrl"},
+		[]string{"v1|1|0", " Item 1 ", "http://image/1"},
+		[]string{""},
+		[]string{"v1|2|0", "Item 2"},
+	)
+
+	pr, err := ebay.NewParallelItemReader(bytes.NewReader(data), nil)
+	assert.NilError(t, err)
+	defer pr.Close()
+
+	item, err := pr.Next()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, item, &ebay.Item{ID: "v1|1|0", Title: "Item 1", ImageURL: "http://image/1"})
+	assert.Equal(t, pr.Text(), "v1|1|0\t Item 1 \thttp://image/1")
+	assert.Equal(t, pr.Columns(), 3)
+	assert.Equal(t, pr.Line(), 2)
+	item, err = pr.Next()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, item, &ebay.Item{ID: "v1|2|0", Title: "Item 2"})
+	assert.Equal(t, pr.Text(), "v1|2|0\tItem 2")
+	assert.Equal(t, pr.Columns(), 2)
+	assert.Equal(t, pr.Line(), 4)
+	_, err = pr.Next()
+	assert.Equal(t, err, io.EOF)
+
+	_, err = ebay.NewParallelItemReader(bytes.NewReader([]byte("not gzipped")), nil)
+	assert.ErrorContains(t, err, "cannot gunzip feed")
+}
+
+func Test_IsParallelItemReaderReturningReadErrors(t *testing.T) {
+	data := testFeed(2000)
+	truncated := data[:len(data)/2]
+
+	r, err := ebay.NewItemReader(bytes.NewReader(truncated))
+	assert.NilError(t, err)
+	want, err := readAll(r.Next)
+	assert.ErrorContains(t, err, "cannot read feed at line")
+
+	// The items read before the error are returned first
+	pr, err := ebay.NewParallelItemReader(bytes.NewReader(truncated), &ebay.ParallelOptions{Workers: 3, ChunkSize: 10, Ordered: true})
+	assert.NilError(t, err)
+	defer pr.Close()
+
+	got, err := readAll(pr.Next)
+	assert.ErrorContains(t, err, "cannot read feed at line")
+	assert.DeepEqual(t, got, want)
+}
+
+func Test_IsParallelItemReaderClosedEarly(t *testing.T) {
+	pr, err := ebay.NewParallelItemReader(bytes.NewReader(testFeed(5000)), &ebay.ParallelOptions{Workers: 2, ChunkSize: 10, Ordered: true})
+	assert.NilError(t, err)
+
+	_, err = pr.Next()
+	assert.NilError(t, err)
+	assert.NilError(t, pr.Close())
+	assert.NilError(t, pr.Close())
+}
+
+func BenchmarkNewItemFromTSV(b *testing.B) {
+	r, err := ebay.NewItemReader(bytes.NewReader(testFeed(1)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err := r.Next(); err != nil {
+		b.Fatal(err)
+	}
+	row := r.Text()
+
+	b.SetBytes(int64(len(row)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		ebay.NewItemFromTSV(row)
+	}
+}
+
+// itemIterator is implemented by ItemReader and ParallelItemReader
+type itemIterator interface {
+	Next() (*ebay.Item, error)
+	Close() error
+}
+
+// benchmarkReader reads the benchmarked feed with the reader created by open
+func benchmarkReader(b *testing.B, open func(r io.Reader) (itemIterator, error)) {
+	data := testFeed(benchmarkRows)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		it, err := open(bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+		read := 0
+		for ; ; read++ {
+			if _, err := it.Next(); err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+		}
+		it.Close()
+		if read != benchmarkRows {
+			b.Fatalf("read %v items, want %v", read, benchmarkRows)
+		}
+	}
+}
+
+func BenchmarkItemReader(b *testing.B) {
+	benchmarkReader(b, func(r io.Reader) (itemIterator, error) {
+		return ebay.NewItemReader(r)
+	})
+}
+
+func BenchmarkParallelItemReader(b *testing.B) {
+	for _, ordered := range []bool{true, false} {
+		for _, workers := range []int{1, 2, 4, 8} {
+			b.Run(fmt.Sprintf("ordered=%v/workers=%v", ordered, workers), func(b *testing.B) {
+				benchmarkReader(b, func(r io.Reader) (itemIterator, error) {
+					return ebay.NewParallelItemReader(r, &ebay.ParallelOptions{Workers: workers, Ordered: ordered})
+				})
+			})
+		}
+	}
+}
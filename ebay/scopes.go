@@ -0,0 +1,43 @@
+package ebay
+
+import (
+	"ebay-api-client/oauth2"
+	"fmt"
+	"net/http"
+)
+
+// ScopedService is an eBay API service declaring the oAuth2 scopes it requires
+type ScopedService interface {
+	// RequiredScopes returns the scopes the HTTP client tokens must be granted to call the API
+	RequiredScopes() []string
+}
+
+// RequiredScopes returns the scopes required by all the given services, without duplicates.
+// It can be used to request the scopes when creating the HTTP client shared by the services.
+func RequiredScopes(services ...ScopedService) []string {
+	seen := make(map[string]bool)
+	var scopes []string
+	for _, s := range services {
+		for _, scope := range s.RequiredScopes() {
+			if !seen[scope] {
+				seen[scope] = true
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return scopes
+}
+
+// CheckScopes returns an error if the HTTP client is not granted the scopes required by the service.
+// Only the HTTP clients created by the oauth2 package can be checked, the others are assumed to be valid.
+func CheckScopes(httpClient HTTPClient, service ScopedService) error {
+	c, ok := httpClient.(*http.Client)
+	if !ok {
+		return nil
+	}
+
+	if err := oauth2.CheckScopes(c, service.RequiredScopes()); err != nil {
+		return fmt.Errorf("CheckScopes(): %T cannot be used: %v", service, err)
+	}
+	return nil
+}
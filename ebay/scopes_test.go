@@ -0,0 +1,39 @@
+package ebay
+
+import (
+	"context"
+	"ebay-api-client/oauth2"
+	"net/http"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func Test_RequiredScopes(t *testing.T) {
+	scopes := RequiredScopes(NewProdFeedService(nil), NewProdAnalyticsService(nil), NewSandboxFeedService(nil))
+	assert.DeepEqual(t, scopes, []string{oauth2.ScopeBuyFeedAPI, oauth2.ScopeAPI})
+}
+
+func Test_IsCheckScopesReturningErrorIfScopeMissing(t *testing.T) {
+	c, err := oauth2.NewClientCredentialsClient(context.Background(), oauth2.NewStaticCredentials("ID", "SECRET"), oauth2.SandBoxEndpoint.TokenURL, []string{oauth2.ScopeAPI})
+	assert.NilError(t, err)
+
+	err = CheckScopes(c, NewSandboxFeedService(c))
+	assert.Error(t, err, "CheckScopes(): *ebay.FeedService cannot be used: CheckScopes(): client is missing the required scopes: "+oauth2.ScopeBuyFeedAPI)
+
+	assert.NilError(t, CheckScopes(c, NewSandboxAnalyticsService(c)))
+
+	_, err = NewCheckedSandboxFeedService(c)
+	assert.ErrorContains(t, err, "client is missing the required scopes: "+oauth2.ScopeBuyFeedAPI)
+	_, err = NewCheckedProdFeedService(c)
+	assert.ErrorContains(t, err, "client is missing the required scopes: "+oauth2.ScopeBuyFeedAPI)
+}
+
+func Test_IsCheckScopesIgnoringUnknownClients(t *testing.T) {
+	assert.NilError(t, CheckScopes(http.DefaultClient, NewSandboxFeedService(nil)))
+	assert.NilError(t, CheckScopes(nil, NewSandboxFeedService(nil)))
+
+	s, err := NewCheckedProdFeedService(http.DefaultClient)
+	assert.NilError(t, err)
+	assert.Equal(t, s.BaseURL, DefaultProdBaseURL)
+}
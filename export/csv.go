@@ -0,0 +1,78 @@
+package export
+
+import (
+	"ebay-api-client/ebay"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CSVWriter writes the items as RFC 4180 CSV records, after a header record of the csv tags of the selected fields.
+// The typed lists are joined with '|'.
+type CSVWriter struct {
+	out     *output
+	csv     *csv.Writer
+	columns []column
+	typed   bool
+	record  []string
+}
+
+// NewCSVWriter creates a new CSVWriter writing to w and writes the header record
+func NewCSVWriter(w io.Writer, opts *Options) (*CSVWriter, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	columns, err := columns(opts.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("NewCSVWriter(): %v", err)
+	}
+
+	out := newOutput(w, opts.Gzip)
+	c := &CSVWriter{out: out, csv: csv.NewWriter(out), columns: columns, typed: opts.Typed, record: make([]string, len(columns))}
+
+	for n, column := range columns {
+		c.record[n] = column.csv
+	}
+	if err := c.csv.Write(c.record); err != nil {
+		return nil, fmt.Errorf("NewCSVWriter(): cannot write header: %v", err)
+	}
+	return c, nil
+}
+
+// Write writes the item as a CSV record
+func (c *CSVWriter) Write(item *ebay.Item) error {
+	for n, column := range c.columns {
+		c.record[n] = column.raw(item)
+		if c.typed {
+			c.record[n] = formatTyped(column.typed(c.record[n]))
+		}
+	}
+	return c.csv.Write(c.record)
+}
+
+// Close flushes the buffered items and terminates the gzip stream. It does not close the underlying writer.
+func (c *CSVWriter) Close() error {
+	c.csv.Flush()
+	if err := c.csv.Error(); err != nil {
+		return err
+	}
+	return c.out.close()
+}
+
+// formatTyped formats a typed value as a CSV field
+func formatTyped(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []string:
+		return strings.Join(v, "|")
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,195 @@
+// Package export writes item streams, i.e. the items of an ebay.ItemReader or a filter.Reader, as JSON Lines,
+// RFC 4180 CSV or TSV. The exported fields can be selected, the values can be written raw, as found in the feed, or
+// typed (numbers, booleans and lists) and the output can be gzipped.
+package export
+
+import (
+	"bufio"
+	"compress/gzip"
+	"ebay-api-client/ebay"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Options tunes the exported items
+type Options struct {
+	// Fields are the exported fields, in order: the Item field names or their json or csv tags, case insensitive.
+	// Defaults to all the Item fields.
+	Fields []string
+	// Typed writes the numeric, boolean and list fields as numbers, booleans and lists instead of the raw feed values.
+	// The values which cannot be converted are written raw.
+	Typed bool
+	// Gzip compresses the output
+	Gzip bool
+}
+
+// Writer writes the items in an output format
+type Writer interface {
+	// Write writes the item
+	Write(item *ebay.Item) error
+	// Close flushes the buffered items and terminates the gzip stream. It does not close the underlying writer.
+	Close() error
+}
+
+// Copy writes the items of the iterator until io.EOF and returns the number of written items.
+// The writer is not closed.
+func Copy(w Writer, it ebay.ItemIterator) (int, error) {
+	written := 0
+	for {
+		item, err := it.Next()
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+
+		if err := w.Write(item); err != nil {
+			return written, err
+		}
+		written++
+	}
+}
+
+// kind is the type of a field in the typed output
+type kind int
+
+const (
+	kindString kind = iota
+	kindNumber
+	kindBool
+	kindList
+)
+
+// fieldKinds are the types of the Item fields which are not strings
+var fieldKinds = map[string]kind{
+	"SellerFeedbackPercentage":      kindNumber,
+	"SellerFeedbackScore":           kindNumber,
+	"PriceValue":                    kindNumber,
+	"EstimatedAvailableQuantity":    kindNumber,
+	"AvailabilityThreshold":         kindNumber,
+	"ReturnPeriodValue":             kindNumber,
+	"RestockingFeePercentage":       kindNumber,
+	"OriginalPriceValue":            kindNumber,
+	"DiscountAmount":                kindNumber,
+	"DiscountPercentage":            kindNumber,
+	"LotSize":                       kindNumber,
+	"PackageWidth":                  kindNumber,
+	"PackageHeight":                 kindNumber,
+	"PackageLength":                 kindNumber,
+	"PackageWeight":                 kindNumber,
+	"ShippingCost":                  kindNumber,
+	"AdditionalShippingCostPerUnit": kindNumber,
+	"QuantityUsedForEstimate":       kindNumber,
+	"UnitPrice":                     kindNumber,
+	"ImageAlteringProhibited":       kindBool,
+	"ReturnsAccepted":               kindBool,
+	"BuyingOptions":                 kindList,
+	"AcceptedPaymentMethods":        kindList,
+	"DeliveryOptions":               kindList,
+	"QualifiedPrograms":             kindList,
+	"AdditionalImages":              kindList,
+	"Alerts":                        kindList,
+}
+
+// listSeparator is the separator of the list values in the feed
+const listSeparator = "|"
+
+// column is an exported Item field
+type column struct {
+	index int
+	name  string
+	json  string
+	csv   string
+	kind  kind
+}
+
+// columns returns the columns of the given fields, all the Item fields if empty
+func columns(fields []string) ([]column, error) {
+	t := reflect.TypeOf(ebay.Item{})
+
+	all := make([]column, t.NumField())
+	for i := range all {
+		f := t.Field(i)
+		all[i] = column{index: i, name: f.Name, json: f.Tag.Get("json"), csv: f.Tag.Get("csv"), kind: fieldKinds[f.Name]}
+	}
+	if len(fields) == 0 {
+		return all, nil
+	}
+
+	selected := make([]column, len(fields))
+	for n, name := range fields {
+		found := false
+		for _, c := range all {
+			if strings.EqualFold(name, c.name) || strings.EqualFold(name, c.json) || strings.EqualFold(name, c.csv) {
+				selected[n], found = c, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("columns(): unknown field %q", name)
+		}
+	}
+	return selected, nil
+}
+
+// typed converts the raw feed value to the column type. Empty values are nil.
+func (c column) typed(raw string) interface{} {
+	if c.kind == kindString {
+		return raw
+	}
+	if raw == "" {
+		return nil
+	}
+
+	switch c.kind {
+	case kindNumber:
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			return n
+		}
+	case kindBool:
+		if b, err := strconv.ParseBool(strings.ToLower(raw)); err == nil {
+			return b
+		}
+	case kindList:
+		values := strings.Split(raw, listSeparator)
+		for n := range values {
+			values[n] = strings.TrimSpace(values[n])
+		}
+		return values
+	}
+	return raw
+}
+
+// output is the buffered, optionally gzipped, output of the writers
+type output struct {
+	*bufio.Writer
+	gz *gzip.Writer
+}
+
+func newOutput(w io.Writer, gzipped bool) *output {
+	if !gzipped {
+		return &output{Writer: bufio.NewWriter(w)}
+	}
+	gz := gzip.NewWriter(w)
+	return &output{Writer: bufio.NewWriter(gz), gz: gz}
+}
+
+// close flushes the buffer and terminates the gzip stream
+func (o *output) close() error {
+	if err := o.Flush(); err != nil {
+		return err
+	}
+	if o.gz == nil {
+		return nil
+	}
+	return o.gz.Close()
+}
+
+// raw returns the feed value of the column
+func (c column) raw(item *ebay.Item) string {
+	return reflect.ValueOf(item).Elem().Field(c.index).String()
+}
@@ -0,0 +1,127 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"ebay-api-client/ebay"
+	"ebay-api-client/ebay/ebaytest"
+	"ebay-api-client/filter"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+var items = []*ebay.Item{
+	{ID: "v1|1|0", Title: `Red "smart" phone, 64GB`, CategoryID: "9355", PriceValue: "9.50", BuyingOptions: "FIXED_PRICE|BEST_OFFER", ReturnsAccepted: "TRUE"},
+	{ID: "v1|2|0", Title: "Laptop", CategoryID: "177", PriceValue: "n/a"},
+}
+
+func Test_IsJSONLinesWriterWritingRawValues(t *testing.T) {
+	out := new(bytes.Buffer)
+	w, err := NewJSONLinesWriter(out, &Options{Fields: []string{"ID", "title", "PriceValue", "buyingOptions"}})
+	assert.NilError(t, err)
+
+	n, err := Copy(w, ebaytest.ItemReader(items...))
+	assert.NilError(t, err)
+	assert.Equal(t, n, 2)
+	assert.NilError(t, w.Close())
+
+	assert.Equal(t, out.String(),
+		`{"itemId":"v1|1|0","title":"Red \"smart\" phone, 64GB","priceValue":"9.50","buyingOptions":"FIXED_PRICE|BEST_OFFER"}`+"\n"+
+			`{"itemId":"v1|2|0","title":"Laptop","priceValue":"n/a","buyingOptions":""}`+"\n")
+
+	// Without fields the object is the Item
+	out.Reset()
+	w, err = NewJSONLinesWriter(out, nil)
+	assert.NilError(t, err)
+	assert.NilError(t, w.Write(items[0]))
+	assert.NilError(t, w.Close())
+
+	var item ebay.Item
+	assert.NilError(t, json.Unmarshal(out.Bytes(), &item))
+	assert.DeepEqual(t, &item, items[0])
+}
+
+func Test_IsJSONLinesWriterWritingTypedValues(t *testing.T) {
+	out := new(bytes.Buffer)
+	w, err := NewJSONLinesWriter(out, &Options{Fields: []string{"ID", "PriceValue", "BuyingOptions", "ReturnsAccepted"}, Typed: true})
+	assert.NilError(t, err)
+
+	_, err = Copy(w, ebaytest.ItemReader(items...))
+	assert.NilError(t, err)
+	assert.NilError(t, w.Close())
+
+	assert.Equal(t, out.String(),
+		`{"itemId":"v1|1|0","priceValue":9.5,"buyingOptions":["FIXED_PRICE","BEST_OFFER"],"returnsAccepted":true}`+"\n"+
+			`{"itemId":"v1|2|0","priceValue":"n/a","buyingOptions":null,"returnsAccepted":null}`+"\n")
+}
+
+func Test_IsCSVWriterWritingRecords(t *testing.T) {
+	for _, typed := range []bool{false, true} {
+		out := new(bytes.Buffer)
+		w, err := NewCSVWriter(out, &Options{Fields: []string{"itemId", "Title", "PriceValue", "BuyingOptions"}, Typed: typed, Gzip: true})
+		assert.NilError(t, err)
+
+		_, err = Copy(w, ebaytest.ItemReader(items...))
+		assert.NilError(t, err)
+		assert.NilError(t, w.Close())
+
+		gz, err := gzip.NewReader(out)
+		assert.NilError(t, err)
+		data, err := ioutil.ReadAll(gz)
+		assert.NilError(t, err)
+
+		records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		assert.NilError(t, err)
+
+		price := "9.50"
+		if typed {
+			price = "9.5"
+		}
+		assert.DeepEqual(t, records, [][]string{
+			{"ID", "Title", "PriceValue", "BuyingOptions"},
+			{"v1|1|0", `Red "smart" phone, 64GB`, price, "FIXED_PRICE|BEST_OFFER"},
+			{"v1|2|0", "Laptop", "n/a", ""},
+		})
+		assert.Assert(t, bytes.Contains(data, []byte(`"Red ""smart"" phone, 64GB"`)))
+	}
+}
+
+func Test_IsTSVWriterWritingRows(t *testing.T) {
+	out := new(bytes.Buffer)
+	w, err := NewTSVWriter(out, &Options{Fields: []string{"itemId", "Title", "PriceValue", "BuyingOptions"}})
+	assert.NilError(t, err)
+
+	_, err = Copy(w, ebaytest.ItemReader(items...))
+	assert.NilError(t, err)
+	assert.NilError(t, w.Close())
+	assert.Equal(t, out.String(), "ID\tTitle\tPriceValue\tBuyingOptions\n"+
+		"v1|1|0\tRed \"smart\" phone, 64GB\t9.50\tFIXED_PRICE|BEST_OFFER\n"+
+		"v1|2|0\tLaptop\tn/a\t\n")
+}
+
+func Test_IsWriterPluggingIntoFilterReader(t *testing.T) {
+	p, err := filter.Parse("CategoryID = 177")
+	assert.NilError(t, err)
+
+	out := new(bytes.Buffer)
+	w, err := NewCSVWriter(out, &Options{Fields: []string{"ID"}})
+	assert.NilError(t, err)
+
+	n, err := Copy(w, filter.NewReader(ebaytest.ItemReader(items...), p))
+	assert.NilError(t, err)
+	assert.Equal(t, n, 1)
+	assert.NilError(t, w.Close())
+	assert.Equal(t, out.String(), "ID\nv1|2|0\n")
+}
+
+func Test_IsWriterRejectingUnknownFields(t *testing.T) {
+	_, err := NewJSONLinesWriter(new(bytes.Buffer), &Options{Fields: []string{"Price"}})
+	assert.ErrorContains(t, err, `unknown field "Price"`)
+
+	_, err = NewCSVWriter(new(bytes.Buffer), &Options{Fields: []string{"ID", "Quantity"}})
+	assert.ErrorContains(t, err, `unknown field "Quantity"`)
+}
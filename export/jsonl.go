@@ -0,0 +1,70 @@
+package export
+
+import (
+	"bytes"
+	"ebay-api-client/ebay"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// JSONLinesWriter writes one JSON object per item and per line, keyed by the json tags of the Item fields
+// in the order of the selected fields
+type JSONLinesWriter struct {
+	out     *output
+	columns []column
+	keys    [][]byte
+	typed   bool
+	buf     bytes.Buffer
+}
+
+// NewJSONLinesWriter creates a new JSONLinesWriter writing to w
+func NewJSONLinesWriter(w io.Writer, opts *Options) (*JSONLinesWriter, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	columns, err := columns(opts.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("NewJSONLinesWriter(): %v", err)
+	}
+
+	keys := make([][]byte, len(columns))
+	for n, c := range columns {
+		keys[n], _ = json.Marshal(c.json)
+	}
+
+	return &JSONLinesWriter{out: newOutput(w, opts.Gzip), columns: columns, keys: keys, typed: opts.Typed}, nil
+}
+
+// Write writes the item as a JSON object line
+func (j *JSONLinesWriter) Write(item *ebay.Item) error {
+	j.buf.Reset()
+	j.buf.WriteByte('{')
+	for n, c := range j.columns {
+		if n > 0 {
+			j.buf.WriteByte(',')
+		}
+		j.buf.Write(j.keys[n])
+		j.buf.WriteByte(':')
+
+		var value interface{} = c.raw(item)
+		if j.typed {
+			value = c.typed(c.raw(item))
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("Write(): cannot encode %v: %v", c.json, err)
+		}
+		j.buf.Write(data)
+	}
+	j.buf.WriteString("}\n")
+
+	_, err := j.out.Write(j.buf.Bytes())
+	return err
+}
+
+// Close flushes the buffered items and terminates the gzip stream. It does not close the underlying writer.
+func (j *JSONLinesWriter) Close() error {
+	return j.out.close()
+}
@@ -0,0 +1,376 @@
+package export
+
+import (
+	"ebay-api-client/ebay"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xitongsys/parquet-go-source/writerfile"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+const (
+	// DefaultRowGroupSize is the default size of the Parquet row groups, buffered in memory before being written
+	DefaultRowGroupSize int64 = 128 * 1024 * 1024
+	// DefaultCompression is the default compression of the Parquet pages
+	DefaultCompression = "snappy"
+)
+
+// ParquetOptions tunes the Parquet output
+type ParquetOptions struct {
+	// Typed writes the TypedItem schema instead of the raw feed values as strings
+	Typed bool
+	// RowGroupSize is the size in bytes of the row groups, defaults to DefaultRowGroupSize
+	RowGroupSize int64
+	// Compression is the compression of the pages: uncompressed, snappy, gzip or zstd. Defaults to DefaultCompression.
+	Compression string
+}
+
+// Aspect is a localized aspect of an item, i.e. Color: Red
+type Aspect struct {
+	Name  string `parquet:"name=name, type=UTF8"`
+	Value string `parquet:"name=value, type=UTF8"`
+}
+
+// TypedItem is the typed form of an Item: the prices are decimals with 2 digits, the end date is a timestamp
+// in milliseconds, the lists and aspects are repeated fields. The values which cannot be converted are null, the
+// aspects which cannot be decoded are an error of NewTypedItem.
+type TypedItem struct {
+	ID                            string   `parquet:"name=itemId, type=UTF8"`
+	Title                         string   `parquet:"name=title, type=UTF8"`
+	ImageURL                      string   `parquet:"name=imageUrl, type=UTF8"`
+	Category                      string   `parquet:"name=category, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	CategoryID                    string   `parquet:"name=categoryId, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	BuyingOptions                 []string `parquet:"name=buyingOptions, type=UTF8, repetitiontype=REPEATED"`
+	SellerUsername                string   `parquet:"name=sellerUsername, type=UTF8"`
+	SellerFeedbackPercentage      *float64 `parquet:"name=sellerFeedbackPercentage, type=DOUBLE"`
+	SellerFeedbackScore           *float64 `parquet:"name=sellerFeedbackScore, type=DOUBLE"`
+	GTIN                          string   `parquet:"name=gtin, type=UTF8"`
+	Brand                         string   `parquet:"name=brand, type=UTF8"`
+	MPN                           string   `parquet:"name=mpn, type=UTF8"`
+	EPID                          string   `parquet:"name=epid, type=UTF8"`
+	ConditionID                   string   `parquet:"name=conditionId, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	Condition                     string   `parquet:"name=condition, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	PriceValue                    *int64   `parquet:"name=priceValue, type=DECIMAL, scale=2, precision=18, basetype=INT64"`
+	PriceCurrency                 string   `parquet:"name=priceCurrency, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	PrimaryItemGroupID            string   `parquet:"name=primaryItemGroupId, type=UTF8"`
+	PrimaryItemGroupType          string   `parquet:"name=primaryItemGroupType, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	EndDate                       *int64   `parquet:"name=endDate, type=TIMESTAMP_MILLIS"`
+	SellerItemRevision            string   `parquet:"name=sellerItemRevision, type=UTF8"`
+	LocationCountry               string   `parquet:"name=locationCountry, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	LocalizedAspects              []Aspect `parquet:"name=localizedAspects, repetitiontype=REPEATED"`
+	SellerTrustLevel              string   `parquet:"name=sellerTrustLevel, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	Availability                  string   `parquet:"name=availability, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	ImageAlteringProhibited       *bool    `parquet:"name=imageAlteringProhibited, type=BOOLEAN"`
+	EstimatedAvailableQuantity    *int64   `parquet:"name=estimatedAvailableQuantity, type=INT64"`
+	AvailabilityThresholdType     string   `parquet:"name=availabilityThresholdType, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	AvailabilityThreshold         *int64   `parquet:"name=availabilityThreshold, type=INT64"`
+	ReturnsAccepted               *bool    `parquet:"name=returnsAccepted, type=BOOLEAN"`
+	ReturnPeriodValue             *int64   `parquet:"name=returnPeriodValue, type=INT64"`
+	ReturnPeriodUnit              string   `parquet:"name=returnPeriodUnit, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	RefundMethod                  string   `parquet:"name=refundMethod, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	ReturnMethod                  string   `parquet:"name=returnMethod, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	ReturnShippingCostPayer       string   `parquet:"name=returnShippingCostPayer, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	RestockingFeePercentage       *float64 `parquet:"name=restockingFeePercentage, type=DOUBLE"`
+	AcceptedPaymentMethods        []string `parquet:"name=acceptedPaymentMethods, type=UTF8, repetitiontype=REPEATED"`
+	DeliveryOptions               []string `parquet:"name=deliveryOptions, type=UTF8, repetitiontype=REPEATED"`
+	ShipToIncludedRegions         string   `parquet:"name=shipToIncludedRegions, type=UTF8"`
+	ShipToExcludedRegions         string   `parquet:"name=shipToExcludedRegions, type=UTF8"`
+	InferredEPID                  string   `parquet:"name=inferredEpid, type=UTF8"`
+	InferredGTIN                  string   `parquet:"name=inferredGtin, type=UTF8"`
+	InferredBrand                 string   `parquet:"name=inferredBrand, type=UTF8"`
+	InferredMPN                   string   `parquet:"name=inferredMpn, type=UTF8"`
+	InferredLocalizedAspects      []Aspect `parquet:"name=inferredLocalizedAspects, repetitiontype=REPEATED"`
+	AdditionalImages              []string `parquet:"name=additionalImages, type=UTF8, repetitiontype=REPEATED"`
+	OriginalPriceValue            *int64   `parquet:"name=originalPriceValue, type=DECIMAL, scale=2, precision=18, basetype=INT64"`
+	OriginalPriceCurrency         string   `parquet:"name=originalPriceCurrency, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	DiscountAmount                *int64   `parquet:"name=discountAmount, type=DECIMAL, scale=2, precision=18, basetype=INT64"`
+	DiscountPercentage            *float64 `parquet:"name=discountPercentage, type=DOUBLE"`
+	EnergyEfficiencyClass         string   `parquet:"name=energyEfficiencyClass, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	QualifiedPrograms             []string `parquet:"name=qualifiedPrograms, type=UTF8, repetitiontype=REPEATED"`
+	LotSize                       *int64   `parquet:"name=lotSize, type=INT64"`
+	LengthUnitOfMeasure           string   `parquet:"name=lengthUnitOfMeasure, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	PackageWidth                  *float64 `parquet:"name=packageWidth, type=DOUBLE"`
+	PackageHeight                 *float64 `parquet:"name=packageHeight, type=DOUBLE"`
+	PackageLength                 *float64 `parquet:"name=packageLength, type=DOUBLE"`
+	WeightUnitOfMeasure           string   `parquet:"name=weightUnitOfMeasure, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	PackageWeight                 *float64 `parquet:"name=packageWeight, type=DOUBLE"`
+	ShippingCarrierCode           string   `parquet:"name=shippingCarrierCode, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	ShippingServiceCode           string   `parquet:"name=shippingServiceCode, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	ShippingType                  string   `parquet:"name=shippingType, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	ShippingCost                  *int64   `parquet:"name=shippingCost, type=DECIMAL, scale=2, precision=18, basetype=INT64"`
+	ShippingCostType              string   `parquet:"name=shippingCostType, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	AdditionalShippingCostPerUnit *int64   `parquet:"name=additionalShippingCostPerUnit, type=DECIMAL, scale=2, precision=18, basetype=INT64"`
+	QuantityUsedForEstimate       *int64   `parquet:"name=quantityUsedForEstimate, type=INT64"`
+	UnitPrice                     *int64   `parquet:"name=unitPrice, type=DECIMAL, scale=2, precision=18, basetype=INT64"`
+	UnitPricingMeasure            string   `parquet:"name=unitPricingMeasure, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	LegacyItemID                  string   `parquet:"name=legacyItemId, type=UTF8"`
+	Alerts                        []string `parquet:"name=alerts, type=UTF8, repetitiontype=REPEATED"`
+}
+
+// NewTypedItem converts the Item to its typed form. An error is returned if the aspects cannot be decoded.
+func NewTypedItem(item *ebay.Item) (*TypedItem, error) {
+	aspects, err := item.Aspects()
+	if err != nil {
+		return nil, fmt.Errorf("NewTypedItem(): invalid localizedAspects of item %v: %v", item.ID, err)
+	}
+	inferredAspects, err := item.InferredAspects()
+	if err != nil {
+		return nil, fmt.Errorf("NewTypedItem(): invalid inferredLocalizedAspects of item %v: %v", item.ID, err)
+	}
+
+	return &TypedItem{
+		ID:                            item.ID,
+		Title:                         item.Title,
+		ImageURL:                      item.ImageURL,
+		Category:                      item.Category,
+		CategoryID:                    item.CategoryID,
+		BuyingOptions:                 parseList(item.BuyingOptions),
+		SellerUsername:                item.SellerUsername,
+		SellerFeedbackPercentage:      parseFloat(item.SellerFeedbackPercentage),
+		SellerFeedbackScore:           parseFloat(item.SellerFeedbackScore),
+		GTIN:                          item.GTIN,
+		Brand:                         item.Brand,
+		MPN:                           item.MPN,
+		EPID:                          item.EPID,
+		ConditionID:                   item.ConditionID,
+		Condition:                     item.Condition,
+		PriceValue:                    parseDecimal(item.PriceValue),
+		PriceCurrency:                 item.PriceCurrency,
+		PrimaryItemGroupID:            item.PrimaryItemGroupID,
+		PrimaryItemGroupType:          item.PrimaryItemGroupType,
+		EndDate:                       parseTimestamp(item.EndDate),
+		SellerItemRevision:            item.SellerItemRevision,
+		LocationCountry:               item.LocationCountry,
+		LocalizedAspects:              typedAspects(aspects),
+		SellerTrustLevel:              item.SellerTrustLevel,
+		Availability:                  item.Availability,
+		ImageAlteringProhibited:       parseBool(item.ImageAlteringProhibited),
+		EstimatedAvailableQuantity:    parseInt(item.EstimatedAvailableQuantity),
+		AvailabilityThresholdType:     item.AvailabilityThresholdType,
+		AvailabilityThreshold:         parseInt(item.AvailabilityThreshold),
+		ReturnsAccepted:               parseBool(item.ReturnsAccepted),
+		ReturnPeriodValue:             parseInt(item.ReturnPeriodValue),
+		ReturnPeriodUnit:              item.ReturnPeriodUnit,
+		RefundMethod:                  item.RefundMethod,
+		ReturnMethod:                  item.ReturnMethod,
+		ReturnShippingCostPayer:       item.ReturnShippingCostPayer,
+		RestockingFeePercentage:       parseFloat(item.RestockingFeePercentage),
+		AcceptedPaymentMethods:        parseList(item.AcceptedPaymentMethods),
+		DeliveryOptions:               parseList(item.DeliveryOptions),
+		ShipToIncludedRegions:         item.ShipToIncludedRegions,
+		ShipToExcludedRegions:         item.ShipToExcludedRegions,
+		InferredEPID:                  item.InferredEPID,
+		InferredGTIN:                  item.InferredGTIN,
+		InferredBrand:                 item.InferredBrand,
+		InferredMPN:                   item.InferredMPN,
+		InferredLocalizedAspects:      typedAspects(inferredAspects),
+		AdditionalImages:              parseList(item.AdditionalImages),
+		OriginalPriceValue:            parseDecimal(item.OriginalPriceValue),
+		OriginalPriceCurrency:         item.OriginalPriceCurrency,
+		DiscountAmount:                parseDecimal(item.DiscountAmount),
+		DiscountPercentage:            parseFloat(item.DiscountPercentage),
+		EnergyEfficiencyClass:         item.EnergyEfficiencyClass,
+		QualifiedPrograms:             parseList(item.QualifiedPrograms),
+		LotSize:                       parseInt(item.LotSize),
+		LengthUnitOfMeasure:           item.LengthUnitOfMeasure,
+		PackageWidth:                  parseFloat(item.PackageWidth),
+		PackageHeight:                 parseFloat(item.PackageHeight),
+		PackageLength:                 parseFloat(item.PackageLength),
+		WeightUnitOfMeasure:           item.WeightUnitOfMeasure,
+		PackageWeight:                 parseFloat(item.PackageWeight),
+		ShippingCarrierCode:           item.ShippingCarrierCode,
+		ShippingServiceCode:           item.ShippingServiceCode,
+		ShippingType:                  item.ShippingType,
+		ShippingCost:                  parseDecimal(item.ShippingCost),
+		ShippingCostType:              item.ShippingCostType,
+		AdditionalShippingCostPerUnit: parseDecimal(item.AdditionalShippingCostPerUnit),
+		QuantityUsedForEstimate:       parseInt(item.QuantityUsedForEstimate),
+		UnitPrice:                     parseDecimal(item.UnitPrice),
+		UnitPricingMeasure:            item.UnitPricingMeasure,
+		LegacyItemID:                  item.LegacyItemID,
+		Alerts:                        parseList(item.Alerts),
+	}, nil
+}
+
+func parseFloat(s string) *float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
+
+func parseInt(s string) *int64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f != math.Trunc(f) {
+		return nil
+	}
+	n := int64(f)
+	return &n
+}
+
+// Scale and precision of the DECIMAL fields of TypedItem
+const (
+	decimalScale     = 2
+	decimalPrecision = 18
+)
+
+// parseDecimal returns the decimal value in cents. The value is parsed exactly, it is rejected if it has more
+// fraction digits than the scale or more digits than the precision.
+func parseDecimal(s string) *int64 {
+	digits, negative := s, strings.HasPrefix(s, "-")
+	if negative || strings.HasPrefix(s, "+") {
+		digits = s[1:]
+	}
+	fraction := ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		digits, fraction = digits[:i], digits[i+1:]
+	}
+	if digits == "" && fraction == "" || len(fraction) > decimalScale {
+		return nil
+	}
+
+	digits += fraction + strings.Repeat("0", decimalScale-len(fraction))
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return nil
+		}
+	}
+	if len(strings.TrimLeft(digits, "0")) > decimalPrecision {
+		return nil
+	}
+
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return nil
+	}
+	if negative {
+		n = -n
+	}
+	return &n
+}
+
+func parseBool(s string) *bool {
+	b, err := strconv.ParseBool(strings.ToLower(s))
+	if err != nil {
+		return nil
+	}
+	return &b
+}
+
+// parseTimestamp returns the RFC 3339 date in milliseconds since the epoch
+func parseTimestamp(s string) *int64 {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil
+	}
+	ms := t.UnixNano() / int64(time.Millisecond)
+	return &ms
+}
+
+func parseList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	values := strings.Split(s, listSeparator)
+	for n := range values {
+		values[n] = strings.TrimSpace(values[n])
+	}
+	return values
+}
+
+// typedAspects converts the decoded aspects to their Parquet form
+func typedAspects(aspects []ebay.Aspect) []Aspect {
+	if aspects == nil {
+		return nil
+	}
+	typed := make([]Aspect, len(aspects))
+	for n, a := range aspects {
+		typed[n] = Aspect{Name: a.Name, Value: a.Value}
+	}
+	return typed
+}
+
+// ParquetWriter writes the items to a Parquet file, either the raw feed values as strings or their TypedItem form.
+// The rows are buffered in memory until a row group is full, the file footer is written by Close.
+type ParquetWriter struct {
+	pw     *writer.ParquetWriter
+	csv    *writer.CSVWriter
+	values []*string
+}
+
+// NewParquetWriter creates a new ParquetWriter writing to w
+func NewParquetWriter(w io.Writer, opts *ParquetOptions) (*ParquetWriter, error) {
+	if opts == nil {
+		opts = &ParquetOptions{}
+	}
+
+	compression := opts.Compression
+	if compression == "" {
+		compression = DefaultCompression
+	}
+	codec, err := parquet.CompressionCodecFromString(strings.ToUpper(compression))
+	if err != nil || (codec != parquet.CompressionCodec_UNCOMPRESSED && codec != parquet.CompressionCodec_SNAPPY &&
+		codec != parquet.CompressionCodec_GZIP && codec != parquet.CompressionCodec_ZSTD) {
+		return nil, fmt.Errorf("NewParquetWriter(): unsupported compression %q", opts.Compression)
+	}
+
+	rowGroupSize := opts.RowGroupSize
+	if rowGroupSize <= 0 {
+		rowGroupSize = DefaultRowGroupSize
+	}
+
+	file := writerfile.NewWriterFile(w)
+	np := int64(runtime.NumCPU())
+	p := &ParquetWriter{}
+
+	if opts.Typed {
+		p.pw, err = writer.NewParquetWriter(file, new(TypedItem), np)
+	} else {
+		all, _ := columns(nil)
+		metadata := make([]string, len(all))
+		for n, c := range all {
+			metadata[n] = fmt.Sprintf("name=%v, type=UTF8", c.json)
+		}
+		p.values = make([]*string, len(all))
+		if p.csv, err = writer.NewCSVWriter(metadata, file, np); err == nil {
+			p.pw = &p.csv.ParquetWriter
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("NewParquetWriter(): cannot create writer: %v", err)
+	}
+
+	p.pw.RowGroupSize = rowGroupSize
+	p.pw.CompressionType = codec
+	return p, nil
+}
+
+// Write buffers the item in the current row group, writing the row group when full
+func (p *ParquetWriter) Write(item *ebay.Item) error {
+	if p.csv == nil {
+		typed, err := NewTypedItem(item)
+		if err != nil {
+			return err
+		}
+		return p.pw.Write(typed)
+	}
+
+	values := item.Values()
+	for n := range values {
+		p.values[n] = &values[n]
+	}
+	return p.csv.WriteString(p.values)
+}
+
+// Close writes the last row group and the file footer. It does not close the underlying writer.
+func (p *ParquetWriter) Close() error {
+	return p.pw.WriteStop()
+}
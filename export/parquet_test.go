@@ -0,0 +1,171 @@
+package export
+
+import (
+	"bytes"
+	"ebay-api-client/ebay"
+	"ebay-api-client/ebay/ebaytest"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"gotest.tools/v3/assert"
+)
+
+var parquetItem = &ebay.Item{
+	ID:               "v1|1|0",
+	Title:            "Red phone",
+	CategoryID:       "9355",
+	BuyingOptions:    "FIXED_PRICE|BEST_OFFER",
+	PriceValue:       "9.99",
+	PriceCurrency:    "USD",
+	EndDate:          "2020-06-12T20:47:08.000Z",
+	LocalizedAspects: "Q29sb3I=:UmVk;U2l6ZQ==:NjQgR0I=",
+	ReturnsAccepted:  "TRUE",
+	LotSize:          "2",
+	AdditionalImages: "http://i.ebayimg.com/1.JPG|http://i.ebayimg.com/2.JPG",
+	ShippingCost:     "not a price",
+}
+
+// writeParquet writes the items and returns a reader of the Parquet file
+func writeParquet(t *testing.T, opts *ParquetOptions, obj interface{}, items ...*ebay.Item) *reader.ParquetReader {
+	out := new(bytes.Buffer)
+	w, err := NewParquetWriter(out, opts)
+	assert.NilError(t, err)
+
+	n, err := Copy(w, ebaytest.ItemReader(items...))
+	assert.NilError(t, err)
+	assert.Equal(t, n, len(items))
+	assert.NilError(t, w.Close())
+
+	file, err := buffer.NewBufferFile(out.Bytes())
+	assert.NilError(t, err)
+	pr, err := reader.NewParquetReader(file, obj, 1)
+	assert.NilError(t, err)
+	return pr
+}
+
+func Test_IsParquetWriterWritingTypedItems(t *testing.T) {
+	pr := writeParquet(t, &ParquetOptions{Typed: true, Compression: "gzip"}, new(TypedItem), parquetItem, &ebay.Item{ID: "v1|2|0"})
+	defer pr.ReadStop()
+	assert.Equal(t, pr.GetNumRows(), int64(2))
+
+	got := make([]TypedItem, 2)
+	assert.NilError(t, pr.Read(&got))
+
+	want, err := NewTypedItem(parquetItem)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &got[0], want)
+	assert.DeepEqual(t, got[0].BuyingOptions, []string{"FIXED_PRICE", "BEST_OFFER"})
+	assert.Equal(t, *got[0].PriceValue, int64(999))
+	assert.Equal(t, *got[0].EndDate, time.Date(2020, 6, 12, 20, 47, 8, 0, time.UTC).UnixNano()/int64(time.Millisecond))
+	assert.DeepEqual(t, got[0].LocalizedAspects, []Aspect{{Name: "Color", Value: "Red"}, {Name: "Size", Value: "64 GB"}})
+	assert.Equal(t, *got[0].ReturnsAccepted, true)
+	assert.Equal(t, *got[0].LotSize, int64(2))
+	assert.Equal(t, len(got[0].AdditionalImages), 2)
+	assert.Assert(t, got[0].ShippingCost == nil)
+
+	assert.Equal(t, got[1].ID, "v1|2|0")
+	assert.Assert(t, got[1].PriceValue == nil && got[1].EndDate == nil && got[1].BuyingOptions == nil)
+}
+
+func Test_IsParquetWriterRejectingInvalidAspects(t *testing.T) {
+	tests := []struct {
+		aspects string
+		err     string
+	}{
+		{aspects: "Q29sb3I=:UmVk;", err: ""},
+		{aspects: "Color:UmVk", err: `cannot decode aspect name "Color"`},
+		{aspects: "Q29sb3I=:Red", err: `cannot decode aspect value "Red"`},
+		{aspects: "Q29sb3I=", err: `aspect "Q29sb3I=" has no value`},
+	}
+	for _, tt := range tests {
+		w, err := NewParquetWriter(new(bytes.Buffer), &ParquetOptions{Typed: true})
+		assert.NilError(t, err)
+
+		err = w.Write(&ebay.Item{ID: "v1|1|0", LocalizedAspects: tt.aspects})
+		if tt.err == "" {
+			assert.NilError(t, err)
+			continue
+		}
+		assert.ErrorContains(t, err, "invalid localizedAspects of item v1|1|0")
+		assert.ErrorContains(t, err, tt.err)
+	}
+}
+
+func Test_IsDecimalParsedExactly(t *testing.T) {
+	tests := []struct {
+		s    string
+		want interface{}
+	}{
+		{s: "9.99", want: int64(999)},
+		{s: "0.29", want: int64(29)},
+		{s: "1.1", want: int64(110)},
+		{s: "12", want: int64(1200)},
+		{s: ".5", want: int64(50)},
+		{s: "-4.01", want: int64(-401)},
+		{s: "+3.", want: int64(300)},
+		{s: "9999999999999999.99", want: int64(999999999999999999)},
+		{s: "00012.30", want: int64(1230)},
+		{s: "9.999", want: nil},
+		{s: "99999999999999999", want: nil},
+		{s: "1e3", want: nil},
+		{s: "1.2.3", want: nil},
+		{s: "-", want: nil},
+		{s: ".", want: nil},
+		{s: "", want: nil},
+		{s: "NaN", want: nil},
+	}
+	for _, tt := range tests {
+		got := parseDecimal(tt.s)
+		if tt.want == nil {
+			assert.Assert(t, got == nil, tt.s)
+			continue
+		}
+		assert.Assert(t, got != nil, tt.s)
+		assert.Equal(t, *got, tt.want, tt.s)
+	}
+}
+
+func Test_IsParquetWriterWritingRawItems(t *testing.T) {
+	items := make([]*ebay.Item, 100)
+	for n := range items {
+		items[n] = &ebay.Item{ID: fmt.Sprintf("v1|%v|0", n), Title: "Item", PriceValue: "n/a"}
+	}
+
+	pr := writeParquet(t, &ParquetOptions{RowGroupSize: 1, Compression: "uncompressed"}, nil, items...)
+	defer pr.ReadStop()
+	assert.Equal(t, pr.GetNumRows(), int64(100))
+
+	// The raw columns are the json tags of the Item fields
+	assert.Equal(t, len(pr.Footer.Schema), len(ebay.ItemFields)+1)
+	assert.Equal(t, pr.SchemaHandler.GetExName(1), "itemId")
+	assert.Equal(t, pr.SchemaHandler.GetExName(16), "priceValue")
+
+	rows, err := pr.ReadByNumber(1)
+	assert.NilError(t, err)
+	data, err := json.Marshal(rows[0])
+	assert.NilError(t, err)
+	assert.Assert(t, bytes.Contains(data, []byte(`"v1|0|0"`)) && bytes.Contains(data, []byte(`"n/a"`)), string(data))
+}
+
+func Test_IsParquetWriterApplyingOptions(t *testing.T) {
+	w, err := NewParquetWriter(new(bytes.Buffer), nil)
+	assert.NilError(t, err)
+	assert.Equal(t, w.pw.RowGroupSize, DefaultRowGroupSize)
+	assert.Equal(t, w.pw.CompressionType, parquet.CompressionCodec_SNAPPY)
+
+	w, err = NewParquetWriter(new(bytes.Buffer), &ParquetOptions{Typed: true, RowGroupSize: 1024, Compression: "ZSTD"})
+	assert.NilError(t, err)
+	assert.Equal(t, w.pw.RowGroupSize, int64(1024))
+	assert.Equal(t, w.pw.CompressionType, parquet.CompressionCodec_ZSTD)
+
+	_, err = NewParquetWriter(new(bytes.Buffer), &ParquetOptions{Compression: "lzo"})
+	assert.ErrorContains(t, err, `unsupported compression "lzo"`)
+
+	_, err = NewParquetWriter(new(bytes.Buffer), &ParquetOptions{Compression: "rar"})
+	assert.ErrorContains(t, err, `unsupported compression "rar"`)
+}
@@ -0,0 +1,59 @@
+package export
+
+import (
+	"ebay-api-client/ebay"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TSVWriter writes the items as tab separated rows, after a header row of the Item field names of the selected fields,
+// i.e. in the feed format when all the fields are selected. The typed lists are joined with '|'.
+type TSVWriter struct {
+	out     *output
+	columns []column
+	typed   bool
+	row     []string
+}
+
+// NewTSVWriter creates a new TSVWriter writing to w and writes the header row
+func NewTSVWriter(w io.Writer, opts *Options) (*TSVWriter, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	columns, err := columns(opts.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("NewTSVWriter(): %v", err)
+	}
+
+	t := &TSVWriter{out: newOutput(w, opts.Gzip), columns: columns, typed: opts.Typed, row: make([]string, len(columns))}
+	for n, column := range columns {
+		t.row[n] = column.name
+	}
+	if err := t.writeRow(); err != nil {
+		return nil, fmt.Errorf("NewTSVWriter(): cannot write header: %v", err)
+	}
+	return t, nil
+}
+
+// Write writes the item as a tab separated row
+func (t *TSVWriter) Write(item *ebay.Item) error {
+	for n, column := range t.columns {
+		t.row[n] = column.raw(item)
+		if t.typed {
+			t.row[n] = formatTyped(column.typed(t.row[n]))
+		}
+	}
+	return t.writeRow()
+}
+
+func (t *TSVWriter) writeRow() error {
+	_, err := t.out.WriteString(strings.Join(t.row, "\t") + "\n")
+	return err
+}
+
+// Close flushes the buffered items and terminates the gzip stream. It does not close the underlying writer.
+func (t *TSVWriter) Close() error {
+	return t.out.close()
+}
@@ -0,0 +1,281 @@
+// Package filter selects the feed items matching composable predicates: field comparisons, regular expressions,
+// set membership, combined with And, Or and Not. The predicates can also be parsed from an expression string
+// (see Parse), so that the filters can be given in configuration files or on the command line.
+package filter
+
+import (
+	"ebay-api-client/ebay"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Op is a comparison operator
+type Op string
+
+// Comparison operators
+const (
+	Equal          Op = "="
+	NotEqual       Op = "!="
+	Less           Op = "<"
+	LessOrEqual    Op = "<="
+	Greater        Op = ">"
+	GreaterOrEqual Op = ">="
+)
+
+// listSeparators are the separators of the multi-valued fields, i.e. BuyingOptions
+const listSeparators = "|,;"
+
+// Predicate reports whether an item matches a condition
+type Predicate interface {
+	Match(item *ebay.Item) bool
+}
+
+// PredicateFunc is a function used as Predicate
+type PredicateFunc func(item *ebay.Item) bool
+
+// Match calls the function
+func (f PredicateFunc) Match(item *ebay.Item) bool {
+	return f(item)
+}
+
+// All is the predicate matching every item
+var All Predicate = PredicateFunc(func(*ebay.Item) bool { return true })
+
+// And returns a predicate matching the items matching all the given predicates
+func And(predicates ...Predicate) Predicate {
+	return PredicateFunc(func(item *ebay.Item) bool {
+		for _, p := range predicates {
+			if !p.Match(item) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Or returns a predicate matching the items matching at least one of the given predicates
+func Or(predicates ...Predicate) Predicate {
+	return PredicateFunc(func(item *ebay.Item) bool {
+		for _, p := range predicates {
+			if p.Match(item) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Not returns a predicate matching the items not matching the given predicate
+func Not(p Predicate) Predicate {
+	return PredicateFunc(func(item *ebay.Item) bool {
+		return !p.Match(item)
+	})
+}
+
+// field returns the index of the Item field matching the given case insensitive name, see ebay.ItemFields.
+// The predicates resolve their field once, when created.
+func field(name string) (int, error) {
+	for i, f := range ebay.ItemFields {
+		if strings.EqualFold(f, name) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown field %q", name)
+}
+
+// value returns the value of the field with the given index
+func value(item *ebay.Item, field int) string {
+	return reflect.ValueOf(item).Elem().Field(field).String()
+}
+
+// Compare returns a predicate comparing the field with the value. The values are compared as numbers
+// when both are numeric, as strings otherwise. An empty field never matches the order operators.
+func Compare(name string, op Op, v string) (Predicate, error) {
+	f, err := field(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var check func(cmp int) bool
+	switch op {
+	case Equal:
+		check = func(cmp int) bool { return cmp == 0 }
+	case NotEqual:
+		check = func(cmp int) bool { return cmp != 0 }
+	case Less:
+		check = func(cmp int) bool { return cmp < 0 }
+	case LessOrEqual:
+		check = func(cmp int) bool { return cmp <= 0 }
+	case Greater:
+		check = func(cmp int) bool { return cmp > 0 }
+	case GreaterOrEqual:
+		check = func(cmp int) bool { return cmp >= 0 }
+	default:
+		return nil, fmt.Errorf("unknown operator %q", op)
+	}
+
+	ordered := op != Equal && op != NotEqual
+	number, numErr := strconv.ParseFloat(v, 64)
+
+	return PredicateFunc(func(item *ebay.Item) bool {
+		got := value(item, f)
+		if ordered && got == "" {
+			return false
+		}
+
+		if numErr == nil {
+			if n, err := strconv.ParseFloat(got, 64); err == nil {
+				switch {
+				case n < number:
+					return check(-1)
+				case n > number:
+					return check(1)
+				}
+				return check(0)
+			}
+		}
+		return check(strings.Compare(got, v))
+	}), nil
+}
+
+// Between returns a predicate matching the items whose numeric field is in the range [min, max]
+func Between(name string, min, max float64) (Predicate, error) {
+	f, err := field(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return PredicateFunc(func(item *ebay.Item) bool {
+		n, err := strconv.ParseFloat(value(item, f), 64)
+		return err == nil && n >= min && n <= max
+	}), nil
+}
+
+// Matches returns a predicate matching the items whose field matches the regular expression
+func Matches(name string, re *regexp.Regexp) (Predicate, error) {
+	f, err := field(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return PredicateFunc(func(item *ebay.Item) bool {
+		return re.MatchString(value(item, f))
+	}), nil
+}
+
+// Contains returns a predicate matching the items whose field contains the substring, ignoring the case
+func Contains(name, substr string) (Predicate, error) {
+	f, err := field(name)
+	if err != nil {
+		return nil, err
+	}
+
+	substr = strings.ToLower(substr)
+	return PredicateFunc(func(item *ebay.Item) bool {
+		return strings.Contains(strings.ToLower(value(item, f)), substr)
+	}), nil
+}
+
+// In returns a predicate matching the items whose field is one of the values, i.e. a set of CategoryID
+func In(name string, values ...string) (Predicate, error) {
+	f, err := field(name)
+	if err != nil {
+		return nil, err
+	}
+
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+
+	return PredicateFunc(func(item *ebay.Item) bool {
+		return set[value(item, f)]
+	}), nil
+}
+
+// Has returns a predicate matching the items whose multi-valued field, i.e. BuyingOptions, contains at least one of the values.
+// The field values are separated by '|', ',' or ';'.
+func Has(name string, values ...string) (Predicate, error) {
+	f, err := field(name)
+	if err != nil {
+		return nil, err
+	}
+
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[strings.ToUpper(v)] = true
+	}
+
+	return PredicateFunc(func(item *ebay.Item) bool {
+		for _, v := range strings.FieldsFunc(value(item, f), isListSeparator) {
+			if set[strings.ToUpper(strings.TrimSpace(v))] {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+func isListSeparator(r rune) bool {
+	return strings.ContainsRune(listSeparators, r)
+}
+
+// Reader reads the items of a feed matching a predicate
+type Reader struct {
+	ebay.ItemIterator
+	// Predicate selects the items returned by Next
+	Predicate Predicate
+	// Read is the number of items read from the feed
+	Read int
+	// Matched is the number of items returned by Next
+	Matched int
+}
+
+// NewReader creates a new Reader returning the items of r, i.e. an ebay.ItemReader or ebay.ParallelItemReader,
+// matching the predicate
+func NewReader(r ebay.ItemIterator, p Predicate) *Reader {
+	return &Reader{ItemIterator: r, Predicate: p}
+}
+
+// Next returns the next item matching the predicate. The error is io.EOF when there are no more items.
+func (r *Reader) Next() (*ebay.Item, error) {
+	for {
+		item, err := r.ItemIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		r.Read++
+
+		if r.Predicate.Match(item) {
+			r.Matched++
+			return item, nil
+		}
+	}
+}
+
+// Items calls fn for each item of the gzipped feed read from r matching the predicate, stopping at the first error
+func Items(r io.Reader, p Predicate, fn func(item *ebay.Item) error) error {
+	ir, err := ebay.NewItemReader(r)
+	if err != nil {
+		return err
+	}
+	defer ir.Close()
+
+	fr := NewReader(ir, p)
+	for {
+		item, err := fr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+}
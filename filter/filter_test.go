@@ -0,0 +1,139 @@
+package filter
+
+import (
+	"bytes"
+	"ebay-api-client/ebay"
+	"ebay-api-client/ebay/ebaytest"
+	"errors"
+	"io"
+	"regexp"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+var items = []*ebay.Item{
+	{ID: "v1|1|0", Title: "Red phone", CategoryID: "9355", PriceValue: "9.5", PriceCurrency: "USD", BuyingOptions: "FIXED_PRICE"},
+	{ID: "v1|2|0", Title: "Blue Phone case", CategoryID: "9355", PriceValue: "12", PriceCurrency: "USD", BuyingOptions: "AUCTION|BEST_OFFER"},
+	{ID: "v1|3|0", Title: "Laptop", CategoryID: "177", PriceValue: "350", PriceCurrency: "EUR", BuyingOptions: "FIXED_PRICE|BEST_OFFER"},
+	{ID: "v1|4|0", Title: "Unpriced", CategoryID: "177"},
+}
+
+// matching returns the ids of the items matching the predicate
+func matching(p Predicate) []string {
+	var ids []string
+	for _, item := range items {
+		if p.Match(item) {
+			ids = append(ids, item.ID)
+		}
+	}
+	return ids
+}
+
+// mustPredicate panics on the predicate constructor error
+func mustPredicate(p Predicate, err error) Predicate {
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func Test_IsCompareMatchingNumbersAndStrings(t *testing.T) {
+	tests := []struct {
+		field string
+		op    Op
+		value string
+		want  []string
+	}{
+		{"PriceValue", GreaterOrEqual, "12", []string{"v1|2|0", "v1|3|0"}},
+		{"PriceValue", Less, "100", []string{"v1|1|0", "v1|2|0"}},
+		{"pricevalue", LessOrEqual, "9.50", []string{"v1|1|0"}},
+		{"PriceValue", Greater, "9.5", []string{"v1|2|0", "v1|3|0"}},
+		{"PriceCurrency", Equal, "USD", []string{"v1|1|0", "v1|2|0"}},
+		{"PriceCurrency", NotEqual, "USD", []string{"v1|3|0", "v1|4|0"}},
+		{"Title", Less, "M", []string{"v1|2|0", "v1|3|0"}},
+	}
+	for _, tt := range tests {
+		p := mustPredicate(Compare(tt.field, tt.op, tt.value))
+		assert.DeepEqual(t, matching(p), tt.want)
+	}
+
+	_, err := Compare("Price", Equal, "1")
+	assert.ErrorContains(t, err, `unknown field "Price"`)
+	_, err = Compare("PriceValue", Op("=="), "1")
+	assert.ErrorContains(t, err, "unknown operator")
+}
+
+func Test_IsPredicateMatchingValues(t *testing.T) {
+	between := mustPredicate(Between("PriceValue", 9, 12))
+	assert.DeepEqual(t, matching(between), []string{"v1|1|0", "v1|2|0"})
+
+	matches := mustPredicate(Matches("Title", regexp.MustCompile(`(?i)^\w+ phone`)))
+	assert.DeepEqual(t, matching(matches), []string{"v1|1|0", "v1|2|0"})
+
+	contains := mustPredicate(Contains("Title", "PHONE"))
+	assert.DeepEqual(t, matching(contains), []string{"v1|1|0", "v1|2|0"})
+
+	in := mustPredicate(In("CategoryID", "177", "1"))
+	assert.DeepEqual(t, matching(in), []string{"v1|3|0", "v1|4|0"})
+
+	has := mustPredicate(Has("BuyingOptions", "best_offer"))
+	assert.DeepEqual(t, matching(has), []string{"v1|2|0", "v1|3|0"})
+
+	for _, err := range []error{
+		func() error { _, err := Between("Price", 0, 1); return err }(),
+		func() error { _, err := Matches("Price", regexp.MustCompile(".")); return err }(),
+		func() error { _, err := Contains("Price", ""); return err }(),
+		func() error { _, err := In("Price"); return err }(),
+		func() error { _, err := Has("Price"); return err }(),
+	} {
+		assert.ErrorContains(t, err, "unknown field")
+	}
+}
+
+func Test_IsPredicateComposable(t *testing.T) {
+	usd := mustPredicate(Compare("PriceCurrency", Equal, "USD"))
+	laptop := mustPredicate(Contains("Title", "laptop"))
+	cheap := mustPredicate(Compare("PriceValue", Less, "10"))
+
+	assert.DeepEqual(t, matching(And(usd, Not(cheap))), []string{"v1|2|0"})
+	assert.DeepEqual(t, matching(Or(cheap, laptop)), []string{"v1|1|0", "v1|3|0"})
+	assert.DeepEqual(t, matching(Not(Or(usd, laptop))), []string{"v1|4|0"})
+	assert.DeepEqual(t, matching(And()), []string{"v1|1|0", "v1|2|0", "v1|3|0", "v1|4|0"})
+	assert.Assert(t, matching(Or()) == nil)
+	assert.Equal(t, len(matching(All)), len(items))
+}
+
+func Test_IsReaderSkippingUnmatchedItems(t *testing.T) {
+	data := ebaytest.ItemFeed(items...)
+
+	ir, err := ebay.NewItemReader(bytes.NewReader(data))
+	assert.NilError(t, err)
+	defer ir.Close()
+
+	r := NewReader(ir, mustPredicate(In("CategoryID", "177")))
+	var ids []string
+	for {
+		item, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NilError(t, err)
+		ids = append(ids, item.ID)
+	}
+	assert.DeepEqual(t, ids, []string{"v1|3|0", "v1|4|0"})
+	assert.Equal(t, r.Read, 4)
+	assert.Equal(t, r.Matched, 2)
+
+	ids = nil
+	err = Items(bytes.NewReader(data), mustPredicate(Contains("Title", "phone")), func(item *ebay.Item) error {
+		ids = append(ids, item.ID)
+		return nil
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ids, []string{"v1|1|0", "v1|2|0"})
+
+	stop := errors.New("stop")
+	err = Items(bytes.NewReader(data), All, func(item *ebay.Item) error { return stop })
+	assert.Equal(t, err, stop)
+}
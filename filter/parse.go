@@ -0,0 +1,303 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// tokenKind is the kind of an expression token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// operatorChars are the characters of the comparison operators
+const operatorChars = "=!<>~"
+
+// Parse parses a filter expression into a Predicate. The expression combines comparisons with AND, OR, NOT
+// and parentheses; AND binds tighter than OR and the keywords are case insensitive. The comparisons are:
+//
+//	Field = value, Field != value, Field < value, Field <= value, Field > value, Field >= value
+//	Field ~ "regexp"              the field matches the regular expression, !~ for not matching
+//	Field IN (value, ...)         the field is one of the values
+//	Field HAS (value, ...)        the multi-valued field contains one of the values, i.e. BuyingOptions
+//	Field CONTAINS value          the field contains the value, ignoring the case
+//
+// The fields are the Item field names, case insensitive. The values are bare words or strings quoted with " or '.
+// For example:
+//
+//	PriceValue >= 10 AND PriceValue < 100 AND CategoryID IN (9355, 177) AND NOT Title ~ "(?i)broken"
+func Parse(expr string) (Predicate, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, fmt.Errorf("Parse(): %v", err)
+	}
+
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return All, nil
+	}
+
+	predicate, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("Parse(): %v", err)
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("Parse(): unexpected %q at position %v", t.text, t.pos)
+	}
+	return predicate, nil
+}
+
+// lex splits the expression into tokens
+func lex(expr string) ([]token, error) {
+	var tokens []token
+
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLeftParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRightParen, text: ")", pos: i})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: i})
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			var b strings.Builder
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && runes[i+1] == r {
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %v", start)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: b.String(), pos: start})
+			i++
+		case strings.ContainsRune(operatorChars, r):
+			start := i
+			for i < len(runes) && strings.ContainsRune(operatorChars, runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: string(runes[start:i]), pos: start})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(operatorChars+`(),"'`, runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: string(runes[start:i]), pos: start})
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, text: "end of expression", pos: len(runes)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword reports whether the next token is the given keyword, consuming it if so
+func (p *parser) keyword(k string) bool {
+	t := p.peek()
+	if t.kind == tokenWord && strings.EqualFold(t.text, k) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (Predicate, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	predicates := []Predicate{left}
+	for p.keyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, right)
+	}
+
+	if len(predicates) == 1 {
+		return left, nil
+	}
+	return Or(predicates...), nil
+}
+
+func (p *parser) parseAnd() (Predicate, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	predicates := []Predicate{left}
+	for p.keyword("AND") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, right)
+	}
+
+	if len(predicates) == 1 {
+		return left, nil
+	}
+	return And(predicates...), nil
+}
+
+func (p *parser) parseUnary() (Predicate, error) {
+	if p.keyword("NOT") {
+		predicate, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(predicate), nil
+	}
+
+	if p.peek().kind == tokenLeftParen {
+		p.next()
+		predicate, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokenRightParen {
+			return nil, fmt.Errorf("expected ) at position %v, found %q", t.pos, t.text)
+		}
+		return predicate, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Predicate, error) {
+	f := p.next()
+	if f.kind != tokenWord {
+		return nil, fmt.Errorf("expected a field at position %v, found %q", f.pos, f.text)
+	}
+	if _, err := field(f.text); err != nil {
+		return nil, fmt.Errorf("%v at position %v", err, f.pos)
+	}
+
+	switch {
+	case p.keyword("IN"):
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return In(f.text, values...)
+	case p.keyword("HAS"):
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return Has(f.text, values...)
+	case p.keyword("CONTAINS"):
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return Contains(f.text, v)
+	}
+
+	op := p.next()
+	if op.kind != tokenOp {
+		return nil, fmt.Errorf("expected an operator after %v at position %v, found %q", f.text, op.pos, op.text)
+	}
+
+	v, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.text {
+	case "~", "!~":
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression at position %v: %v", op.pos, err)
+		}
+		predicate, err := Matches(f.text, re)
+		if err != nil || op.text == "~" {
+			return predicate, err
+		}
+		return Not(predicate), nil
+	case "==":
+		return Compare(f.text, Equal, v)
+	}
+
+	predicate, err := Compare(f.text, Op(op.text), v)
+	if err != nil {
+		return nil, fmt.Errorf("%v at position %v", err, op.pos)
+	}
+	return predicate, nil
+}
+
+func (p *parser) parseValue() (string, error) {
+	t := p.next()
+	if t.kind != tokenWord && t.kind != tokenString {
+		return "", fmt.Errorf("expected a value at position %v, found %q", t.pos, t.text)
+	}
+	return t.text, nil
+}
+
+// parseList parses a parenthesized, comma separated list of values
+func (p *parser) parseList() ([]string, error) {
+	if t := p.next(); t.kind != tokenLeftParen {
+		return nil, fmt.Errorf("expected ( at position %v, found %q", t.pos, t.text)
+	}
+
+	var values []string
+	for {
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+
+		t := p.next()
+		if t.kind == tokenRightParen {
+			return values, nil
+		}
+		if t.kind != tokenComma {
+			return nil, fmt.Errorf("expected , or ) at position %v, found %q", t.pos, t.text)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package filter
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func Test_IsParseBuildingPredicates(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"", []string{"v1|1|0", "v1|2|0", "v1|3|0", "v1|4|0"}},
+		{"PriceValue >= 10", []string{"v1|2|0", "v1|3|0"}},
+		{"PriceValue >= 10 AND PriceValue < 100", []string{"v1|2|0"}},
+		{"PriceCurrency == 'USD'", []string{"v1|1|0", "v1|2|0"}},
+		{"categoryid in (177, 1) and not title = Laptop", []string{"v1|4|0"}},
+		{`Title ~ "(?i)phone$" OR CategoryID = 177`, []string{"v1|1|0", "v1|3|0", "v1|4|0"}},
+		{`Title !~ "(?i)phone"`, []string{"v1|3|0", "v1|4|0"}},
+		{"BuyingOptions HAS (FIXED_PRICE) AND PriceCurrency != EUR", []string{"v1|1|0"}},
+		{"NOT (PriceCurrency = USD OR Title CONTAINS lap)", []string{"v1|4|0"}},
+		{`Title = "Blue Phone case"`, []string{"v1|2|0"}},
+		{`Title = 'It\'s'`, nil},
+		{
+			`PriceValue >= 10 AND PriceValue < 1000 AND CategoryID IN (9355, 177) AND NOT Title ~ "(?i)case" AND BuyingOptions HAS (FIXED_PRICE, AUCTION)`,
+			[]string{"v1|3|0"},
+		},
+	}
+	for _, tt := range tests {
+		p, err := Parse(tt.expr)
+		assert.NilError(t, err, tt.expr)
+		assert.DeepEqual(t, matching(p), tt.want)
+	}
+}
+
+func Test_IsParseReportingSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"Price = 1", `unknown field "Price" at position 0`},
+		{"PriceValue", "expected an operator after PriceValue at position 10"},
+		{"PriceValue >", "expected a value at position 12"},
+		{"PriceValue => 1", `unknown operator "=>" at position 11`},
+		{"PriceValue = 1 AND", "expected a field at position 18"},
+		{"(PriceValue = 1", "expected ) at position 15"},
+		{"PriceValue = 1)", `unexpected ")" at position 14`},
+		{"PriceValue = 1 PriceValue = 2", `unexpected "PriceValue" at position 15`},
+		{"CategoryID IN 177", "expected ( at position 14"},
+		{"CategoryID IN (177 1)", "expected , or ) at position 19"},
+		{`Title ~ "("`, "invalid regular expression at position 6"},
+		{`Title = "red`, "unterminated string at position 8"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.expr)
+		assert.ErrorContains(t, err, tt.want, tt.expr)
+	}
+}
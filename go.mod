@@ -3,9 +3,13 @@ module ebay-api-client
 go 1.14
 
 require (
-	github.com/golang/mock v1.4.3
+	github.com/BurntSushi/toml v0.3.1
 	github.com/google/go-querystring v1.0.0
+	github.com/xitongsys/parquet-go v1.5.2
+	github.com/xitongsys/parquet-go-source v0.0.0-20200509081216-8db33acb0acf
+	go.etcd.io/bbolt v1.3.5
 	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
+	gopkg.in/yaml.v2 v2.3.0
 	gotest.tools v2.2.0+incompatible
 	gotest.tools/v3 v3.0.2
 )
@@ -0,0 +1,271 @@
+// Package inventory maintains the current state of a category from the Feed API files: the weekly item bootstrap
+// is loaded into a Store keyed by item id, then the hourly item snapshots are applied in order on top of it.
+// The consolidated state can be written out as a feed file with the same format of the bootstrap.
+package inventory
+
+import (
+	"compress/gzip"
+	"ebay-api-client/ebay"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	availabilityUnavailable string = "UNAVAILABLE"
+	availabilityOutOfStock  string = "OUT_OF_STOCK"
+
+	// batchSize is the number of changes written to the store at once
+	batchSize = 10000
+)
+
+var (
+	// ErrStaleSnapshot is returned by ApplySnapshot when the snapshot changes are already in the inventory
+	ErrStaleSnapshot = errors.New("snapshot is older than the inventory watermark")
+	// ErrSnapshotGap is returned by ApplySnapshot when the snapshots of the hours between the watermark and the snapshot are missing
+	ErrSnapshotGap = errors.New("snapshot does not follow the inventory watermark")
+)
+
+// Inventory applies the item snapshots onto a bootstrap
+type Inventory struct {
+	// Store keeps the items
+	Store Store
+	// Header is the columns header written by WriteFeed, it is set by LoadBootstrap
+	Header []string
+	// Removed reports whether a snapshot row removes the item from the inventory, defaults to IsRemoved
+	Removed func(item *ebay.Item) bool
+}
+
+// Changes counts the changes applied by a snapshot
+type Changes struct {
+	// Added is the number of items not in the inventory before the snapshot
+	Added int
+	// Updated is the number of items replaced by the snapshot
+	Updated int
+	// Removed is the number of items removed by the snapshot
+	Removed int
+	// Ignored is the number of removals of items which were not in the inventory
+	Ignored int
+}
+
+// New creates a new Inventory keeping the items in the given store
+func New(store Store) *Inventory {
+	return &Inventory{
+		Store:   store,
+		Header:  ebay.ItemFields,
+		Removed: IsRemoved,
+	}
+}
+
+// IsRemoved is the default rule deciding whether a snapshot row removes the item: the item is not available anymore
+// or the row only carries the item id.
+func IsRemoved(item *ebay.Item) bool {
+	switch strings.ToUpper(item.Availability) {
+	case availabilityUnavailable, availabilityOutOfStock:
+		return true
+	}
+	return item.Title == "" && item.CategoryID == "" && item.PriceValue == ""
+}
+
+// LoadBootstrap replaces the inventory content with the items of the gzipped bootstrap feed read from r.
+// generatedAt is when the bootstrap was generated (the feed Last-Modified): the snapshots of the hours before it
+// are already included. If zero, the first snapshot applied is accepted whatever its hour.
+// It returns the number of items loaded.
+func (inv *Inventory) LoadBootstrap(r io.Reader, generatedAt time.Time) (int, error) {
+	ir, err := ebay.NewItemReader(r)
+	if err != nil {
+		return 0, fmt.Errorf("LoadBootstrap(): %v", err)
+	}
+	defer ir.Close()
+
+	if err := inv.Store.Clear(); err != nil {
+		return 0, fmt.Errorf("LoadBootstrap(): cannot clear store: %v", err)
+	}
+
+	b := newBatch(inv.Store)
+	loaded := 0
+	for {
+		item, err := ir.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return loaded, fmt.Errorf("LoadBootstrap(): %v", err)
+		}
+		if item.ID == "" {
+			continue
+		}
+
+		if err := b.put(item.ID, ir.Text()); err != nil {
+			return loaded, fmt.Errorf("LoadBootstrap(): cannot store items: %v", err)
+		}
+		loaded++
+	}
+	if err := b.flush(); err != nil {
+		return loaded, fmt.Errorf("LoadBootstrap(): cannot store items: %v", err)
+	}
+
+	if err := inv.Store.SetWatermark(generatedAt.UTC()); err != nil {
+		return loaded, fmt.Errorf("LoadBootstrap(): cannot set watermark: %v", err)
+	}
+	inv.Header = ir.Header()
+
+	return loaded, nil
+}
+
+// ApplySnapshot applies the gzipped item snapshot of the given hour read from r: the items are inserted or replaced,
+// or removed when the Removed rule says so. The snapshots must be applied in order, without gaps:
+// ErrStaleSnapshot is returned if the snapshot is already included and ErrSnapshotGap if a previous one is missing.
+// On success the watermark moves to the end of the snapshot hour.
+func (inv *Inventory) ApplySnapshot(r io.Reader, hour time.Time) (*Changes, error) {
+	hour = hour.UTC().Truncate(time.Hour)
+	end := hour.Add(time.Hour)
+
+	watermark, err := inv.Store.Watermark()
+	if err != nil {
+		return nil, fmt.Errorf("ApplySnapshot(): cannot read watermark: %v", err)
+	}
+	if !watermark.IsZero() {
+		if !end.After(watermark) {
+			return nil, ErrStaleSnapshot
+		}
+		if hour.After(watermark) {
+			return nil, ErrSnapshotGap
+		}
+	}
+
+	ir, err := ebay.NewItemReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("ApplySnapshot(): %v", err)
+	}
+	defer ir.Close()
+
+	removed := inv.Removed
+	if removed == nil {
+		removed = IsRemoved
+	}
+
+	b := newBatch(inv.Store)
+	changes := &Changes{}
+	for {
+		item, err := ir.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return changes, fmt.Errorf("ApplySnapshot(): %v", err)
+		}
+		if item.ID == "" {
+			continue
+		}
+
+		exists, err := b.exists(item.ID)
+		if err != nil {
+			return changes, fmt.Errorf("ApplySnapshot(): cannot read item %v: %v", item.ID, err)
+		}
+
+		switch {
+		case removed(item) && exists:
+			err = b.delete(item.ID)
+			changes.Removed++
+		case removed(item):
+			changes.Ignored++
+		default:
+			err = b.put(item.ID, ir.Text())
+			if exists {
+				changes.Updated++
+			} else {
+				changes.Added++
+			}
+		}
+		if err != nil {
+			return changes, fmt.Errorf("ApplySnapshot(): cannot update items: %v", err)
+		}
+	}
+	if err := b.flush(); err != nil {
+		return changes, fmt.Errorf("ApplySnapshot(): cannot update items: %v", err)
+	}
+
+	if err := inv.Store.SetWatermark(end); err != nil {
+		return changes, fmt.Errorf("ApplySnapshot(): cannot set watermark: %v", err)
+	}
+	return changes, nil
+}
+
+// batch buffers the changes written to the store by batches of batchSize changes
+type batch struct {
+	store   Store
+	changes []Change
+	// pending tells whether the buffered changes put (true) or delete (false) an item, by item id
+	pending map[string]bool
+}
+
+func newBatch(store Store) *batch {
+	return &batch{store: store, pending: make(map[string]bool)}
+}
+
+// exists reports whether the item is in the store once the buffered changes are written
+func (b *batch) exists(id string) (bool, error) {
+	if put, ok := b.pending[id]; ok {
+		return put, nil
+	}
+	_, ok, err := b.store.Get(id)
+	return ok, err
+}
+
+func (b *batch) put(id, row string) error {
+	return b.add(Change{ID: id, Row: row})
+}
+
+func (b *batch) delete(id string) error {
+	return b.add(Change{ID: id, Delete: true})
+}
+
+func (b *batch) add(c Change) error {
+	b.changes = append(b.changes, c)
+	b.pending[c.ID] = !c.Delete
+	if len(b.changes) == batchSize {
+		return b.flush()
+	}
+	return nil
+}
+
+// flush writes the buffered changes
+func (b *batch) flush() error {
+	if len(b.changes) == 0 {
+		return nil
+	}
+	err := b.store.WriteBatch(b.changes)
+	b.changes = b.changes[:0]
+	b.pending = make(map[string]bool)
+	return err
+}
+
+// Watermark returns the time up to which the changes are applied
+func (inv *Inventory) Watermark() (time.Time, error) {
+	return inv.Store.Watermark()
+}
+
+// WriteFeed writes the inventory into w as a gzipped TSV feed ordered by item id, with the bootstrap header
+func (inv *Inventory) WriteFeed(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+
+	if _, err := io.WriteString(gz, strings.Join(inv.Header, "\t")+"\n"); err != nil {
+		return fmt.Errorf("WriteFeed(): cannot write header: %v", err)
+	}
+
+	err := inv.Store.ForEach(func(id, row string) error {
+		_, err := io.WriteString(gz, row+"\n")
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("WriteFeed(): cannot write items: %v", err)
+	}
+
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("WriteFeed(): cannot write feed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package inventory
+
+import (
+	"bytes"
+	"ebay-api-client/ebay"
+	"ebay-api-client/ebay/ebaytest"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+// readFeed returns the items of a gzipped feed
+func readFeed(t *testing.T, data []byte) []*ebay.Item {
+	r, err := ebay.NewItemReader(bytes.NewReader(data))
+	assert.NilError(t, err)
+	defer r.Close()
+
+	var items []*ebay.Item
+	for {
+		item, err := r.Next()
+		if err == io.EOF {
+			return items
+		}
+		assert.NilError(t, err)
+		items = append(items, item)
+	}
+}
+
+func hour(h int) time.Time {
+	return time.Date(2020, 5, 6, h, 0, 0, 0, time.UTC)
+}
+
+func Test_IsSnapshotAppliedOntoBootstrap(t *testing.T) {
+	inv := New(NewMemoryStore())
+
+	n, err := inv.LoadBootstrap(bytes.NewReader(ebaytest.ItemFeed(
+		&ebay.Item{ID: "v1|3|0", Title: "Item 3", PriceValue: "3"},
+		&ebay.Item{ID: "v1|1|0", Title: "Item 1", PriceValue: "1"},
+		&ebay.Item{ID: "v1|2|0", Title: "Item 2", PriceValue: "2"},
+	)), hour(5).Add(20*time.Minute))
+	assert.NilError(t, err)
+	assert.Equal(t, n, 3)
+
+	changes, err := inv.ApplySnapshot(bytes.NewReader(ebaytest.ItemFeed(
+		&ebay.Item{ID: "v1|1|0", Availability: "OUT_OF_STOCK", Title: "Item 1"},
+		&ebay.Item{ID: "v1|2|0", Title: "Item 2", PriceValue: "2.5"},
+		&ebay.Item{ID: "v1|4|0", Title: "Item 4", PriceValue: "4"},
+		&ebay.Item{ID: "v1|9|0"},
+	)), hour(5))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, changes, &Changes{Added: 1, Updated: 1, Removed: 1, Ignored: 1})
+
+	watermark, err := inv.Watermark()
+	assert.NilError(t, err)
+	assert.Equal(t, watermark, hour(6))
+
+	_, err = inv.ApplySnapshot(bytes.NewReader(ebaytest.ItemFeed(&ebay.Item{ID: "v1|5|0", Title: "Item 5"})), hour(5))
+	assert.Equal(t, err, ErrStaleSnapshot)
+
+	_, err = inv.ApplySnapshot(bytes.NewReader(ebaytest.ItemFeed(&ebay.Item{ID: "v1|5|0", Title: "Item 5"})), hour(7))
+	assert.Equal(t, err, ErrSnapshotGap)
+
+	changes, err = inv.ApplySnapshot(bytes.NewReader(ebaytest.ItemFeed(&ebay.Item{ID: "v1|3|0"})), hour(6))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, changes, &Changes{Removed: 1})
+
+	out := new(bytes.Buffer)
+	assert.NilError(t, inv.WriteFeed(out))
+
+	items := readFeed(t, out.Bytes())
+	assert.Equal(t, len(items), 2)
+	assert.Equal(t, items[0].ID, "v1|2|0")
+	assert.Equal(t, items[0].PriceValue, "2.5")
+	assert.Equal(t, items[1].ID, "v1|4|0")
+}
+
+func Test_IsSnapshotBeforeBootstrapStale(t *testing.T) {
+	inv := New(NewMemoryStore())
+
+	_, err := inv.LoadBootstrap(bytes.NewReader(ebaytest.ItemFeed(&ebay.Item{ID: "v1|1|0", Title: "Item 1"})), hour(5).Add(20*time.Minute))
+	assert.NilError(t, err)
+
+	_, err = inv.ApplySnapshot(bytes.NewReader(ebaytest.ItemFeed(&ebay.Item{ID: "v1|1|0"})), hour(4))
+	assert.Equal(t, err, ErrStaleSnapshot)
+
+	n, err := inv.Store.Len()
+	assert.NilError(t, err)
+	assert.Equal(t, n, 1)
+}
+
+func Test_IsFirstSnapshotAcceptedWithoutWatermark(t *testing.T) {
+	inv := New(NewMemoryStore())
+	inv.Removed = func(item *ebay.Item) bool { return item.EndDate != "" }
+
+	_, err := inv.LoadBootstrap(bytes.NewReader(ebaytest.ItemFeed(&ebay.Item{ID: "v1|1|0", Title: "Item 1"})), time.Time{})
+	assert.NilError(t, err)
+
+	changes, err := inv.ApplySnapshot(bytes.NewReader(ebaytest.ItemFeed(&ebay.Item{ID: "v1|1|0", EndDate: "2020-05-06T10:00:00.000Z"})), hour(10))
+	assert.NilError(t, err)
+	assert.Equal(t, changes.Removed, 1)
+
+	_, err = inv.ApplySnapshot(bytes.NewReader([]byte("not gzipped")), hour(11))
+	assert.ErrorContains(t, err, "cannot gunzip feed")
+}
+
+// batchStore is a MemoryStore recording the sizes of the written batches, the single writes are not expected
+type batchStore struct {
+	*MemoryStore
+	batches []int
+}
+
+func (b *batchStore) Put(id, row string) error {
+	panic("unexpected Put of " + id)
+}
+
+func (b *batchStore) Delete(id string) error {
+	panic("unexpected Delete of " + id)
+}
+
+func (b *batchStore) WriteBatch(changes []Change) error {
+	b.batches = append(b.batches, len(changes))
+	return b.MemoryStore.WriteBatch(changes)
+}
+
+func Test_IsInventoryWritingBatches(t *testing.T) {
+	store := &batchStore{MemoryStore: NewMemoryStore()}
+	inv := New(store)
+
+	items := make([]*ebay.Item, batchSize+1)
+	for n := range items {
+		items[n] = &ebay.Item{ID: fmt.Sprintf("v1|%v|0", n), Title: "Item"}
+	}
+	n, err := inv.LoadBootstrap(bytes.NewReader(ebaytest.ItemFeed(items...)), hour(5))
+	assert.NilError(t, err)
+	assert.Equal(t, n, batchSize+1)
+	assert.DeepEqual(t, store.batches, []int{batchSize, 1})
+
+	// The changes of the same item in a batch are counted with the previous changes of the batch
+	changes, err := inv.ApplySnapshot(bytes.NewReader(ebaytest.ItemFeed(
+		&ebay.Item{ID: "v1|new|0", Title: "Added"},
+		&ebay.Item{ID: "v1|new|0", Title: "Updated"},
+		&ebay.Item{ID: "v1|0|0", Availability: "UNAVAILABLE"},
+		&ebay.Item{ID: "v1|0|0", Availability: "UNAVAILABLE"},
+	)), hour(5))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, changes, &Changes{Added: 1, Updated: 1, Removed: 1, Ignored: 1})
+	assert.DeepEqual(t, store.batches, []int{batchSize, 1, 3})
+
+	row, ok, err := store.Get("v1|new|0")
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	assert.Equal(t, ebay.NewItemFromTSV(row).Title, "Updated")
+	_, ok, err = store.Get("v1|0|0")
+	assert.NilError(t, err)
+	assert.Assert(t, !ok)
+}
+
+func Test_IsMemoryStoreOrderedByID(t *testing.T) {
+	s := NewMemoryStore()
+	for _, id := range []string{"c", "a", "b"} {
+		assert.NilError(t, s.Put(id, "row "+id))
+	}
+	assert.NilError(t, s.Delete("b"))
+	assert.NilError(t, s.Delete("unknown"))
+	assert.NilError(t, s.WriteBatch([]Change{{ID: "d", Row: "row d"}, {ID: "d", Delete: true}, {ID: "e", Row: "row e"}}))
+
+	var rows []string
+	assert.NilError(t, s.ForEach(func(id, row string) error {
+		rows = append(rows, row)
+		return nil
+	}))
+	assert.DeepEqual(t, rows, []string{"row a", "row c", "row e"})
+
+	assert.NilError(t, s.SetWatermark(hour(1)))
+	assert.NilError(t, s.Clear())
+
+	n, err := s.Len()
+	assert.NilError(t, err)
+	assert.Equal(t, n, 0)
+
+	watermark, err := s.Watermark()
+	assert.NilError(t, err)
+	assert.Assert(t, watermark.IsZero())
+}
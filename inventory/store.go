@@ -0,0 +1,147 @@
+package inventory
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store keeps the feed rows of the inventory by item id, together with the snapshot watermark.
+type Store interface {
+	// Get returns the row of the item with the given id, false if the item is not in the store
+	Get(id string) (string, bool, error)
+	// Put inserts or replaces the row of the item with the given id
+	Put(id, row string) error
+	// Delete removes the item with the given id, it does nothing if the item is not in the store
+	Delete(id string) error
+	// WriteBatch applies the changes in order, at once when the store supports transactions
+	WriteBatch(changes []Change) error
+	// Len returns the number of items in the store
+	Len() (int, error)
+	// ForEach calls fn for each item, ordered by id, stopping at the first error
+	ForEach(fn func(id, row string) error) error
+	// Clear removes all the items and resets the watermark
+	Clear() error
+	// Watermark returns the time up to which the changes are applied, zero if unknown
+	Watermark() (time.Time, error)
+	// SetWatermark sets the time up to which the changes are applied
+	SetWatermark(t time.Time) error
+}
+
+// Change is a write of a batch: the row of the item, or its removal if Delete is set
+type Change struct {
+	// ID is the item id
+	ID string
+	// Row is the new feed row of the item
+	Row string
+	// Delete removes the item instead
+	Delete bool
+}
+
+// MemoryStore is a Store keeping the rows in memory
+type MemoryStore struct {
+	mu        sync.RWMutex
+	rows      map[string]string
+	watermark time.Time
+}
+
+// NewMemoryStore creates a new empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{rows: make(map[string]string)}
+}
+
+// Get returns the row of the item with the given id, false if the item is not in the store
+func (m *MemoryStore) Get(id string) (string, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	row, ok := m.rows[id]
+	return row, ok, nil
+}
+
+// Put inserts or replaces the row of the item with the given id
+func (m *MemoryStore) Put(id, row string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rows[id] = row
+	return nil
+}
+
+// Delete removes the item with the given id
+func (m *MemoryStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.rows, id)
+	return nil
+}
+
+// WriteBatch applies the changes in order
+func (m *MemoryStore) WriteBatch(changes []Change) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range changes {
+		if c.Delete {
+			delete(m.rows, c.ID)
+		} else {
+			m.rows[c.ID] = c.Row
+		}
+	}
+	return nil
+}
+
+// Len returns the number of items in the store
+func (m *MemoryStore) Len() (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.rows), nil
+}
+
+// ForEach calls fn for each item, ordered by id. The store must not be modified by fn.
+func (m *MemoryStore) ForEach(fn func(id, row string) error) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := make([]string, 0, len(m.rows))
+	for id := range m.rows {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if err := fn(id, m.rows[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Clear removes all the items and resets the watermark
+func (m *MemoryStore) Clear() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rows = make(map[string]string)
+	m.watermark = time.Time{}
+	return nil
+}
+
+// Watermark returns the time up to which the changes are applied
+func (m *MemoryStore) Watermark() (time.Time, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.watermark, nil
+}
+
+// SetWatermark sets the time up to which the changes are applied
+func (m *MemoryStore) SetWatermark(t time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.watermark = t
+	return nil
+}
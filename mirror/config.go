@@ -0,0 +1,94 @@
+package mirror
+
+import (
+	"ebay-api-client/config"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+const (
+	// DefaultStateFile is the name of the state file created in the mirror directory when Config.StateFile is not set
+	DefaultStateFile string = "mirror-state.json"
+)
+
+// Config lists the feeds to mirror and where to store them
+type Config struct {
+	// Dir is the directory the feeds are written into, in <market>/<category> sub directories
+	Dir string `json:"dir" yaml:"dir" toml:"dir"`
+	// StateFile is the path of the state file, defaults to DefaultStateFile in Dir
+	StateFile string `json:"state_file" yaml:"state_file" toml:"state_file"`
+	// Feeds are the marketplaces and categories to mirror
+	Feeds []FeedConfig `json:"feeds" yaml:"feeds" toml:"feeds"`
+}
+
+// FeedConfig selects the feeds to mirror for one marketplace
+type FeedConfig struct {
+	// MarketID is the eBay marketplace id, i.e. EBAY_US
+	MarketID string `json:"market_id" yaml:"market_id" toml:"market_id"`
+	// CategoryIDs are the eBay top level category ids
+	CategoryIDs []string `json:"category_ids" yaml:"category_ids" toml:"category_ids"`
+	// Kinds are the feed kinds to mirror, all of them if empty
+	Kinds []Kind `json:"kinds" yaml:"kinds" toml:"kinds"`
+}
+
+// LoadConfig reads the mirror configuration from the given file.
+// The file format is detected from its extension: .json, .yaml, .yml and .toml are supported.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("LoadConfig(): cannot read configuration file: %v", err)
+	}
+
+	c := &Config{}
+	if err := config.Unmarshal(path, data, c); err != nil {
+		return nil, fmt.Errorf("LoadConfig(): %v", err)
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Validate checks that the configuration is complete
+func (c *Config) Validate() error {
+	if c.Dir == "" {
+		return fmt.Errorf("Validate(): dir is required")
+	}
+
+	if len(c.Feeds) == 0 {
+		return fmt.Errorf("Validate(): at least one feed is required")
+	}
+
+	for _, f := range c.Feeds {
+		if f.MarketID == "" {
+			return fmt.Errorf("Validate(): market_id is required")
+		}
+		if len(f.CategoryIDs) == 0 {
+			return fmt.Errorf("Validate(): category_ids are required for %v", f.MarketID)
+		}
+		for _, k := range f.Kinds {
+			if !k.valid() {
+				return fmt.Errorf("Validate(): unknown feed kind %q for %v, expected one of %v", k, f.MarketID, Kinds)
+			}
+		}
+	}
+	return nil
+}
+
+// statePath returns the path of the state file
+func (c *Config) statePath() string {
+	if c.StateFile != "" {
+		return c.StateFile
+	}
+	return filepath.Join(c.Dir, DefaultStateFile)
+}
+
+// kinds returns the feed kinds to mirror
+func (f *FeedConfig) kinds() []Kind {
+	if len(f.Kinds) == 0 {
+		return Kinds
+	}
+	return f.Kinds
+}
@@ -0,0 +1,251 @@
+// Package mirror keeps a local copy of eBay feeds up to date. It downloads the weekly bootstrap, daily newly listed
+// and hourly snapshot feeds of the configured marketplaces and categories when eBay publishes them, records the last
+// feed downloaded in a state file and, after a restart, catches up on the feeds missed in the meantime.
+// The feeds are written atomically: a partial download is never visible under the final file name.
+package mirror
+
+import (
+	"context"
+	"ebay-api-client/ebay"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"time"
+)
+
+const (
+	bootstrapSlotFormat string = "20060102"
+	dailySlotFormat     string = "20060102"
+	snapshotSlotFormat  string = "2006010215"
+)
+
+// FeedService downloads the feeds, it is implemented by ebay.FeedService
+type FeedService interface {
+	WeeklyItemBoostrap(ctx context.Context, marketID, categoryID string, dst io.Writer) (*ebay.FeedInfo, error)
+	DailyNewlyItems(ctx context.Context, marketID, categoryID string, date time.Time, dst io.Writer) (*ebay.FeedInfo, error)
+	ItemShapshot(ctx context.Context, marketID, categoryID string, date time.Time, dst io.Writer) (*ebay.FeedInfo, error)
+}
+
+// Mirror downloads the configured feeds into the mirror directory
+type Mirror struct {
+	// Service is the Feed API service used to download the feeds
+	Service FeedService
+	// Config lists the feeds to mirror
+	Config *Config
+	// Schedule is the feeds publication schedule
+	Schedule Schedule
+	// Logger reports the downloads and the errors. Nothing is logged if nil.
+	Logger *log.Logger
+
+	// now returns the current time, overridden in tests
+	now func() time.Time
+	// pending is set by Sync when some feeds were not published yet
+	pending bool
+}
+
+// New creates a new Mirror following the DefaultSchedule
+func New(service FeedService, config *Config) *Mirror {
+	return &Mirror{
+		Service:  service,
+		Config:   config,
+		Schedule: DefaultSchedule,
+		now:      time.Now,
+	}
+}
+
+// Run synchronizes the mirror until the context is cancelled, waking up when the next feed is published.
+// The feeds which failed or were not yet published are retried after Schedule.RetryInterval.
+// An invalid configuration cannot be fixed by retrying, Run returns its error right away.
+func (m *Mirror) Run(ctx context.Context) error {
+	if err := m.Config.Validate(); err != nil {
+		return err
+	}
+
+	for {
+		err := m.Sync(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		wait := m.nextSync(err != nil || m.pending).Sub(m.now())
+		if wait < 0 {
+			wait = 0
+		}
+		m.logf("next synchronization in %v", wait.Round(time.Second))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Sync downloads the feeds published since the last downloaded ones and updates the state file after each download.
+// The latest feeds not yet published (no content or an unchanged bootstrap) are tried again at the next Sync,
+// while the older missing ones are skipped.
+// All the feeds are tried even if some fail, except when the call limit is exceeded. The first error is returned.
+// The errors are logged, including the ones of the configuration and of the state file which stop Sync.
+func (m *Mirror) Sync(ctx context.Context) error {
+	if err := m.Config.Validate(); err != nil {
+		m.logf("cannot synchronize: %v", err)
+		return err
+	}
+
+	statePath := m.Config.statePath()
+	state, err := LoadState(statePath)
+	if err != nil {
+		m.logf("cannot synchronize: %v", err)
+		return err
+	}
+
+	m.pending = false
+
+	var first error
+	for _, f := range m.Config.Feeds {
+		for _, categoryID := range f.CategoryIDs {
+			for _, kind := range f.kinds() {
+				err := m.syncFeed(ctx, state, statePath, kind, f.MarketID, categoryID)
+				if err == nil {
+					continue
+				}
+
+				m.logf("%v: %v", feedKey(kind, f.MarketID, categoryID), err)
+				if first == nil {
+					first = err
+				}
+				if ebay.IsRateLimited(err) || ctx.Err() != nil {
+					return first
+				}
+			}
+		}
+	}
+	return first
+}
+
+// syncFeed downloads the missed feeds of a kind, marketplace and category, oldest first
+func (m *Mirror) syncFeed(ctx context.Context, state *State, statePath string, kind Kind, marketID, categoryID string) error {
+	last := state.Last(kind, marketID, categoryID)
+
+	var lastSlot time.Time
+	if last != nil {
+		lastSlot = last.Slot
+	}
+
+	slots := m.Schedule.slots(kind, lastSlot, m.now())
+	for n, slot := range slots {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		fs, err := m.download(ctx, kind, marketID, categoryID, slot, last)
+		if ebay.IsNoContent(err) && n < len(slots)-1 {
+			// a newer feed is expected, this one is missing and will not be published anymore
+			m.logf("%v: no feed for %v", feedKey(kind, marketID, categoryID), slot.Format(time.RFC3339))
+			continue
+		}
+		if ebay.IsNoContent(err) || err == errNotPublished {
+			m.logf("%v: feed of %v not published yet", feedKey(kind, marketID, categoryID), slot.Format(time.RFC3339))
+			m.pending = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		state.Feeds[fs.Key()] = fs
+		if err := state.Save(statePath); err != nil {
+			return err
+		}
+		m.logf("%v: downloaded %v (%v bytes)", fs.Key(), fs.File, fs.Info.Size)
+		last = fs
+	}
+	return nil
+}
+
+// errNotPublished is returned by download when the weekly bootstrap feed has not been replaced yet
+var errNotPublished = fmt.Errorf("feed not published yet")
+
+// download downloads the feed of the given slot into the mirror directory
+func (m *Mirror) download(ctx context.Context, kind Kind, marketID, categoryID string, slot time.Time, last *FeedState) (*FeedState, error) {
+	path := m.path(kind, marketID, categoryID, slot)
+
+	var info *ebay.FeedInfo
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		var err error
+		switch kind {
+		case Bootstrap:
+			info, err = m.Service.WeeklyItemBoostrap(ctx, marketID, categoryID, w)
+		case Daily:
+			info, err = m.Service.DailyNewlyItems(ctx, marketID, categoryID, slot, w)
+		default:
+			info, err = m.Service.ItemShapshot(ctx, marketID, categoryID, slot, w)
+		}
+		if err != nil {
+			return err
+		}
+
+		// eBay keeps serving the previous bootstrap feed until the new one is generated
+		if kind == Bootstrap && last != nil && !info.LastModified.After(last.Info.LastModified) {
+			return errNotPublished
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &FeedState{
+		Kind:         kind,
+		MarketID:     marketID,
+		CategoryID:   categoryID,
+		Slot:         slot,
+		File:         path,
+		Info:         *info,
+		DownloadedAt: m.now().UTC(),
+	}, nil
+}
+
+// path returns the path of the feed file: <dir>/<market>/<category>/<kind>_<slot>.tsv.gz
+func (m *Mirror) path(kind Kind, marketID, categoryID string, slot time.Time) string {
+	format := snapshotSlotFormat
+	switch kind {
+	case Bootstrap:
+		format = bootstrapSlotFormat
+	case Daily:
+		format = dailySlotFormat
+	}
+
+	name := fmt.Sprintf("%v_%v.tsv.gz", kind, slot.Format(format))
+	return filepath.Join(m.Config.Dir, marketID, categoryID, name)
+}
+
+// nextSync returns when the next feed is published, or when to retry if the last Sync failed or found feeds not published yet
+func (m *Mirror) nextSync(retry bool) time.Time {
+	now := m.now()
+
+	var next time.Time
+	for _, f := range m.Config.Feeds {
+		for _, kind := range f.kinds() {
+			if t := m.Schedule.next(kind, now); next.IsZero() || t.Before(next) {
+				next = t
+			}
+		}
+	}
+
+	if retry {
+		if t := now.Add(m.Schedule.RetryInterval); t.Before(next) {
+			next = t
+		}
+	}
+	return next
+}
+
+func (m *Mirror) logf(format string, args ...interface{}) {
+	if m.Logger != nil {
+		m.Logger.Printf(format, args...)
+	}
+}
@@ -0,0 +1,264 @@
+package mirror
+
+import (
+	"context"
+	"ebay-api-client/ebay"
+	"ebay-api-client/ebay/ebaytest"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+// newTestMirror creates a Mirror downloading from a fake Feed API server into a temporary directory
+func newTestMirror(t *testing.T, now time.Time, kinds ...Kind) (*Mirror, *ebaytest.FeedServer, func()) {
+	server := ebaytest.NewFeedServer()
+
+	service := ebay.NewSandboxFeedService(server.Client())
+	service.BaseURL = server.BaseURL()
+	service.ChunkSize = 10
+
+	dir, err := ioutil.TempDir("", "mirror")
+	assert.NilError(t, err)
+
+	m := New(service, &Config{
+		Dir:   dir,
+		Feeds: []FeedConfig{{MarketID: "EBAY_US", CategoryIDs: []string{"1"}, Kinds: kinds}},
+	})
+	m.Schedule.DailyCatchUp = 2
+	m.Schedule.SnapshotCatchUp = 3
+	m.now = func() time.Time { return now }
+
+	return m, server, func() {
+		server.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func Test_IsSyncDownloadingPublishedFeeds(t *testing.T) {
+	m, server, cleanup := newTestMirror(t, date(2020, 5, 6, 10))
+	defer cleanup()
+
+	server.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItem, Scope: "ALL_ACTIVE", LastModified: date(2020, 5, 5, 6), Data: []byte("bootstrap")})
+	server.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItem, Scope: "NEWLY_LISTED", Date: "20200504", Data: []byte("daily 4")})
+	server.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItem, Scope: "NEWLY_LISTED", Date: "20200505", Data: []byte("daily 5")})
+	server.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItemSnapshot, Date: "2020-05-06T06:00:00.000Z", Data: []byte("snapshot 6")})
+	server.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItemSnapshot, Date: "2020-05-06T08:00:00.000Z", Data: []byte("snapshot 8")})
+
+	assert.NilError(t, m.Sync(context.Background()))
+	assert.Assert(t, !m.pending)
+
+	for name, want := range map[string]string{
+		"bootstrap_20200505.tsv.gz":  "bootstrap",
+		"daily_20200504.tsv.gz":      "daily 4",
+		"daily_20200505.tsv.gz":      "daily 5",
+		"snapshot_2020050606.tsv.gz": "snapshot 6",
+		"snapshot_2020050608.tsv.gz": "snapshot 8",
+	} {
+		b, err := ioutil.ReadFile(filepath.Join(m.Config.Dir, "EBAY_US", "1", name))
+		assert.NilError(t, err, name)
+		assert.Equal(t, string(b), want)
+	}
+
+	// The missing 07:00 snapshot is skipped since a newer one is published
+	_, err := os.Stat(filepath.Join(m.Config.Dir, "EBAY_US", "1", "snapshot_2020050607.tsv.gz"))
+	assert.Assert(t, os.IsNotExist(err))
+
+	state, err := LoadState(m.Config.statePath())
+	assert.NilError(t, err)
+	assert.Equal(t, len(state.Feeds), 3)
+	assert.Equal(t, state.Last(Daily, "EBAY_US", "1").Slot, date(2020, 5, 5, 0))
+	assert.Equal(t, state.Last(Snapshot, "EBAY_US", "1").Slot, date(2020, 5, 6, 8))
+	assert.Equal(t, state.Last(Bootstrap, "EBAY_US", "1").Info.Size, int64(len("bootstrap")))
+
+	// Nothing new is published, nothing is downloaded
+	requests := len(server.Requests())
+	assert.NilError(t, m.Sync(context.Background()))
+	assert.Equal(t, len(server.Requests()), requests)
+}
+
+func Test_IsSyncCatchingUpAfterRestart(t *testing.T) {
+	m, server, cleanup := newTestMirror(t, date(2020, 5, 6, 10), Daily)
+	defer cleanup()
+
+	server.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItem, Scope: "NEWLY_LISTED", Data: []byte("daily")})
+	assert.NilError(t, m.Sync(context.Background()))
+
+	// A new mirror sharing the state file, three days later
+	restarted := New(m.Service, m.Config)
+	restarted.Schedule = m.Schedule
+	restarted.Schedule.DailyCatchUp = 14
+	restarted.now = func() time.Time { return date(2020, 5, 9, 10) }
+
+	assert.NilError(t, restarted.Sync(context.Background()))
+
+	for _, name := range []string{"daily_20200506.tsv.gz", "daily_20200507.tsv.gz", "daily_20200508.tsv.gz"} {
+		_, err := os.Stat(filepath.Join(m.Config.Dir, "EBAY_US", "1", name))
+		assert.NilError(t, err, name)
+	}
+
+	var dates []string
+	for _, r := range server.Requests() {
+		dates = append(dates, r.URL.Query().Get("date"))
+	}
+	assert.Equal(t, strings.Join(dates, ","), "20200504,20200505,20200506,20200507,20200508")
+}
+
+func Test_IsSyncWaitingForUnpublishedFeeds(t *testing.T) {
+	m, server, cleanup := newTestMirror(t, date(2020, 5, 6, 10), Bootstrap, Daily)
+	defer cleanup()
+
+	bootstrap := &ebaytest.Feed{Path: ebaytest.PathItem, Scope: "ALL_ACTIVE", LastModified: date(2020, 4, 28, 6), Data: []byte("bootstrap")}
+	server.AddFeed(bootstrap)
+	server.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItem, Scope: "NEWLY_LISTED", Date: "20200504", Data: []byte("daily 4")})
+
+	state := &State{Feeds: map[string]*FeedState{}}
+	last := &FeedState{Kind: Bootstrap, MarketID: "EBAY_US", CategoryID: "1", Slot: date(2020, 4, 28, 0), Info: ebay.FeedInfo{LastModified: date(2020, 4, 28, 6)}}
+	state.Feeds[last.Key()] = last
+	assert.NilError(t, state.Save(m.Config.statePath()))
+
+	assert.NilError(t, m.Sync(context.Background()))
+	assert.Assert(t, m.pending)
+
+	// Neither the old bootstrap nor the missing daily are recorded
+	state, err := LoadState(m.Config.statePath())
+	assert.NilError(t, err)
+	assert.Equal(t, state.Last(Bootstrap, "EBAY_US", "1").Slot, date(2020, 4, 28, 0))
+	assert.Equal(t, state.Last(Daily, "EBAY_US", "1").Slot, date(2020, 5, 4, 0))
+
+	_, err = os.Stat(filepath.Join(m.Config.Dir, "EBAY_US", "1", "bootstrap_20200505.tsv.gz"))
+	assert.Assert(t, os.IsNotExist(err))
+
+	assert.Equal(t, m.nextSync(true), date(2020, 5, 6, 10).Add(m.Schedule.RetryInterval))
+	assert.Equal(t, m.nextSync(false), date(2020, 5, 7, 9))
+
+	bootstrap.LastModified = date(2020, 5, 5, 6)
+	assert.NilError(t, m.Sync(context.Background()))
+
+	state, err = LoadState(m.Config.statePath())
+	assert.NilError(t, err)
+	assert.Equal(t, state.Last(Bootstrap, "EBAY_US", "1").Slot, date(2020, 5, 5, 0))
+}
+
+func Test_IsFailedDownloadNotVisible(t *testing.T) {
+	m, server, cleanup := newTestMirror(t, date(2020, 5, 6, 10), Bootstrap)
+	defer cleanup()
+
+	server.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItem, Scope: "ALL_ACTIVE", Data: []byte("a bootstrap feed bigger than a chunk")})
+	server.InjectFault(ebaytest.ServerError(1, http.StatusInternalServerError))
+	server.InjectFault(ebaytest.Truncated(1, 5))
+
+	// The first Sync gets a server error, the second one a truncated chunk
+	requests := 0
+	for i := 0; i < 2; i++ {
+		assert.Assert(t, m.Sync(context.Background()) != nil)
+		assert.Assert(t, len(server.Requests()) > requests)
+		requests = len(server.Requests())
+	}
+
+	entries, err := ioutil.ReadDir(filepath.Join(m.Config.Dir, "EBAY_US", "1"))
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 0)
+
+	state, err := LoadState(m.Config.statePath())
+	assert.NilError(t, err)
+	assert.Assert(t, state.Last(Bootstrap, "EBAY_US", "1") == nil)
+
+	assert.NilError(t, m.Sync(context.Background()))
+	b, err := ioutil.ReadFile(filepath.Join(m.Config.Dir, "EBAY_US", "1", "bootstrap_20200505.tsv.gz"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "a bootstrap feed bigger than a chunk")
+}
+
+func Test_IsRunStoppingWithContext(t *testing.T) {
+	m, server, cleanup := newTestMirror(t, date(2020, 5, 6, 10), Bootstrap)
+	defer cleanup()
+
+	server.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItem, Scope: "ALL_ACTIVE", Data: []byte("bootstrap")})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, m.Run(ctx), context.DeadlineExceeded)
+
+	_, err := os.Stat(filepath.Join(m.Config.Dir, "EBAY_US", "1", "bootstrap_20200505.tsv.gz"))
+	assert.NilError(t, err)
+}
+
+func Test_IsSyncLoggingStateError(t *testing.T) {
+	m, _, cleanup := newTestMirror(t, date(2020, 5, 6, 10), Bootstrap)
+	defer cleanup()
+
+	logs := new(strings.Builder)
+	m.Logger = log.New(logs, "", 0)
+	assert.NilError(t, ioutil.WriteFile(m.Config.statePath(), []byte("not json"), 0644))
+
+	err := m.Sync(context.Background())
+	assert.Assert(t, err != nil)
+	assert.Assert(t, strings.HasPrefix(logs.String(), "cannot synchronize: "), logs.String())
+}
+
+func Test_IsRunStoppingIfConfigInvalid(t *testing.T) {
+	m, _, cleanup := newTestMirror(t, date(2020, 5, 6, 10), Bootstrap)
+	defer cleanup()
+
+	m.Config.Feeds[0].MarketID = ""
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := m.Run(ctx)
+	assert.Assert(t, err != nil && err != context.DeadlineExceeded, "%v", err)
+}
+
+func Test_IsSyncCancellingDownload(t *testing.T) {
+	m, server, cleanup := newTestMirror(t, date(2020, 5, 6, 10), Bootstrap)
+	defer cleanup()
+
+	server.AddFeed(&ebaytest.Feed{Path: ebaytest.PathItem, Scope: "ALL_ACTIVE", Data: []byte("bootstrap")})
+	server.InjectFault(ebaytest.Slow(0, time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := m.Sync(ctx)
+	assert.ErrorContains(t, err, "context deadline exceeded")
+	assert.Assert(t, time.Since(start) < 5*time.Second, "Sync returned after %v", time.Since(start))
+
+	entries, err := ioutil.ReadDir(filepath.Join(m.Config.Dir, "EBAY_US", "1"))
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 0)
+}
+
+func Test_IsConfigLoadedAndValidated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mirror")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "mirror.yaml")
+	assert.NilError(t, ioutil.WriteFile(path, []byte("dir: /data/feeds\nfeeds:\n  - market_id: EBAY_US\n    category_ids: [\"1\", \"220\"]\n    kinds: [daily]\n"), 0644))
+
+	c, err := LoadConfig(path)
+	assert.NilError(t, err)
+	assert.Equal(t, c.Dir, "/data/feeds")
+	assert.Equal(t, c.statePath(), filepath.Join("/data/feeds", DefaultStateFile))
+	assert.DeepEqual(t, c.Feeds[0].CategoryIDs, []string{"1", "220"})
+	assert.DeepEqual(t, c.Feeds[0].kinds(), []Kind{Daily})
+
+	for _, c := range []*Config{
+		{Feeds: []FeedConfig{{MarketID: "EBAY_US", CategoryIDs: []string{"1"}}}},
+		{Dir: dir},
+		{Dir: dir, Feeds: []FeedConfig{{CategoryIDs: []string{"1"}}}},
+		{Dir: dir, Feeds: []FeedConfig{{MarketID: "EBAY_US"}}},
+		{Dir: dir, Feeds: []FeedConfig{{MarketID: "EBAY_US", CategoryIDs: []string{"1"}, Kinds: []Kind{"weekly"}}}},
+	} {
+		assert.Assert(t, c.Validate() != nil)
+	}
+}
@@ -0,0 +1,125 @@
+package mirror
+
+import (
+	"time"
+)
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+// Kind is the kind of a mirrored feed
+type Kind string
+
+const (
+	// Bootstrap is the weekly item bootstrap feed, only the latest one is available
+	Bootstrap Kind = "bootstrap"
+	// Daily is the daily newly listed items feed
+	Daily Kind = "daily"
+	// Snapshot is the hourly item snapshot feed
+	Snapshot Kind = "snapshot"
+)
+
+// Kinds are all the feed kinds, in the order they are synchronized
+var Kinds = []Kind{Bootstrap, Daily, Snapshot}
+
+func (k Kind) valid() bool {
+	for _, kind := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Schedule describes when eBay publishes the feeds and how far back the missed ones are caught up.
+// All the times are UTC.
+type Schedule struct {
+	// BootstrapDay is the week day the weekly bootstrap feed is published
+	BootstrapDay time.Weekday
+	// BootstrapDelay is the time after midnight of BootstrapDay when the bootstrap feed is available
+	BootstrapDelay time.Duration
+	// DailyDelay is the time after the end of the day when its newly listed items feed is available
+	DailyDelay time.Duration
+	// SnapshotDelay is the time after the end of the hour when its snapshot feed is available
+	SnapshotDelay time.Duration
+	// DailyCatchUp is the number of past daily feeds downloaded when they were missed
+	DailyCatchUp int
+	// SnapshotCatchUp is the number of past hourly snapshots downloaded when they were missed
+	SnapshotCatchUp int
+	// RetryInterval is the time waited before retrying the feeds which failed or were not yet published
+	RetryInterval time.Duration
+}
+
+// DefaultSchedule is the schedule of eBay feeds publication. The catch up windows match
+// the feeds retention: two weeks of daily feeds and one week of snapshots.
+var DefaultSchedule = Schedule{
+	BootstrapDay:    time.Tuesday,
+	BootstrapDelay:  9 * time.Hour,
+	DailyDelay:      9 * time.Hour,
+	SnapshotDelay:   30 * time.Minute,
+	DailyCatchUp:    14,
+	SnapshotCatchUp: 7 * 24,
+	RetryInterval:   15 * time.Minute,
+}
+
+// latest returns the slot of the most recent feed of the given kind available at now.
+// The slot is the day of the bootstrap and daily feeds and the hour of the snapshots.
+func (s Schedule) latest(kind Kind, now time.Time) time.Time {
+	now = now.UTC()
+
+	switch kind {
+	case Bootstrap:
+		slot := now.Truncate(day)
+		for slot.Weekday() != s.BootstrapDay || slot.Add(s.BootstrapDelay).After(now) {
+			slot = slot.Add(-day)
+		}
+		return slot
+	case Daily:
+		return now.Add(-s.DailyDelay - day).Truncate(day)
+	}
+	return now.Add(-s.SnapshotDelay - time.Hour).Truncate(time.Hour)
+}
+
+// slots returns the slots of the feeds of the given kind to download at now, oldest first, given the last downloaded one.
+// The bootstrap feed is never caught up since eBay only serves the latest one.
+func (s Schedule) slots(kind Kind, last, now time.Time) []time.Time {
+	latest := s.latest(kind, now)
+
+	var (
+		step  time.Duration
+		start time.Time
+	)
+	switch kind {
+	case Bootstrap:
+		step, start = week, latest
+	case Daily:
+		step, start = day, latest.Add(-time.Duration(s.DailyCatchUp-1)*day)
+	default:
+		step, start = time.Hour, latest.Add(-time.Duration(s.SnapshotCatchUp-1)*time.Hour)
+	}
+
+	if !last.IsZero() && !last.Add(step).Before(start) {
+		start = last.Add(step)
+	}
+
+	var slots []time.Time
+	for slot := start; !slot.After(latest); slot = slot.Add(step) {
+		slots = append(slots, slot)
+	}
+	return slots
+}
+
+// next returns when the feed following the latest one available at now is published
+func (s Schedule) next(kind Kind, now time.Time) time.Time {
+	latest := s.latest(kind, now)
+
+	switch kind {
+	case Bootstrap:
+		return latest.Add(week + s.BootstrapDelay)
+	case Daily:
+		return latest.Add(2*day + s.DailyDelay)
+	}
+	return latest.Add(2*time.Hour + s.SnapshotDelay)
+}
@@ -0,0 +1,63 @@
+package mirror
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func date(year int, month time.Month, day, hour int) time.Time {
+	return time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+}
+
+func Test_IsLatestSlotMatchingPublicationTimes(t *testing.T) {
+	s := DefaultSchedule
+
+	tests := []struct {
+		name string
+		kind Kind
+		now  time.Time
+		want time.Time
+	}{
+		{name: "Is bootstrap of the week available on Wednesday?", kind: Bootstrap, now: date(2020, 5, 6, 10), want: date(2020, 5, 5, 0)},
+		{name: "Is bootstrap of the past week used before publication?", kind: Bootstrap, now: date(2020, 5, 5, 8), want: date(2020, 4, 28, 0)},
+		{name: "Is bootstrap available on Tuesday after the delay?", kind: Bootstrap, now: date(2020, 5, 5, 9), want: date(2020, 5, 5, 0)},
+		{name: "Is yesterday daily available after the delay?", kind: Daily, now: date(2020, 5, 6, 10), want: date(2020, 5, 5, 0)},
+		{name: "Is yesterday daily not available before the delay?", kind: Daily, now: date(2020, 5, 6, 8), want: date(2020, 5, 4, 0)},
+		{name: "Is previous hour snapshot available after the delay?", kind: Snapshot, now: date(2020, 5, 6, 10).Add(31 * time.Minute), want: date(2020, 5, 6, 9)},
+		{name: "Is previous hour snapshot not available before the delay?", kind: Snapshot, now: date(2020, 5, 6, 10).Add(29 * time.Minute), want: date(2020, 5, 6, 8)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, s.latest(tt.kind, tt.now), tt.want)
+		})
+	}
+}
+
+func Test_IsSlotsCatchingUpMissedFeeds(t *testing.T) {
+	s := DefaultSchedule
+	s.DailyCatchUp = 3
+	s.SnapshotCatchUp = 4
+	now := date(2020, 5, 6, 10)
+
+	assert.DeepEqual(t, s.slots(Daily, time.Time{}, now), []time.Time{date(2020, 5, 3, 0), date(2020, 5, 4, 0), date(2020, 5, 5, 0)})
+	assert.DeepEqual(t, s.slots(Daily, date(2020, 5, 3, 0), now), []time.Time{date(2020, 5, 4, 0), date(2020, 5, 5, 0)})
+	assert.DeepEqual(t, s.slots(Daily, date(2020, 4, 1, 0), now), []time.Time{date(2020, 5, 3, 0), date(2020, 5, 4, 0), date(2020, 5, 5, 0)})
+	assert.Equal(t, len(s.slots(Daily, date(2020, 5, 5, 0), now)), 0)
+
+	assert.DeepEqual(t, s.slots(Snapshot, date(2020, 5, 6, 7), now), []time.Time{date(2020, 5, 6, 8)})
+	assert.Equal(t, len(s.slots(Snapshot, time.Time{}, now)), 4)
+
+	assert.DeepEqual(t, s.slots(Bootstrap, date(2020, 4, 21, 0), now), []time.Time{date(2020, 5, 5, 0)})
+	assert.Equal(t, len(s.slots(Bootstrap, date(2020, 5, 5, 0), now)), 0)
+}
+
+func Test_IsNextSlotFollowingLatest(t *testing.T) {
+	s := DefaultSchedule
+	now := date(2020, 5, 6, 10)
+
+	assert.Equal(t, s.next(Bootstrap, now), date(2020, 5, 12, 9))
+	assert.Equal(t, s.next(Daily, now), date(2020, 5, 7, 9))
+	assert.Equal(t, s.next(Snapshot, now), date(2020, 5, 6, 10).Add(30*time.Minute))
+}
@@ -0,0 +1,123 @@
+package mirror
+
+import (
+	"ebay-api-client/ebay"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// State records the last feed successfully downloaded for each mirrored feed
+type State struct {
+	// Feeds are the feed states by feed key (see FeedState.Key)
+	Feeds map[string]*FeedState `json:"feeds"`
+}
+
+// FeedState is the last feed successfully downloaded for a kind, marketplace and category
+type FeedState struct {
+	Kind       Kind   `json:"kind"`
+	MarketID   string `json:"market_id"`
+	CategoryID string `json:"category_id"`
+	// Slot is the day (bootstrap and daily) or hour (snapshot) of the feed
+	Slot time.Time `json:"slot"`
+	// File is the path of the downloaded feed
+	File string `json:"file"`
+	// Info is the information about the feed returned by the Feed API
+	Info         ebay.FeedInfo `json:"info"`
+	DownloadedAt time.Time     `json:"downloaded_at"`
+}
+
+// feedKey returns the key identifying a mirrored feed in the State
+func feedKey(kind Kind, marketID, categoryID string) string {
+	return fmt.Sprintf("%v/%v/%v", kind, marketID, categoryID)
+}
+
+// Key returns the key identifying the feed in the State
+func (f *FeedState) Key() string {
+	return feedKey(f.Kind, f.MarketID, f.CategoryID)
+}
+
+// LoadState reads the state from the given file. An empty state is returned if the file does not exist.
+func LoadState(path string) (*State, error) {
+	s := &State{Feeds: make(map[string]*FeedState)}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("LoadState(): cannot read state: %v", err)
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("LoadState(): cannot decode state: %v", err)
+	}
+	if s.Feeds == nil {
+		s.Feeds = make(map[string]*FeedState)
+	}
+	return s, nil
+}
+
+// Save writes the state into the given file. The file is replaced atomically.
+func (s *State) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Save(): cannot encode state: %v", err)
+	}
+
+	err = writeFileAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(append(data, '\n'))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Save(): cannot write state: %v", err)
+	}
+	return nil
+}
+
+// Last returns the state of the given feed, nil if it was never downloaded
+func (s *State) Last(kind Kind, marketID, categoryID string) *FeedState {
+	return s.Feeds[feedKey(kind, marketID, categoryID)]
+}
+
+// writeFileAtomic writes a temporary file in the destination directory with the given function
+// and renames it to path only if the function succeeds, so that readers never see a partial file.
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("writeFileAtomic(): cannot create %v: %v", dir, err)
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("writeFileAtomic(): cannot create temporary file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writeFileAtomic(): cannot sync %v: %v", tmp.Name(), err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writeFileAtomic(): cannot write %v: %v", tmp.Name(), err)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("writeFileAtomic(): cannot set permissions of %v: %v", tmp.Name(), err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writeFileAtomic(): cannot rename %v: %v", tmp.Name(), err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package mirror
+
+import (
+	"ebay-api-client/ebay"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func Test_IsStateSavedAndLoaded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mirror")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "state", DefaultStateFile)
+
+	s, err := LoadState(path)
+	assert.NilError(t, err)
+	assert.Equal(t, len(s.Feeds), 0)
+	assert.Assert(t, s.Last(Daily, "EBAY_US", "1") == nil)
+
+	fs := &FeedState{
+		Kind:       Daily,
+		MarketID:   "EBAY_US",
+		CategoryID: "1",
+		Slot:       date(2020, 5, 5, 0),
+		File:       "daily_20200505.tsv.gz",
+		Info:       ebay.FeedInfo{Type: "item", Size: 42, LastModified: date(2020, 5, 6, 7)},
+	}
+	s.Feeds[fs.Key()] = fs
+	assert.NilError(t, s.Save(path))
+
+	s, err = LoadState(path)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, s.Last(Daily, "EBAY_US", "1"), fs)
+
+	// Only the state file is left, the temporary file is renamed
+	entries, err := ioutil.ReadDir(filepath.Dir(path))
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 1)
+
+	assert.NilError(t, ioutil.WriteFile(path, []byte("{"), 0644))
+	_, err = LoadState(path)
+	assert.ErrorContains(t, err, "cannot decode state")
+}
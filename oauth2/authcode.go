@@ -0,0 +1,353 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	// refreshTokenExpiresIn is the token response field reporting the refresh token lifetime in seconds
+	refreshTokenExpiresIn string = "refresh_token_expires_in"
+	// refreshTokenIssuedAt is the extra token field holding the time the token was requested, in unix seconds
+	refreshTokenIssuedAt string = "refresh_token_issued_at"
+)
+
+// AuthCodeConfig describes an eBay application using the oAuth2 authorization code grant flow
+// to obtain User Access Tokens, which are required by the APIs acting on behalf of an eBay user.
+// Check here for more details https://developer.ebay.com/api-docs/static/oauth-authorization-code-grant.html
+type AuthCodeConfig struct {
+	// ClientID is the eBay application client id
+	ClientID string
+	// ClientSecret is the eBay application client secret
+	ClientSecret string
+	// RuName is the eBay Redirect URL name of the application, used as redirect_uri
+	RuName string
+	// Scopes are the scopes the user is asked to consent to
+	Scopes []string
+	// Endpoint is the eBay oAuth2 endpoint (SandBoxEndpoint or ProdEndpoint)
+	Endpoint oauth2.Endpoint
+
+	// now is the clock stamping the issue time of the refresh tokens, time.Now when nil
+	now func() time.Time
+}
+
+// NewSandboxAuthCodeConfig creates a new AuthCodeConfig pointing to eBay sandbox environment
+func NewSandboxAuthCodeConfig(clientID, clientSecret, ruName string, scopes []string) *AuthCodeConfig {
+	return &AuthCodeConfig{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RuName:       ruName,
+		Scopes:       scopes,
+		Endpoint:     SandBoxEndpoint,
+		now:          time.Now,
+	}
+}
+
+// NewProdAuthCodeConfig creates a new AuthCodeConfig pointing to eBay production environment
+func NewProdAuthCodeConfig(clientID, clientSecret, ruName string, scopes []string) *AuthCodeConfig {
+	return &AuthCodeConfig{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RuName:       ruName,
+		Scopes:       scopes,
+		Endpoint:     ProdEndpoint,
+		now:          time.Now,
+	}
+}
+
+// oauth2Config returns the equivalent golang.org/x/oauth2 configuration
+func (c *AuthCodeConfig) oauth2Config() *oauth2.Config {
+	endpoint := c.Endpoint
+	endpoint.AuthStyle = oauth2.AuthStyleInHeader
+
+	return &oauth2.Config{
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		RedirectURL:  c.RuName,
+		Scopes:       c.Scopes,
+		Endpoint:     endpoint,
+	}
+}
+
+// timeNow returns the current time of the configuration clock
+func (c *AuthCodeConfig) timeNow() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+	return c.now()
+}
+
+// ConsentURL returns the URL of eBay consent page the user has to visit to grant the application access.
+// The state is returned untouched in the redirect and has to be checked to prevent CSRF attacks.
+func (c *AuthCodeConfig) ConsentURL(state string) string {
+	return c.oauth2Config().AuthCodeURL(state)
+}
+
+// Exchange exchanges the authorization code received in the consent redirect for a User Access Token.
+// The returned token contains the refresh token which can be used later on to mint new access tokens.
+func (c *AuthCodeConfig) Exchange(ctx context.Context, code string) (*oauth2.Token, error) {
+	issued := c.timeNow()
+	tk, err := c.oauth2Config().Exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	// Forcing the TokenType to bearer, eBay returns "User Access Token"
+	tk.TokenType = "bearer"
+	return withRefreshTokenLifetime(tk, issued.Unix(), extraInt(tk, refreshTokenExpiresIn)), nil
+}
+
+// Refresh mints a new User Access Token from the given refresh token.
+func (c *AuthCodeConfig) Refresh(ctx context.Context, refreshToken string) (*oauth2.Token, error) {
+	return (&refreshTokenSource{ctx: ctx, conf: c, refreshToken: refreshToken}).Token()
+}
+
+// TokenSource returns a TokenSource which returns the given token until it expires, then refreshes it
+// through its refresh token.
+func (c *AuthCodeConfig) TokenSource(ctx context.Context, tk *oauth2.Token) oauth2.TokenSource {
+	refresher := &refreshTokenSource{
+		ctx:          ctx,
+		conf:         c,
+		refreshToken: tk.RefreshToken,
+		issuedAt:     extraInt(tk, refreshTokenIssuedAt),
+		expiresIn:    extraInt(tk, refreshTokenExpiresIn),
+	}
+	return &tokenSource{
+		ctx:  ctx,
+		orig: oauth2.ReuseTokenSource(tk, refresher),
+		renew: func() oauth2.TokenSource {
+			return oauth2.ReuseTokenSource(nil, refresher)
+		},
+	}
+}
+
+// Client creates a new HTTP client authorized with the given User Access Token, which is refreshed when expired.
+func (c *AuthCodeConfig) Client(ctx context.Context, tk *oauth2.Token) *http.Client {
+	return &http.Client{
+		Transport: &Transport{
+			Base:   contextClient(ctx).Transport,
+			Source: c.TokenSource(ctx, tk),
+			Scopes: append([]string{}, c.Scopes...),
+		},
+	}
+}
+
+// refreshTokenSource mints new access tokens using the refresh token grant.
+// It is not relying on golang.org/x/oauth2 refresh as eBay requires the scopes to be sent in the refresh request.
+// It is shared by the token sources renewed after an invalidation, so the refresh token is guarded by mu.
+type refreshTokenSource struct {
+	ctx  context.Context
+	conf *AuthCodeConfig
+
+	mu           sync.Mutex
+	refreshToken string
+	// issuedAt (unix seconds) and expiresIn (seconds) are the lifetime of the refresh token, 0 if unknown
+	issuedAt  int64
+	expiresIn int64
+}
+
+func (r *refreshTokenSource) Token() (*oauth2.Token, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.refreshToken == "" {
+		return nil, fmt.Errorf("Token(): token expired and refresh token is not set")
+	}
+
+	conf := &clientcredentials.Config{
+		ClientID:     r.conf.ClientID,
+		ClientSecret: r.conf.ClientSecret,
+		TokenURL:     r.conf.Endpoint.TokenURL,
+		Scopes:       r.conf.Scopes,
+		AuthStyle:    oauth2.AuthStyleInHeader,
+		EndpointParams: url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {r.refreshToken},
+		},
+	}
+
+	issued := r.conf.timeNow()
+	tk, err := conf.Token(r.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// eBay does not return the refresh token when refreshing, the current one is still valid and keeps its lifetime
+	if tk.RefreshToken != "" && tk.RefreshToken != r.refreshToken {
+		r.refreshToken = tk.RefreshToken
+		r.issuedAt, r.expiresIn = issued.Unix(), extraInt(tk, refreshTokenExpiresIn)
+	}
+	tk.RefreshToken = r.refreshToken
+
+	tk.TokenType = "bearer"
+	return withRefreshTokenLifetime(tk, r.issuedAt, r.expiresIn), nil
+}
+
+// withRefreshTokenLifetime returns the token with the issue time and the lifetime of its refresh token in its extra
+// fields, so that the refresh token expiry does not depend on when it is computed. The fields not known are omitted.
+func withRefreshTokenLifetime(tk *oauth2.Token, issuedAt, expiresIn int64) *oauth2.Token {
+	extra := map[string]interface{}{}
+	if issuedAt > 0 {
+		extra[refreshTokenIssuedAt] = issuedAt
+	}
+	if expiresIn > 0 {
+		extra[refreshTokenExpiresIn] = expiresIn
+	}
+	return tk.WithExtra(extra)
+}
+
+// RefreshTokenExpiry returns the expiration time of the refresh token issued together with the given token,
+// counted from the time the refresh token was requested by Exchange or by a refresh returning a new one.
+// The zero time is returned if the lifetime was not reported or the token was not obtained from them.
+func RefreshTokenExpiry(tk *oauth2.Token) time.Time {
+	seconds := extraInt(tk, refreshTokenExpiresIn)
+	issued := extraInt(tk, refreshTokenIssuedAt)
+	if seconds <= 0 || issued <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(issued, 0).Add(time.Duration(seconds) * time.Second)
+}
+
+// extraInt returns the integer extra field of the token, 0 if it is missing
+func extraInt(tk *oauth2.Token, key string) int64 {
+	switch v := tk.Extra(key).(type) {
+	case float64:
+		return int64(v)
+	case int64:
+		return v
+	}
+	return 0
+}
+
+// LoopbackRedirect is a local HTTP server capturing the authorization code returned by eBay consent page.
+// It is meant for command line logins: the application RuName has to be configured in the eBay developer
+// portal with the loopback URL as "auth accepted" URL.
+type LoopbackRedirect struct {
+	listener net.Listener
+	server   *http.Server
+	state    string
+	path     string
+	result   chan loopbackResult
+}
+
+type loopbackResult struct {
+	code string
+	err  error
+}
+
+// NewLoopbackRedirect starts listening on the given address (i.e. "127.0.0.1:8080") for the consent redirect
+// on the given path. The redirect is accepted only if it carries the given state.
+func NewLoopbackRedirect(addr, path, state string) (*LoopbackRedirect, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("NewLoopbackRedirect(): cannot listen on %v: %v", addr, err)
+	}
+
+	l := &LoopbackRedirect{
+		listener: listener,
+		state:    state,
+		path:     path,
+		result:   make(chan loopbackResult, 1),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, l.handle)
+	l.server = &http.Server{Handler: mux}
+
+	go l.server.Serve(listener)
+
+	return l, nil
+}
+
+// URL returns the URL the redirect is listening on
+func (l *LoopbackRedirect) URL() string {
+	return "http://" + l.listener.Addr().String() + l.path
+}
+
+func (l *LoopbackRedirect) handle(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var result loopbackResult
+	switch {
+	case q.Get("error") != "":
+		result.err = fmt.Errorf("handle(): consent not granted: %v %v", q.Get("error"), q.Get("error_description"))
+	case q.Get("state") != l.state:
+		result.err = fmt.Errorf("handle(): state mismatch: %v", q.Get("state"))
+	case q.Get("code") == "":
+		result.err = fmt.Errorf("handle(): authorization code is missing")
+	default:
+		result.code = q.Get("code")
+	}
+
+	if result.err != nil {
+		http.Error(w, result.err.Error(), http.StatusBadRequest)
+	} else {
+		fmt.Fprintln(w, "Authorization completed, you can close this window.")
+	}
+
+	select {
+	case l.result <- result:
+	default:
+	}
+}
+
+// Wait blocks until the consent redirect is received or the context is done, then returns the authorization code.
+func (l *LoopbackRedirect) Wait(ctx context.Context) (string, error) {
+	select {
+	case r := <-l.result:
+		return r.code, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Close stops the redirect server
+func (l *LoopbackRedirect) Close() error {
+	return l.server.Close()
+}
+
+// LoginWithLoopback runs the whole authorization code flow for command line tools: it starts a LoopbackRedirect
+// on the given address and path, hands the consent URL to open (i.e. to print it or launch a browser),
+// waits for the user consent and exchanges the authorization code for a User Access Token.
+func (c *AuthCodeConfig) LoginWithLoopback(ctx context.Context, addr, path string, open func(consentURL string) error) (*oauth2.Token, error) {
+	state, err := newState()
+	if err != nil {
+		return nil, err
+	}
+
+	redirect, err := NewLoopbackRedirect(addr, path, state)
+	if err != nil {
+		return nil, err
+	}
+	defer redirect.Close()
+
+	if err := open(c.ConsentURL(state)); err != nil {
+		return nil, err
+	}
+
+	code, err := redirect.Wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Exchange(ctx, code)
+}
+
+// newState generates a random state value for the consent request
+func newState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("newState(): %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
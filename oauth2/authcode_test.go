@@ -0,0 +1,260 @@
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	"gotest.tools/assert"
+)
+
+const (
+	testRuName       = "My_App-MyApp-SBX-abcdef-ghijkl"
+	testCode         = "v^1.1#i^1#f^0#r^1#I^3#p^3#t^Ul41XzA6MkE5"
+	testRefreshToken = "v^1.1#i^1#r^1#f^0#I^3#p^3#t^Ul4xMF8yOkQ"
+)
+
+// testIssuedAt is the time of the clock of the test configurations
+var testIssuedAt = time.Date(2020, 5, 4, 7, 0, 0, 0, time.UTC)
+
+// newTestUserTokenServer is a stand-in for eBay oAuth2 token endpoint supporting the authorization code
+// and refresh token grants. Each request is appended to the given list of forms.
+func newTestUserTokenServer(t *testing.T, forms *[]url.Values) *httptest.Server {
+	apiHandler := http.NewServeMux()
+	apiHandler.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		assert.Assert(t, ok)
+		assert.Equal(t, id, "EBAY_API_CLIENT_ID")
+		assert.Equal(t, secret, "EBAY_API_CLIENT_SECRET")
+
+		assert.NilError(t, r.ParseForm())
+		*forms = append(*forms, r.PostForm)
+
+		w.Header().Add("Content-Type", "application/json")
+		switch r.PostForm.Get("grant_type") {
+		case "authorization_code":
+			fmt.Fprintf(w, `{"access_token": "ACCESS-1", "expires_in": 7200, "refresh_token": %q, "refresh_token_expires_in": 47304000, "token_type": "User Access Token"}`, testRefreshToken)
+		case "refresh_token":
+			fmt.Fprint(w, `{"access_token": "ACCESS-2", "expires_in": 7200, "token_type": "User Access Token"}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": "unsupported_grant_type"}`)
+		}
+	})
+
+	return httptest.NewServer(apiHandler)
+}
+
+func newTestAuthCodeConfig(server *httptest.Server) *AuthCodeConfig {
+	return &AuthCodeConfig{
+		ClientID:     "EBAY_API_CLIENT_ID",
+		ClientSecret: "EBAY_API_CLIENT_SECRET",
+		RuName:       testRuName,
+		Scopes:       []string{"https://api.ebay.com/oauth/api_scope", "https://api.ebay.com/oauth/api_scope/sell.inventory"},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  SandBoxEndpoint.AuthURL,
+			TokenURL: server.URL + "/token",
+		},
+		now: func() time.Time { return testIssuedAt },
+	}
+}
+
+func TestIsConsentURLCreated(t *testing.T) {
+	c := NewSandboxAuthCodeConfig("EBAY_API_CLIENT_ID", "EBAY_API_CLIENT_SECRET", testRuName, []string{"https://api.ebay.com/oauth/api_scope"})
+
+	u, err := url.Parse(c.ConsentURL("STATE"))
+	assert.NilError(t, err)
+
+	assert.Equal(t, u.Scheme+"://"+u.Host+u.Path, SandBoxEndpoint.AuthURL)
+	assert.Equal(t, u.Query().Get("client_id"), "EBAY_API_CLIENT_ID")
+	assert.Equal(t, u.Query().Get("redirect_uri"), testRuName)
+	assert.Equal(t, u.Query().Get("response_type"), "code")
+	assert.Equal(t, u.Query().Get("scope"), "https://api.ebay.com/oauth/api_scope")
+	assert.Equal(t, u.Query().Get("state"), "STATE")
+
+	p := NewProdAuthCodeConfig("EBAY_API_CLIENT_ID", "EBAY_API_CLIENT_SECRET", testRuName, nil)
+	assert.Assert(t, strings.HasPrefix(p.ConsentURL("STATE"), ProdEndpoint.AuthURL))
+}
+
+func TestIsExchangeReturningBearerToken(t *testing.T) {
+	var forms []url.Values
+	server := newTestUserTokenServer(t, &forms)
+	defer server.Close()
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, server.Client())
+
+	tk, err := newTestAuthCodeConfig(server).Exchange(ctx, testCode)
+	assert.NilError(t, err)
+
+	assert.Equal(t, tk.AccessToken, "ACCESS-1")
+	assert.Equal(t, tk.RefreshToken, testRefreshToken)
+	assert.Equal(t, tk.TokenType, "bearer")
+	// The expiry is counted from the exchange, not from the call
+	assert.Assert(t, RefreshTokenExpiry(tk).Equal(testIssuedAt.Add(47304000*time.Second)))
+	assert.Assert(t, RefreshTokenExpiry(&oauth2.Token{AccessToken: "ACCESS"}).IsZero())
+
+	assert.Equal(t, len(forms), 1)
+	assert.Equal(t, forms[0].Get("code"), testCode)
+	assert.Equal(t, forms[0].Get("redirect_uri"), testRuName)
+}
+
+func TestIsRefreshSendingScopesAndKeepingRefreshToken(t *testing.T) {
+	var forms []url.Values
+	server := newTestUserTokenServer(t, &forms)
+	defer server.Close()
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, server.Client())
+	c := newTestAuthCodeConfig(server)
+
+	tk, err := c.Refresh(ctx, testRefreshToken)
+	assert.NilError(t, err)
+
+	assert.Equal(t, tk.AccessToken, "ACCESS-2")
+	assert.Equal(t, tk.RefreshToken, testRefreshToken)
+	assert.Equal(t, tk.TokenType, "bearer")
+	assert.Assert(t, RefreshTokenExpiry(tk).IsZero())
+
+	assert.Equal(t, len(forms), 1)
+	assert.Equal(t, forms[0].Get("grant_type"), "refresh_token")
+	assert.Equal(t, forms[0].Get("refresh_token"), testRefreshToken)
+	assert.Equal(t, forms[0].Get("scope"), strings.Join(c.Scopes, " "))
+
+	_, err = c.Refresh(ctx, "")
+	assert.Error(t, err, "Token(): token expired and refresh token is not set")
+}
+
+func TestIsTokenSourceRefreshingExpiredToken(t *testing.T) {
+	var forms []url.Values
+	server := newTestUserTokenServer(t, &forms)
+	defer server.Close()
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, server.Client())
+	expired := &oauth2.Token{
+		AccessToken:  "ACCESS-0",
+		TokenType:    "User Access Token",
+		RefreshToken: testRefreshToken,
+		Expiry:       time.Now().Add(-time.Minute),
+	}
+
+	tk, err := newTestAuthCodeConfig(server).TokenSource(ctx, expired).Token()
+	assert.NilError(t, err)
+	assert.Equal(t, tk.AccessToken, "ACCESS-2")
+	assert.Equal(t, tk.TokenType, "bearer")
+}
+
+func TestIsRefreshKeepingRefreshTokenExpiry(t *testing.T) {
+	var forms []url.Values
+	server := newTestUserTokenServer(t, &forms)
+	defer server.Close()
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, server.Client())
+	c := newTestAuthCodeConfig(server)
+
+	tk, err := c.Exchange(ctx, testCode)
+	assert.NilError(t, err)
+	expiry := RefreshTokenExpiry(tk)
+
+	// The refresh response does not report the lifetime, the one of the exchanged refresh token is kept
+	c.now = func() time.Time { return testIssuedAt.Add(time.Hour) }
+	tk.Expiry = time.Now().Add(-time.Minute)
+	refreshed, err := c.TokenSource(ctx, tk).Token()
+	assert.NilError(t, err)
+	assert.Equal(t, refreshed.AccessToken, "ACCESS-2")
+	assert.Assert(t, RefreshTokenExpiry(refreshed).Equal(expiry))
+}
+
+func TestIsRefreshTokenSourceSafeForConcurrentUse(t *testing.T) {
+	var forms []url.Values
+	server := newTestUserTokenServer(t, &forms)
+	defer server.Close()
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, server.Client())
+	refresher := &refreshTokenSource{ctx: ctx, conf: newTestAuthCodeConfig(server), refreshToken: testRefreshToken}
+
+	var wg sync.WaitGroup
+	for n := 0; n < 4; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tk, err := refresher.Token()
+			assert.Check(t, err)
+			assert.Check(t, tk != nil && tk.RefreshToken == testRefreshToken)
+		}()
+	}
+	wg.Wait()
+
+	// The refreshes are serialized, the test server appends the forms without locking
+	assert.Equal(t, len(forms), 4)
+}
+
+func TestIsLoopbackRedirectCapturingCode(t *testing.T) {
+	redirect, err := NewLoopbackRedirect("127.0.0.1:0", "/callback", "STATE")
+	assert.NilError(t, err)
+	defer redirect.Close()
+
+	rs, err := http.Get(redirect.URL() + "?state=STATE&code=" + url.QueryEscape(testCode) + "&expires_in=299")
+	assert.NilError(t, err)
+	rs.Body.Close()
+	assert.Equal(t, rs.StatusCode, http.StatusOK)
+
+	code, err := redirect.Wait(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, code, testCode)
+}
+
+func TestIsLoopbackRedirectRejectingWrongState(t *testing.T) {
+	redirect, err := NewLoopbackRedirect("127.0.0.1:0", "/callback", "STATE")
+	assert.NilError(t, err)
+	defer redirect.Close()
+
+	rs, err := http.Get(redirect.URL() + "?state=OTHER&code=CODE")
+	assert.NilError(t, err)
+	rs.Body.Close()
+	assert.Equal(t, rs.StatusCode, http.StatusBadRequest)
+
+	_, err = redirect.Wait(context.Background())
+	assert.Error(t, err, "handle(): state mismatch: OTHER")
+}
+
+func TestIsLoginWithLoopbackExchangingCode(t *testing.T) {
+	var forms []url.Values
+	server := newTestUserTokenServer(t, &forms)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), oauth2.HTTPClient, server.Client()), 10*time.Second)
+	defer cancel()
+
+	// Picking a free port for the loopback redirect, as it would be configured in the RuName accept URL
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	// Simulating the browser: the consent page redirects to the loopback URL which is given as RuName accept URL
+	open := func(consentURL string) error {
+		u, err := url.Parse(consentURL)
+		if err != nil {
+			return err
+		}
+		go func() {
+			rs, err := http.Get("http://" + addr + "/callback?code=CODE&state=" + u.Query().Get("state"))
+			if err == nil {
+				rs.Body.Close()
+			}
+		}()
+		return nil
+	}
+
+	tk, err := newTestAuthCodeConfig(server).LoginWithLoopback(ctx, addr, "/callback", open)
+	assert.NilError(t, err)
+	assert.Equal(t, tk.AccessToken, "ACCESS-1")
+	assert.Equal(t, forms[0].Get("code"), "CODE")
+}
@@ -0,0 +1,197 @@
+package oauth2
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// secretClientID is the name of the file storing the client id in a secrets directory
+	secretClientID string = "ebay_api_client_id"
+	// secretClientSecret is the name of the file storing the client secret in a secrets directory
+	secretClientSecret string = "ebay_api_client_secret"
+)
+
+// Credentials are the eBay application keys used to mint the access tokens
+type Credentials struct {
+	// ClientID is the eBay application client id (App ID)
+	ClientID string `json:"client_id"`
+	// ClientSecret is the eBay application client secret (Cert ID)
+	ClientSecret string `json:"client_secret"`
+}
+
+// valid reports whether both the client id and the client secret are set
+func (c *Credentials) valid() bool {
+	return c != nil && c.ClientID != "" && c.ClientSecret != ""
+}
+
+// CredentialsProvider supplies the eBay application credentials to the HTTP clients
+type CredentialsProvider interface {
+	// Credentials returns the application credentials or an error if they are not available
+	Credentials() (*Credentials, error)
+}
+
+// EnvCredentials reads the credentials from os environment variables
+type EnvCredentials struct {
+	// ClientIDVar is the name of the os variable storing the client id
+	ClientIDVar string
+	// ClientSecretVar is the name of the os variable storing the client secret
+	ClientSecretVar string
+}
+
+// NewEnvCredentials creates a new EnvCredentials reading the os variables given in the const APIClientID and APIClientSecret
+func NewEnvCredentials() *EnvCredentials {
+	return &EnvCredentials{
+		ClientIDVar:     APIClientID,
+		ClientSecretVar: APIClientSecret,
+	}
+}
+
+// Credentials returns the credentials read from the os environment variables
+func (e *EnvCredentials) Credentials() (*Credentials, error) {
+	clientID := os.Getenv(e.ClientIDVar)
+	if clientID == "" {
+		return nil, fmt.Errorf("Environment variable %v is not set", e.ClientIDVar)
+	}
+
+	clientSecret := os.Getenv(e.ClientSecretVar)
+	if clientSecret == "" {
+		return nil, fmt.Errorf("Environment variable %v is not set", e.ClientSecretVar)
+	}
+
+	return &Credentials{ClientID: clientID, ClientSecret: clientSecret}, nil
+}
+
+// StaticCredentials provides credentials given explicitly
+type StaticCredentials Credentials
+
+// NewStaticCredentials creates a new StaticCredentials with the given client id and client secret
+func NewStaticCredentials(clientID, clientSecret string) *StaticCredentials {
+	return &StaticCredentials{ClientID: clientID, ClientSecret: clientSecret}
+}
+
+// Credentials returns the given credentials
+func (s *StaticCredentials) Credentials() (*Credentials, error) {
+	c := Credentials(*s)
+	if !c.valid() {
+		return nil, fmt.Errorf("Client id and client secret are required")
+	}
+	return &c, nil
+}
+
+// FileCredentials reads the credentials from the file system. Path can be either:
+//   - a JSON keyset file with the client_id and client_secret fields, or with one such object for each
+//     application name, in which case App selects the application
+//   - a secrets directory (i.e. docker secrets in /run/secrets) with the ebay_api_client_id
+//     and ebay_api_client_secret files. When App is set, the files are expected in the App sub directory.
+type FileCredentials struct {
+	// Path is the keyset file or the secrets directory
+	Path string
+	// App is the name of the application, optional
+	App string
+}
+
+// NewFileCredentials creates a new FileCredentials reading the given keyset file or secrets directory
+func NewFileCredentials(path, app string) *FileCredentials {
+	return &FileCredentials{Path: path, App: app}
+}
+
+// Credentials returns the credentials read from the file system
+func (f *FileCredentials) Credentials() (*Credentials, error) {
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("Credentials(): cannot read %v: %v", f.Path, err)
+	}
+
+	var c *Credentials
+	if info.IsDir() {
+		c, err = f.fromDir()
+	} else {
+		c, err = f.fromKeyset()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.valid() {
+		return nil, fmt.Errorf("Credentials(): client id or client secret missing in %v", f.Path)
+	}
+	return c, nil
+}
+
+// fromDir reads the credentials from a secrets directory
+func (f *FileCredentials) fromDir() (*Credentials, error) {
+	dir := filepath.Join(f.Path, f.App)
+
+	read := func(name string) (string, error) {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return "", fmt.Errorf("fromDir(): cannot read secret: %v", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	clientID, err := read(secretClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	clientSecret, err := read(secretClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credentials{ClientID: clientID, ClientSecret: clientSecret}, nil
+}
+
+// fromKeyset reads the credentials from a JSON keyset file
+func (f *FileCredentials) fromKeyset() (*Credentials, error) {
+	data, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("fromKeyset(): cannot read keyset: %v", err)
+	}
+
+	if f.App == "" {
+		c := &Credentials{}
+		if err := json.Unmarshal(data, c); err != nil {
+			return nil, fmt.Errorf("fromKeyset(): cannot decode keyset %v: %v", f.Path, err)
+		}
+		return c, nil
+	}
+
+	apps := map[string]*Credentials{}
+	if err := json.Unmarshal(data, &apps); err != nil {
+		return nil, fmt.Errorf("fromKeyset(): cannot decode keyset %v: %v", f.Path, err)
+	}
+
+	c, ok := apps[f.App]
+	if !ok {
+		return nil, fmt.Errorf("fromKeyset(): application %v not found in %v", f.App, f.Path)
+	}
+	return c, nil
+}
+
+// ChainCredentials returns the credentials of the first provider able to supply them
+type ChainCredentials []CredentialsProvider
+
+// NewChainCredentials creates a new ChainCredentials trying the given providers in order
+func NewChainCredentials(providers ...CredentialsProvider) ChainCredentials {
+	return ChainCredentials(providers)
+}
+
+// Credentials returns the credentials of the first provider not returning an error
+func (c ChainCredentials) Credentials() (*Credentials, error) {
+	var errs []string
+	for _, p := range c {
+		creds, err := p.Credentials()
+		if err == nil {
+			return creds, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return nil, fmt.Errorf("Credentials(): no credentials found: %v", strings.Join(errs, "; "))
+}
@@ -0,0 +1,94 @@
+package oauth2
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func newTestCredentialsDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "credentials")
+	assert.NilError(t, err)
+	return dir
+}
+
+func TestIsEnvCredentialsReadingCustomVariables(t *testing.T) {
+	os.Setenv("APP2_CLIENT_ID", "APP2_ID")
+	os.Setenv("APP2_CLIENT_SECRET", "APP2_SECRET")
+	defer os.Unsetenv("APP2_CLIENT_ID")
+	defer os.Unsetenv("APP2_CLIENT_SECRET")
+
+	c, err := (&EnvCredentials{ClientIDVar: "APP2_CLIENT_ID", ClientSecretVar: "APP2_CLIENT_SECRET"}).Credentials()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, c, &Credentials{ClientID: "APP2_ID", ClientSecret: "APP2_SECRET"})
+
+	_, err = NewEnvCredentials().Credentials()
+	assert.Error(t, err, "Environment variable EBAY_API_CLIENT_ID is not set")
+}
+
+func TestIsStaticCredentialsValidated(t *testing.T) {
+	c, err := NewStaticCredentials("ID", "SECRET").Credentials()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, c, &Credentials{ClientID: "ID", ClientSecret: "SECRET"})
+
+	_, err = NewStaticCredentials("", "SECRET").Credentials()
+	assert.Error(t, err, "Client id and client secret are required")
+}
+
+func TestIsFileCredentialsReadingKeyset(t *testing.T) {
+	dir := newTestCredentialsDir(t)
+	defer os.RemoveAll(dir)
+
+	single := filepath.Join(dir, "keyset.json")
+	assert.NilError(t, ioutil.WriteFile(single, []byte(`{"client_id": "ID", "client_secret": "SECRET"}`), 0600))
+
+	c, err := NewFileCredentials(single, "").Credentials()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, c, &Credentials{ClientID: "ID", ClientSecret: "SECRET"})
+
+	multi := filepath.Join(dir, "keysets.json")
+	assert.NilError(t, ioutil.WriteFile(multi, []byte(`{
+		"app1": {"client_id": "ID1", "client_secret": "SECRET1"},
+		"app2": {"client_id": "ID2", "client_secret": "SECRET2"}
+	}`), 0600))
+
+	c, err = NewFileCredentials(multi, "app2").Credentials()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, c, &Credentials{ClientID: "ID2", ClientSecret: "SECRET2"})
+
+	_, err = NewFileCredentials(multi, "app3").Credentials()
+	assert.ErrorContains(t, err, "application app3 not found")
+
+	_, err = NewFileCredentials(multi, "").Credentials()
+	assert.ErrorContains(t, err, "client id or client secret missing")
+}
+
+func TestIsFileCredentialsReadingSecretsDirectory(t *testing.T) {
+	dir := newTestCredentialsDir(t)
+	defer os.RemoveAll(dir)
+
+	assert.NilError(t, os.Mkdir(filepath.Join(dir, "app1"), 0700))
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "app1", secretClientID), []byte("ID1\n"), 0600))
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(dir, "app1", secretClientSecret), []byte("SECRET1\n"), 0600))
+
+	c, err := NewFileCredentials(dir, "app1").Credentials()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, c, &Credentials{ClientID: "ID1", ClientSecret: "SECRET1"})
+
+	_, err = NewFileCredentials(dir, "").Credentials()
+	assert.ErrorContains(t, err, "cannot read secret")
+}
+
+func TestIsChainCredentialsReturningFirstAvailable(t *testing.T) {
+	chain := NewChainCredentials(NewEnvCredentials(), NewStaticCredentials("ID", "SECRET"))
+
+	c, err := chain.Credentials()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, c, &Credentials{ClientID: "ID", ClientSecret: "SECRET"})
+
+	_, err = NewChainCredentials(NewEnvCredentials(), NewStaticCredentials("", "")).Credentials()
+	assert.Error(t, err, "Credentials(): no credentials found: Environment variable EBAY_API_CLIENT_ID is not set; Client id and client secret are required")
+}
@@ -5,7 +5,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"os"
+	"sync"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
@@ -18,60 +18,83 @@ const (
 	APIClientSecret string = "EBAY_API_CLIENT_SECRET"
 )
 
-const (
-	// ScopeBuyFeedAPI is the scope identifier required to access to the buy/feed api
-	ScopeBuyFeedAPI string = "https://api.ebay.com/oauth/api_scope/buy.item.feed"
-)
-
 // SandBoxEndpoint is eBay oAuth2 Sandbox endpoint URLs
 var SandBoxEndpoint = oauth2.Endpoint{
-	AuthURL:  "https://api.sandbox.ebay.com/identity/v1/oauth2/auth",
+	AuthURL:  "https://auth.sandbox.ebay.com/oauth2/authorize",
 	TokenURL: "https://api.sandbox.ebay.com/identity/v1/oauth2/token",
 }
 
 // ProdEndpoint is eBay oAuth2 Prod endpoint URLs
 var ProdEndpoint = oauth2.Endpoint{
-	AuthURL:  "https://api.ebay.com/identity/v1/oauth2/auth",
+	AuthURL:  "https://auth.ebay.com/oauth2/authorize",
 	TokenURL: "https://api.ebay.com/identity/v1/oauth2/token",
 }
 
 // NewSandboxClientCredentialsClient creates a new HTTP client using oAuth2 client credential token flow and pointing to eBay sandbox environment
-func NewSandboxClientCredentialsClient(ctx context.Context, scopes []string) (*http.Client, error) {
-	return NewClientCredentialsClient(ctx, SandBoxEndpoint.TokenURL, scopes)
+func NewSandboxClientCredentialsClient(ctx context.Context, creds CredentialsProvider, scopes []string) (*http.Client, error) {
+	return NewClientCredentialsClient(ctx, creds, SandBoxEndpoint.TokenURL, scopes)
 }
 
-// NewProdClientCredentialsClient creates a new HTTP client using oAuth2 client credential token flow and pointing to eBay sandbox environment
-func NewProdClientCredentialsClient(ctx context.Context, scopes []string) (*http.Client, error) {
-	return NewClientCredentialsClient(ctx, SandBoxEndpoint.TokenURL, scopes)
+// NewProdClientCredentialsClient creates a new HTTP client using oAuth2 client credential token flow and pointing to eBay production environment
+func NewProdClientCredentialsClient(ctx context.Context, creds CredentialsProvider, scopes []string) (*http.Client, error) {
+	return NewClientCredentialsClient(ctx, creds, ProdEndpoint.TokenURL, scopes)
 }
 
 // NewClientCredentialsClient creates a new HTTP client using the oAuth2 client credential token flow.
-// The functions requires that os environment variables axists as given in the const APIClientID and APIClientSecret
+// The application credentials are supplied by the given provider, i.e. NewEnvCredentials() to read them
+// from the os environment variables given in the const APIClientID and APIClientSecret.
 // Check here for more details https://godoc.org/golang.org/x/oauth2/clientcredentials
-func NewClientCredentialsClient(ctx context.Context, tokenURL string, scopes []string) (*http.Client, error) {
+func NewClientCredentialsClient(ctx context.Context, creds CredentialsProvider, tokenURL string, scopes []string) (*http.Client, error) {
+	return newClientCredentialsClient(ctx, creds, tokenURL, scopes, nil)
+}
 
-	clientID := os.Getenv(APIClientID)
-	if clientID == "" {
-		return nil, fmt.Errorf("Environment variable %v is not set", APIClientID)
+// NewCachedClientCredentialsClient creates a new HTTP client using the oAuth2 client credential token flow
+// which reuses the tokens saved in the given TokenStore until shortly before they expire.
+func NewCachedClientCredentialsClient(ctx context.Context, creds CredentialsProvider, tokenURL string, scopes []string, store TokenStore) (*http.Client, error) {
+	return newClientCredentialsClient(ctx, creds, tokenURL, scopes, store)
+}
+
+// newClientCredentialsClient creates the HTTP client using the oAuth2 client credential token flow.
+// When store is not nil, the tokens are shared through it.
+func newClientCredentialsClient(ctx context.Context, creds CredentialsProvider, tokenURL string, scopes []string, store TokenStore) (*http.Client, error) {
+
+	if creds == nil {
+		return nil, fmt.Errorf("Credentials provider is required")
 	}
 
-	clientSecret := os.Getenv(APIClientSecret)
-	if clientSecret == "" {
-		return nil, fmt.Errorf("Environment variable %v is not set", APIClientSecret)
+	c, err := creds.Credentials()
+	if err != nil {
+		return nil, err
 	}
 
-	ts := newTokenSource(ctx, &clientcredentials.Config{
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
+	if !c.valid() {
+		return nil, fmt.Errorf("Client id and client secret are required")
+	}
+
+	conf := &clientcredentials.Config{
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
 		Scopes:       scopes,
 		TokenURL:     tokenURL,
 		AuthStyle:    oauth2.AuthStyleInHeader,
-	})
+	}
+
+	var ts oauth2.TokenSource
+	if store == nil {
+		ts = newTokenSource(ctx, conf)
+	} else {
+		ts = newCachedTokenSource(TokenKey(c.ClientID, tokenURL, scopes), store, &tokenSource{
+			ctx:  ctx,
+			conf: conf,
+			orig: mintTokenSource{ctx: ctx, conf: conf},
+		})
+	}
 
 	return &http.Client{
-		Transport: &oauth2.Transport{
+		Transport: &Transport{
 			Base:   contextClient(ctx).Transport,
 			Source: ts,
+			Scopes: append([]string{}, scopes...),
 		},
 	}, nil
 }
@@ -85,6 +108,9 @@ func newTokenSource(ctx context.Context, conf *clientcredentials.Config) oauth2.
 		ctx:  ctx,
 		conf: conf,
 		orig: conf.TokenSource(ctx),
+		renew: func() oauth2.TokenSource {
+			return conf.TokenSource(ctx)
+		},
 	}
 	return source
 }
@@ -92,18 +118,62 @@ func newTokenSource(ctx context.Context, conf *clientcredentials.Config) oauth2.
 type tokenSource struct {
 	ctx  context.Context
 	conf *clientcredentials.Config
+
+	mu   sync.Mutex
 	orig oauth2.TokenSource
+	// renew creates a new orig token source which does not reuse the current token
+	renew func() oauth2.TokenSource
+	// current is the access token last returned by orig, the only one whose rejection renews orig
+	current string
 }
 
 func (t *tokenSource) Token() (*oauth2.Token, error) {
-	tk, err := t.orig.Token()
+	t.mu.Lock()
+	orig := t.orig
+	t.mu.Unlock()
+
+	tk, err := orig.Token()
 	if err != nil {
 		return tk, err
 	}
 
-	// Forcing the TokenType to bearer
-	tk.TokenType = "bearer"
-	return tk, err
+	t.mu.Lock()
+	if t.orig == orig {
+		t.current = tk.AccessToken
+	}
+	t.mu.Unlock()
+
+	// Forcing the TokenType to bearer on a copy, as orig returns the same token to the concurrent callers
+	bearer := *tk
+	bearer.TokenType = "bearer"
+	return &bearer, nil
+}
+
+// invalidate drops the current token so that the next call to Token gets a new one.
+// A token which is not the current one anymore was already renewed, i.e. by a concurrent request rejected
+// with the same token, and it is ignored so that the concurrent rejections mint a single new token.
+func (t *tokenSource) invalidate(rejected *oauth2.Token) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if rejected == nil || rejected.AccessToken != t.current {
+		return
+	}
+
+	t.current = ""
+	if t.renew != nil {
+		t.orig = t.renew()
+	}
+}
+
+// mintTokenSource mints a new token at each call, the reuse logic is left to the cachedTokenSource
+type mintTokenSource struct {
+	ctx  context.Context
+	conf *clientcredentials.Config
+}
+
+func (m mintTokenSource) Token() (*oauth2.Token, error) {
+	return m.conf.Token(m.ctx)
 }
 
 // Maintaining compatibility with standard oauth2.internal.transport implementation
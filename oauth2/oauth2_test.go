@@ -22,7 +22,7 @@ func TestIsNewClientCredentialsClientCreated(t *testing.T) {
 	os.Setenv(APIClientID, "EBAY_CLIENT_ID")
 	os.Setenv(APIClientSecret, "EBAY_CLIENT_SECRET")
 
-	_, err := NewClientCredentialsClient(oauth2.NoContext, SandBoxEndpoint.TokenURL, []string{ScopeBuyFeedAPI})
+	_, err := NewClientCredentialsClient(oauth2.NoContext, NewEnvCredentials(), SandBoxEndpoint.TokenURL, []string{ScopeBuyFeedAPI})
 	assert.NilError(t, err)
 
 	shutdown()
@@ -31,7 +31,7 @@ func TestIsNewClientCredentialsClientCreated(t *testing.T) {
 func TestIsNewClientCredentialsClientReturningErrorIfNoAPIClientID(t *testing.T) {
 	os.Setenv(APIClientSecret, "EBAY_CLIENT_SECRET")
 
-	_, err := NewClientCredentialsClient(oauth2.NoContext, SandBoxEndpoint.TokenURL, []string{ScopeBuyFeedAPI})
+	_, err := NewClientCredentialsClient(oauth2.NoContext, NewEnvCredentials(), SandBoxEndpoint.TokenURL, []string{ScopeBuyFeedAPI})
 	assert.Error(t, err, "Environment variable EBAY_API_CLIENT_ID is not set")
 
 	shutdown()
@@ -40,7 +40,7 @@ func TestIsNewClientCredentialsClientReturningErrorIfNoAPIClientID(t *testing.T)
 func TestIsNewClientCredentialsClientReturningErrorIfNoAPIClientSecret(t *testing.T) {
 	os.Setenv(APIClientID, "EBAY_CLIENT_ID")
 
-	_, err := NewClientCredentialsClient(oauth2.NoContext, SandBoxEndpoint.TokenURL, []string{ScopeBuyFeedAPI})
+	_, err := NewClientCredentialsClient(oauth2.NoContext, NewEnvCredentials(), SandBoxEndpoint.TokenURL, []string{ScopeBuyFeedAPI})
 	assert.Error(t, err, "Environment variable EBAY_API_CLIENT_SECRET is not set")
 
 	shutdown()
@@ -50,7 +50,7 @@ func TestIsNewSandboxClientCredentialsClientCreated(t *testing.T) {
 	os.Setenv(APIClientID, "EBAY_CLIENT_ID")
 	os.Setenv(APIClientSecret, "EBAY_CLIENT_SECRET")
 
-	_, err := NewSandboxClientCredentialsClient(oauth2.NoContext, []string{ScopeBuyFeedAPI})
+	_, err := NewSandboxClientCredentialsClient(oauth2.NoContext, NewEnvCredentials(), []string{ScopeBuyFeedAPI})
 	assert.NilError(t, err)
 }
 
@@ -58,10 +58,32 @@ func TestIsNewProdClientCredentialsClientCreated(t *testing.T) {
 	os.Setenv(APIClientID, "EBAY_CLIENT_ID")
 	os.Setenv(APIClientSecret, "EBAY_CLIENT_SECRET")
 
-	_, err := NewProdClientCredentialsClient(oauth2.NoContext, []string{ScopeBuyFeedAPI})
+	_, err := NewProdClientCredentialsClient(oauth2.NoContext, NewEnvCredentials(), []string{ScopeBuyFeedAPI})
 	assert.NilError(t, err)
 }
 
+func TestIsNewProdClientCredentialsClientUsingProdTokenURL(t *testing.T) {
+	os.Setenv(APIClientID, "EBAY_CLIENT_ID")
+	os.Setenv(APIClientSecret, "EBAY_CLIENT_SECRET")
+	defer shutdown()
+
+	c, err := NewProdClientCredentialsClient(oauth2.NoContext, NewEnvCredentials(), []string{ScopeBuyFeedAPI})
+	assert.NilError(t, err)
+
+	ts := c.Transport.(*Transport).Source.(*tokenSource)
+	assert.Equal(t, ts.conf.TokenURL, ProdEndpoint.TokenURL)
+}
+
+func TestIsNewClientCredentialsClientReturningErrorIfNoSecret(t *testing.T) {
+	_, err := NewClientCredentialsClient(oauth2.NoContext, NewStaticCredentials("EBAY_CLIENT_ID", ""), SandBoxEndpoint.TokenURL, []string{ScopeBuyFeedAPI})
+	assert.Error(t, err, "Client id and client secret are required")
+}
+
+func TestIsNewClientCredentialsClientReturningErrorIfNoProvider(t *testing.T) {
+	_, err := NewClientCredentialsClient(oauth2.NoContext, nil, SandBoxEndpoint.TokenURL, []string{ScopeBuyFeedAPI})
+	assert.Error(t, err, "Credentials provider is required")
+}
+
 func TestIsTokenSettingTokenTypeToBearer(t *testing.T) {
 
 	tkRs := `{
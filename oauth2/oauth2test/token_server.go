@@ -0,0 +1,312 @@
+// Package oauth2test provides an in-process fake of the eBay oAuth2 token endpoint to test the API clients offline
+package oauth2test
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultExpiry is the lifetime of the issued access tokens, as eBay Application Access Tokens
+	DefaultExpiry = 2 * time.Hour
+	// DefaultTokenType is the token_type returned by eBay, which is not the standard "bearer"
+	DefaultTokenType string = "Application Access Token"
+
+	// tokenPath is the path of the eBay oAuth2 token endpoint
+	tokenPath string = "/identity/v1/oauth2/token"
+)
+
+// eBay oAuth2 error codes. Check for details https://developer.ebay.com/api-docs/static/oauth-error-codes.html
+const (
+	// ErrInvalidClient is returned when the client credentials are wrong
+	ErrInvalidClient string = "invalid_client"
+	// ErrInvalidRequest is returned when a parameter is missing or invalid
+	ErrInvalidRequest string = "invalid_request"
+	// ErrInvalidScope is returned when a requested scope is not granted to the client
+	ErrInvalidScope string = "invalid_scope"
+	// ErrUnsupportedGrantType is returned when the grant type is not supported
+	ErrUnsupportedGrantType string = "unsupported_grant_type"
+	// ErrServerError is returned when eBay cannot process the request
+	ErrServerError string = "server_error"
+)
+
+// OAuthError is the error payload returned by eBay oAuth2 token endpoint
+type OAuthError struct {
+	// Status is the HTTP status code of the response
+	Status int `json:"-"`
+	// Code is the error code, i.e. ErrInvalidClient
+	Code string `json:"error"`
+	// Description is the human readable error description
+	Description string `json:"error_description"`
+}
+
+// Token is an access token issued by the TokenServer
+type Token struct {
+	// AccessToken is the token value
+	AccessToken string
+	// ClientID is the client the token was issued to
+	ClientID string
+	// Scopes are the scopes granted to the token
+	Scopes []string
+	// Expiry is the expiration time of the token
+	Expiry time.Time
+}
+
+// client is an application registered in the TokenServer
+type client struct {
+	secret string
+	scopes map[string]bool
+}
+
+// TokenServer is an in-process fake of the eBay oAuth2 token endpoint implementing the client credentials grant.
+// It checks the Basic auth client credentials and the requested scopes, and returns the eBay error payloads.
+type TokenServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	clients   map[string]*client
+	tokens    map[string]*Token
+	errors    []OAuthError
+	expiry    time.Duration
+	tokenType string
+	requests  int
+	now       func() time.Time
+}
+
+// NewTokenServer starts a new TokenServer. The caller has to call Close when done.
+func NewTokenServer() *TokenServer {
+	s := &TokenServer{
+		clients:   make(map[string]*client),
+		tokens:    make(map[string]*Token),
+		expiry:    DefaultExpiry,
+		tokenType: DefaultTokenType,
+		now:       time.Now,
+	}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// TokenURL returns the token endpoint URL, to be used in place of eBay token URL
+func (s *TokenServer) TokenURL() string {
+	return s.URL + tokenPath
+}
+
+// AddClient registers an application with its credentials and the scopes it can be granted
+func (s *TokenServer) AddClient(clientID, clientSecret string, scopes ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := &client{secret: clientSecret, scopes: make(map[string]bool)}
+	for _, scope := range scopes {
+		c.scopes[scope] = true
+	}
+	s.clients[clientID] = c
+}
+
+// SetExpiry sets the lifetime of the next issued tokens
+func (s *TokenServer) SetExpiry(expiry time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expiry = expiry
+}
+
+// SetTokenType sets the token_type of the next issued tokens
+func (s *TokenServer) SetTokenType(tokenType string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokenType = tokenType
+}
+
+// InjectError makes the server answer the next token request with the given error
+func (s *TokenServer) InjectError(e OAuthError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.errors = append(s.errors, e)
+}
+
+// Requests returns the number of token requests received so far
+func (s *TokenServer) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests
+}
+
+// Revoke invalidates the given access token before its expiry
+func (s *TokenServer) Revoke(accessToken string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tokens, accessToken)
+}
+
+// Lookup returns the issued token matching the given access token, if not expired nor revoked
+func (s *TokenServer) Lookup(accessToken string) (*Token, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tk, ok := s.tokens[accessToken]
+	if !ok || !s.now().Before(tk.Expiry) {
+		return nil, false
+	}
+	return tk, true
+}
+
+// Authorize reports whether the request carries a valid bearer token granted the given scopes.
+// It can be used by the fake API servers to check the requests authorization.
+func (s *TokenServer) Authorize(r *http.Request, scopes ...string) bool {
+	value := r.Header.Get("Authorization")
+	if len(value) < 7 || !strings.EqualFold(value[:7], "bearer ") {
+		return false
+	}
+
+	tk, ok := s.Lookup(value[7:])
+	if !ok {
+		return false
+	}
+
+	granted := make(map[string]bool, len(tk.Scopes))
+	for _, scope := range tk.Scopes {
+		granted[scope] = true
+	}
+	for _, scope := range scopes {
+		if !granted[scope] {
+			return false
+		}
+	}
+	return true
+}
+
+// ServeHTTP serves the token requests
+func (s *TokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests++
+	s.mu.Unlock()
+
+	if r.URL.Path != tokenPath {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeError(w, OAuthError{Status: http.StatusMethodNotAllowed, Code: ErrInvalidRequest, Description: "request method is not supported"})
+		return
+	}
+
+	if e, ok := s.nextError(); ok {
+		writeError(w, e)
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || !s.validClient(clientID, clientSecret) {
+		writeError(w, OAuthError{Status: http.StatusUnauthorized, Code: ErrInvalidClient, Description: "client authentication failed"})
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, OAuthError{Status: http.StatusBadRequest, Code: ErrInvalidRequest, Description: "request is malformed"})
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "client_credentials" {
+		writeError(w, OAuthError{Status: http.StatusBadRequest, Code: ErrUnsupportedGrantType, Description: "grant type in request is not supported by the authorization server"})
+		return
+	}
+
+	scopes := strings.Fields(r.PostForm.Get("scope"))
+	if len(scopes) == 0 || !s.validScopes(clientID, scopes) {
+		writeError(w, OAuthError{Status: http.StatusBadRequest, Code: ErrInvalidScope, Description: "The requested scope is invalid, unknown, malformed, or exceeds the scope granted to the client"})
+		return
+	}
+
+	tk := s.issue(clientID, scopes)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tk)
+}
+
+func (s *TokenServer) nextError() (OAuthError, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.errors) == 0 {
+		return OAuthError{}, false
+	}
+
+	e := s.errors[0]
+	s.errors = s.errors[1:]
+	return e, true
+}
+
+func (s *TokenServer) validClient(clientID, clientSecret string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.clients[clientID]
+	return ok && c.secret == clientSecret
+}
+
+func (s *TokenServer) validScopes(clientID string, scopes []string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.clients[clientID]
+	for _, scope := range scopes {
+		if !c.scopes[scope] {
+			return false
+		}
+	}
+	return true
+}
+
+// issue mints a new access token and returns the token response payload
+func (s *TokenServer) issue(clientID string, scopes []string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tk := &Token{
+		AccessToken: newAccessToken(),
+		ClientID:    clientID,
+		Scopes:      scopes,
+		Expiry:      s.now().Add(s.expiry),
+	}
+	s.tokens[tk.AccessToken] = tk
+
+	return struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+		TokenType   string `json:"token_type"`
+	}{
+		AccessToken: tk.AccessToken,
+		ExpiresIn:   int64(s.expiry / time.Second),
+		TokenType:   s.tokenType,
+	}
+}
+
+// newAccessToken returns a random token value in the eBay format
+func newAccessToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return "v^1.1#i^1#f^0#r^0#I^3#p^1#t^" + hex.EncodeToString(b)
+}
+
+// writeError writes the eBay oAuth2 error payload
+func writeError(w http.ResponseWriter, e OAuthError) {
+	if e.Status == 0 {
+		e.Status = http.StatusBadRequest
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.Status)
+	json.NewEncoder(w).Encode(e)
+}
@@ -0,0 +1,168 @@
+package oauth2test
+
+import (
+	"context"
+	"ebay-api-client/oauth2"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+const (
+	testScope      string = "https://api.ebay.com/oauth/api_scope/buy.item.feed"
+	testOtherScope string = "https://api.ebay.com/oauth/api_scope/buy.marketing"
+)
+
+func newTestTokenRequest(t *testing.T, s *TokenServer, clientID, clientSecret, grantType, scope string) *http.Request {
+	form := url.Values{"grant_type": {grantType}, "scope": {scope}}
+	rq, err := http.NewRequest("POST", s.TokenURL(), strings.NewReader(form.Encode()))
+	assert.NilError(t, err)
+
+	rq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rq.SetBasicAuth(clientID, clientSecret)
+	return rq
+}
+
+func doTokenRequest(t *testing.T, rq *http.Request) (int, map[string]interface{}) {
+	rs, err := http.DefaultClient.Do(rq)
+	assert.NilError(t, err)
+	defer rs.Body.Close()
+
+	payload := map[string]interface{}{}
+	assert.NilError(t, json.NewDecoder(rs.Body).Decode(&payload))
+	return rs.StatusCode, payload
+}
+
+func TestIsTokenServerIssuingTokens(t *testing.T) {
+	s := NewTokenServer()
+	defer s.Close()
+	s.AddClient("ID", "SECRET", testScope)
+
+	status, payload := doTokenRequest(t, newTestTokenRequest(t, s, "ID", "SECRET", "client_credentials", testScope))
+	assert.Equal(t, status, http.StatusOK)
+	assert.Equal(t, payload["token_type"], DefaultTokenType)
+	assert.Equal(t, payload["expires_in"], float64(7200))
+
+	tk, ok := s.Lookup(payload["access_token"].(string))
+	assert.Assert(t, ok)
+	assert.Equal(t, tk.ClientID, "ID")
+	assert.DeepEqual(t, tk.Scopes, []string{testScope})
+	assert.Equal(t, s.Requests(), 1)
+}
+
+func TestIsTokenServerReturningOAuthErrors(t *testing.T) {
+	s := NewTokenServer()
+	defer s.Close()
+	s.AddClient("ID", "SECRET", testScope)
+
+	tests := []struct {
+		name       string
+		rq         *http.Request
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "Is wrong secret rejected?",
+			rq:         newTestTokenRequest(t, s, "ID", "WRONG", "client_credentials", testScope),
+			wantStatus: http.StatusUnauthorized,
+			wantError:  ErrInvalidClient,
+		},
+		{
+			name:       "Is unknown client rejected?",
+			rq:         newTestTokenRequest(t, s, "OTHER", "SECRET", "client_credentials", testScope),
+			wantStatus: http.StatusUnauthorized,
+			wantError:  ErrInvalidClient,
+		},
+		{
+			name:       "Is scope not granted rejected?",
+			rq:         newTestTokenRequest(t, s, "ID", "SECRET", "client_credentials", testScope+" "+testOtherScope),
+			wantStatus: http.StatusBadRequest,
+			wantError:  ErrInvalidScope,
+		},
+		{
+			name:       "Is missing scope rejected?",
+			rq:         newTestTokenRequest(t, s, "ID", "SECRET", "client_credentials", ""),
+			wantStatus: http.StatusBadRequest,
+			wantError:  ErrInvalidScope,
+		},
+		{
+			name:       "Is other grant rejected?",
+			rq:         newTestTokenRequest(t, s, "ID", "SECRET", "password", testScope),
+			wantStatus: http.StatusBadRequest,
+			wantError:  ErrUnsupportedGrantType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, payload := doTokenRequest(t, tt.rq)
+			assert.Equal(t, status, tt.wantStatus)
+			assert.Equal(t, payload["error"], tt.wantError)
+			assert.Assert(t, payload["error_description"] != "")
+		})
+	}
+}
+
+func TestIsTokenServerReturningInjectedError(t *testing.T) {
+	s := NewTokenServer()
+	defer s.Close()
+	s.AddClient("ID", "SECRET", testScope)
+
+	s.InjectError(OAuthError{Status: http.StatusServiceUnavailable, Code: ErrServerError, Description: "temporarily unavailable"})
+
+	status, payload := doTokenRequest(t, newTestTokenRequest(t, s, "ID", "SECRET", "client_credentials", testScope))
+	assert.Equal(t, status, http.StatusServiceUnavailable)
+	assert.Equal(t, payload["error"], ErrServerError)
+
+	status, _ = doTokenRequest(t, newTestTokenRequest(t, s, "ID", "SECRET", "client_credentials", testScope))
+	assert.Equal(t, status, http.StatusOK)
+}
+
+func TestIsTokenServerAuthorizingClientCredentialsClient(t *testing.T) {
+	s := NewTokenServer()
+	defer s.Close()
+	s.AddClient("ID", "SECRET", testScope)
+
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.Authorize(r, testScope) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer apiServer.Close()
+
+	c, err := oauth2.NewClientCredentialsClient(context.Background(), oauth2.NewStaticCredentials("ID", "SECRET"), s.TokenURL(), []string{testScope})
+	assert.NilError(t, err)
+
+	rs, err := c.Get(apiServer.URL)
+	assert.NilError(t, err)
+	rs.Body.Close()
+	assert.Equal(t, rs.StatusCode, http.StatusOK)
+	assert.Equal(t, s.Requests(), 1)
+
+	rs, err = http.Get(apiServer.URL)
+	assert.NilError(t, err)
+	rs.Body.Close()
+	assert.Equal(t, rs.StatusCode, http.StatusUnauthorized)
+}
+
+func TestIsTokenServerExpiringTokens(t *testing.T) {
+	s := NewTokenServer()
+	defer s.Close()
+	s.AddClient("ID", "SECRET", testScope)
+	s.SetExpiry(time.Minute)
+	s.SetTokenType("Bearer")
+
+	status, payload := doTokenRequest(t, newTestTokenRequest(t, s, "ID", "SECRET", "client_credentials", testScope))
+	assert.Equal(t, status, http.StatusOK)
+	assert.Equal(t, payload["token_type"], "Bearer")
+	assert.Equal(t, payload["expires_in"], float64(60))
+
+	s.now = func() time.Time { return time.Now().Add(time.Minute) }
+	_, ok := s.Lookup(payload["access_token"].(string))
+	assert.Assert(t, !ok)
+}
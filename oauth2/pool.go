@@ -0,0 +1,312 @@
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPoolCooldown is how long a rate limited keyset is taken out of rotation when eBay does not tell when to retry
+	DefaultPoolCooldown = 15 * time.Minute
+	// DefaultPoolWindow is the time window of the keysets quota, eBay call limits are mostly daily
+	DefaultPoolWindow = 24 * time.Hour
+	// RateLimitErrorID is the eBay error id reporting that the call limit has been exceeded
+	RateLimitErrorID = 2001
+)
+
+// PoolStrategy defines how the Pool spreads the requests across the keysets
+type PoolStrategy int
+
+const (
+	// RoundRobin sends each request to the next available keyset in turn
+	RoundRobin PoolStrategy = iota
+	// LeastUsed sends each request to the available keyset with the fewest calls in the current window
+	LeastUsed
+)
+
+// PoolKeyset is an eBay application keyset member of the Pool
+type PoolKeyset struct {
+	// Name identifies the keyset in the Pool status
+	Name string
+	// Credentials supplies the keyset credentials
+	Credentials CredentialsProvider
+	// Quota is the number of calls allowed within the pool window. Zero means unlimited.
+	Quota int64
+}
+
+// PoolConfig is the configuration of a Pool
+type PoolConfig struct {
+	// TokenURL is the oAuth2 token URL
+	TokenURL string
+	// Scopes are the scopes requested for each keyset
+	Scopes []string
+	// Keysets are the pool members
+	Keysets []PoolKeyset
+	// Strategy defines how the requests are spread across the keysets
+	Strategy PoolStrategy
+	// Cooldown is how long a rate limited keyset stays out of rotation when the response has no Retry-After. Defaults to DefaultPoolCooldown.
+	Cooldown time.Duration
+	// Window is the time window of the keysets quota. Defaults to DefaultPoolWindow.
+	Window time.Duration
+	// Store, when not nil, shares the keysets tokens as for NewCachedClientCredentialsClient
+	Store TokenStore
+}
+
+// KeysetStatus reports the usage of a keyset in the Pool
+type KeysetStatus struct {
+	// Name is the keyset name
+	Name string
+	// Used is the number of calls sent in the current window
+	Used int64
+	// Quota is the number of calls allowed within the window
+	Quota int64
+	// AvailableAt is the time the keyset is back in rotation. It is zero if the keyset is available.
+	AvailableAt time.Time
+}
+
+// Pool is an http.RoundTripper spreading the requests across multiple eBay application keysets,
+// each with its own token source and quota. A keyset answered with a rate limit error (429 or eBay error 2001)
+// is taken out of rotation until its reset time and the request is replayed on the next available keyset,
+// provided that it can be replayed (see Transport).
+type Pool struct {
+	strategy PoolStrategy
+	cooldown time.Duration
+	window   time.Duration
+	scopes   []string
+
+	mu      sync.Mutex
+	members []*poolMember
+	next    int
+	now     func() time.Time
+}
+
+type poolMember struct {
+	name          string
+	transport     http.RoundTripper
+	quota         int64
+	used          int64
+	windowStart   time.Time
+	disabledUntil time.Time
+}
+
+// NewPool creates a new Pool with the given configuration
+func NewPool(ctx context.Context, conf PoolConfig) (*Pool, error) {
+	if len(conf.Keysets) == 0 {
+		return nil, fmt.Errorf("NewPool(): at least one keyset is required")
+	}
+
+	p := &Pool{
+		strategy: conf.Strategy,
+		cooldown: conf.Cooldown,
+		window:   conf.Window,
+		scopes:   append([]string{}, conf.Scopes...),
+		now:      time.Now,
+	}
+	if p.cooldown <= 0 {
+		p.cooldown = DefaultPoolCooldown
+	}
+	if p.window <= 0 {
+		p.window = DefaultPoolWindow
+	}
+
+	for _, k := range conf.Keysets {
+		c, err := newClientCredentialsClient(ctx, k.Credentials, conf.TokenURL, conf.Scopes, conf.Store)
+		if err != nil {
+			return nil, fmt.Errorf("NewPool(): keyset %v: %v", k.Name, err)
+		}
+
+		p.members = append(p.members, &poolMember{
+			name:        k.Name,
+			transport:   c.Transport,
+			quota:       k.Quota,
+			windowStart: p.now(),
+		})
+	}
+
+	return p, nil
+}
+
+// Client returns a new HTTP client sending its requests through the Pool
+func (p *Pool) Client() *http.Client {
+	return &http.Client{Transport: p}
+}
+
+// Status returns the usage of each keyset
+func (p *Pool) Status() []KeysetStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	status := make([]KeysetStatus, 0, len(p.members))
+	for _, m := range p.members {
+		m.resetWindow(now, p.window)
+
+		s := KeysetStatus{Name: m.name, Used: m.used, Quota: m.quota}
+		if at := m.availableAt(p.window); at.After(now) {
+			s.AvailableAt = at
+		}
+		status = append(status, s)
+	}
+	return status
+}
+
+// RoundTrip sends the request with the next available keyset
+func (p *Pool) RoundTrip(req *http.Request) (*http.Response, error) {
+	tried := make(map[*poolMember]bool)
+	r := req
+
+	// last is the rate limited response of the previous attempt, returned if no other keyset is available
+	var last *http.Response
+
+	for {
+		m, err := p.pick(tried)
+		if err != nil {
+			closeRequestBody(r)
+			if last != nil {
+				return last, nil
+			}
+			return nil, err
+		}
+		tried[m] = true
+
+		if last != nil {
+			io.Copy(ioutil.Discard, last.Body)
+			last.Body.Close()
+		}
+
+		rs, err := m.transport.RoundTrip(r)
+		if err != nil || !isRateLimitedResponse(rs) {
+			return rs, err
+		}
+
+		p.disable(m, retryAfter(rs, p.now()))
+
+		if !isReplayable(req) {
+			return rs, nil
+		}
+
+		replay, err := replayRequest(req)
+		if err != nil {
+			return rs, nil
+		}
+
+		last, r = rs, replay
+	}
+}
+
+// pick selects the keyset for the next request among the ones not tried yet and counts the call
+func (p *Pool) pick(tried map[*poolMember]bool) (*poolMember, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	n := len(p.members)
+
+	var (
+		picked   *poolMember
+		pickedAt int
+		earliest time.Time
+	)
+
+	for i := 0; i < n; i++ {
+		idx := (p.next + i) % n
+		m := p.members[idx]
+		if tried[m] {
+			continue
+		}
+
+		m.resetWindow(now, p.window)
+		if at := m.availableAt(p.window); at.After(now) {
+			if earliest.IsZero() || at.Before(earliest) {
+				earliest = at
+			}
+			continue
+		}
+
+		if picked == nil || (p.strategy == LeastUsed && m.used < picked.used) {
+			picked, pickedAt = m, idx
+		}
+		if p.strategy == RoundRobin {
+			break
+		}
+	}
+
+	if picked == nil {
+		return nil, fmt.Errorf("pick(): all keysets are rate limited or over quota until %v", earliest.Format(time.RFC3339))
+	}
+
+	p.next = (pickedAt + 1) % n
+	picked.used++
+	return picked, nil
+}
+
+// disable takes the keyset out of rotation until the given time
+func (p *Pool) disable(m *poolMember, until time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if until.IsZero() {
+		until = p.now().Add(p.cooldown)
+	}
+	m.disabledUntil = until
+}
+
+// resetWindow starts a new quota window when the current one is over
+func (m *poolMember) resetWindow(now time.Time, window time.Duration) {
+	if !now.Before(m.windowStart.Add(window)) {
+		m.used = 0
+		m.windowStart = now
+	}
+}
+
+// availableAt returns the time the keyset is back in rotation
+func (m *poolMember) availableAt(window time.Duration) time.Time {
+	at := m.disabledUntil
+	if m.quota > 0 && m.used >= m.quota {
+		if end := m.windowStart.Add(window); end.After(at) {
+			at = end
+		}
+	}
+	return at
+}
+
+// isRateLimitedResponse reports whether eBay rejected the request because the call limit was exceeded
+func isRateLimitedResponse(rs *http.Response) bool {
+	if rs.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if rs.StatusCode < http.StatusBadRequest {
+		return false
+	}
+
+	for _, id := range responseErrorIDs(rs) {
+		if id == RateLimitErrorID {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfter returns the time given in the Retry-After header, either in seconds or as HTTP date.
+// The zero time is returned if the header is missing or invalid.
+func retryAfter(rs *http.Response, now time.Time) time.Time {
+	value := rs.Header.Get("Retry-After")
+	if value == "" {
+		return time.Time{}
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return now.Add(time.Duration(seconds) * time.Second)
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		return at
+	}
+	return time.Time{}
+}
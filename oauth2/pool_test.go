@@ -0,0 +1,229 @@
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	"gotest.tools/assert"
+)
+
+// newTestKeysetTokenServer is a stand-in for eBay oAuth2 token endpoint issuing the token ACCESS-<client id>
+func newTestKeysetTokenServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _, _ := r.BasicAuth()
+		w.Header().Add("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "ACCESS-%v", "expires_in": 7200, "token_type": "Application Access Token"}`, id)
+	}))
+}
+
+// testKeysetAPI is a stand-in for an eBay API recording the keyset serving each request
+// and rate limiting the keysets listed in limited.
+type testKeysetAPI struct {
+	mu         sync.Mutex
+	served     []string
+	limited    map[string]bool
+	retryAfter string
+}
+
+func (a *testKeysetAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	keyset := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ACCESS-")
+	if a.limited[keyset] {
+		if a.retryAfter != "" {
+			w.Header().Set("Retry-After", a.retryAfter)
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"errors": [{"errorId": 2001, "domain": "ACCESS", "category": "REQUEST", "message": "Too many requests"}]}`)
+		return
+	}
+
+	a.served = append(a.served, keyset)
+	fmt.Fprint(w, "OK")
+}
+
+func newTestPool(t *testing.T, tokenServer *httptest.Server, strategy PoolStrategy, keysets ...PoolKeyset) *Pool {
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, tokenServer.Client())
+	p, err := NewPool(ctx, PoolConfig{
+		TokenURL: tokenServer.URL,
+		Scopes:   []string{ScopeBuyFeedAPI},
+		Keysets:  keysets,
+		Strategy: strategy,
+	})
+	assert.NilError(t, err)
+	return p
+}
+
+func newTestKeyset(name string, quota int64) PoolKeyset {
+	return PoolKeyset{Name: name, Credentials: NewStaticCredentials(name, "SECRET"), Quota: quota}
+}
+
+func TestIsPoolSpreadingRequestsRoundRobin(t *testing.T) {
+	tokenServer := newTestKeysetTokenServer()
+	defer tokenServer.Close()
+
+	api := &testKeysetAPI{}
+	apiServer := httptest.NewServer(api)
+	defer apiServer.Close()
+
+	c := newTestPool(t, tokenServer, RoundRobin, newTestKeyset("A", 0), newTestKeyset("B", 0), newTestKeyset("C", 0)).Client()
+
+	for i := 0; i < 4; i++ {
+		rs, err := c.Get(apiServer.URL)
+		assert.NilError(t, err)
+		rs.Body.Close()
+	}
+
+	assert.DeepEqual(t, api.served, []string{"A", "B", "C", "A"})
+}
+
+func TestIsPoolSpreadingRequestsToLeastUsed(t *testing.T) {
+	tokenServer := newTestKeysetTokenServer()
+	defer tokenServer.Close()
+
+	api := &testKeysetAPI{}
+	apiServer := httptest.NewServer(api)
+	defer apiServer.Close()
+
+	p := newTestPool(t, tokenServer, LeastUsed, newTestKeyset("A", 0), newTestKeyset("B", 0))
+	p.members[0].used = 2
+
+	for i := 0; i < 3; i++ {
+		rs, err := p.Client().Get(apiServer.URL)
+		assert.NilError(t, err)
+		rs.Body.Close()
+	}
+
+	assert.DeepEqual(t, api.served, []string{"B", "B", "A"})
+}
+
+func TestIsPoolRotatingOutRateLimitedKeyset(t *testing.T) {
+	tokenServer := newTestKeysetTokenServer()
+	defer tokenServer.Close()
+
+	api := &testKeysetAPI{limited: map[string]bool{"A": true}, retryAfter: "120"}
+	apiServer := httptest.NewServer(api)
+	defer apiServer.Close()
+
+	now := time.Date(2020, 5, 4, 7, 0, 0, 0, time.UTC)
+	p := newTestPool(t, tokenServer, RoundRobin, newTestKeyset("A", 0), newTestKeyset("B", 0))
+	p.now = func() time.Time { return now }
+
+	// The request is replayed with the keyset B
+	rs, err := p.Client().Get(apiServer.URL)
+	assert.NilError(t, err)
+	rs.Body.Close()
+	assert.Equal(t, rs.StatusCode, http.StatusOK)
+
+	status := p.Status()
+	assert.Assert(t, status[0].AvailableAt.Equal(now.Add(2*time.Minute)))
+	assert.Assert(t, status[1].AvailableAt.IsZero())
+
+	rs, err = p.Client().Get(apiServer.URL)
+	assert.NilError(t, err)
+	rs.Body.Close()
+	assert.DeepEqual(t, api.served, []string{"B", "B"})
+
+	// After the reset time the keyset A is back in rotation
+	api.limited = nil
+	now = now.Add(3 * time.Minute)
+
+	rs, err = p.Client().Get(apiServer.URL)
+	assert.NilError(t, err)
+	rs.Body.Close()
+	assert.DeepEqual(t, api.served, []string{"B", "B", "A"})
+}
+
+func TestIsPoolReturningRateLimitedResponseWhenAllKeysetsLimited(t *testing.T) {
+	tokenServer := newTestKeysetTokenServer()
+	defer tokenServer.Close()
+
+	api := &testKeysetAPI{limited: map[string]bool{"A": true, "B": true}}
+	apiServer := httptest.NewServer(api)
+	defer apiServer.Close()
+
+	p := newTestPool(t, tokenServer, RoundRobin, newTestKeyset("A", 0), newTestKeyset("B", 0))
+
+	rs, err := p.Client().Get(apiServer.URL)
+	assert.NilError(t, err)
+	rs.Body.Close()
+	assert.Equal(t, rs.StatusCode, http.StatusTooManyRequests)
+
+	// Both keysets are now out of rotation for the default cooldown
+	_, err = p.Client().Get(apiServer.URL)
+	assert.ErrorContains(t, err, "all keysets are rate limited or over quota")
+}
+
+func TestIsPoolHonoringQuota(t *testing.T) {
+	tokenServer := newTestKeysetTokenServer()
+	defer tokenServer.Close()
+
+	api := &testKeysetAPI{}
+	apiServer := httptest.NewServer(api)
+	defer apiServer.Close()
+
+	now := time.Date(2020, 5, 4, 7, 0, 0, 0, time.UTC)
+	p := newTestPool(t, tokenServer, RoundRobin, newTestKeyset("A", 1), newTestKeyset("B", 2))
+	p.now = func() time.Time { return now }
+	for _, m := range p.members {
+		m.windowStart = now
+	}
+
+	for i := 0; i < 3; i++ {
+		rs, err := p.Client().Get(apiServer.URL)
+		assert.NilError(t, err)
+		rs.Body.Close()
+	}
+	assert.DeepEqual(t, api.served, []string{"A", "B", "B"})
+
+	_, err := p.Client().Get(apiServer.URL)
+	assert.ErrorContains(t, err, "until 2020-05-05T07:00:00Z")
+
+	// A new window resets the usage
+	now = now.Add(DefaultPoolWindow)
+
+	rs, err := p.Client().Get(apiServer.URL)
+	assert.NilError(t, err)
+	rs.Body.Close()
+	assert.Equal(t, p.Status()[0].Used, int64(1))
+}
+
+func TestIsNewPoolReturningErrorIfNoKeysets(t *testing.T) {
+	_, err := NewPool(context.Background(), PoolConfig{TokenURL: SandBoxEndpoint.TokenURL})
+	assert.Error(t, err, "NewPool(): at least one keyset is required")
+
+	_, err = NewPool(context.Background(), PoolConfig{TokenURL: SandBoxEndpoint.TokenURL, Keysets: []PoolKeyset{{Name: "A", Credentials: NewStaticCredentials("", "")}}})
+	assert.Error(t, err, "NewPool(): keyset A: Client id and client secret are required")
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2020, 5, 4, 7, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Time
+	}{
+		{name: "Are seconds parsed?", value: "30", want: now.Add(30 * time.Second)},
+		{name: "Is HTTP date parsed?", value: "Mon, 04 May 2020 08:00:00 GMT", want: now.Add(time.Hour)},
+		{name: "Is missing header zero?", value: "", want: time.Time{}},
+		{name: "Is invalid header zero?", value: "soon", want: time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := &http.Response{Header: make(http.Header)}
+			if tt.value != "" {
+				rs.Header.Set("Retry-After", tt.value)
+			}
+			assert.Assert(t, retryAfter(rs, now).Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package oauth2
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// eBay oAuth2 scopes. Check for details https://developer.ebay.com/api-docs/static/oauth-scopes.html
+const (
+	// ScopeAPI is the public scope identifier granted to any application, required i.e. by the Developer Analytics API
+	ScopeAPI string = "https://api.ebay.com/oauth/api_scope"
+	// ScopeBuyFeedAPI is the scope identifier required to access to the buy/feed api
+	ScopeBuyFeedAPI string = "https://api.ebay.com/oauth/api_scope/buy.item.feed"
+	// ScopeBuyItemBulk is the scope identifier required to access to the buy/browse api bulk methods
+	ScopeBuyItemBulk string = "https://api.ebay.com/oauth/api_scope/buy.item.bulk"
+	// ScopeBuyMarketing is the scope identifier required to access to the buy/marketing api
+	ScopeBuyMarketing string = "https://api.ebay.com/oauth/api_scope/buy.marketing"
+	// ScopeBuyProductFeed is the scope identifier required to access to the buy/feed product methods
+	ScopeBuyProductFeed string = "https://api.ebay.com/oauth/api_scope/buy.product.feed"
+	// ScopeBuyMarketplaceInsights is the scope identifier required to access to the buy/marketplace_insights api
+	ScopeBuyMarketplaceInsights string = "https://api.ebay.com/oauth/api_scope/buy.marketplace.insights"
+	// ScopeBuyGuestOrder is the scope identifier required to access to the buy/order api guest checkout
+	ScopeBuyGuestOrder string = "https://api.ebay.com/oauth/api_scope/buy.guest.order"
+	// ScopeBuyOrder is the scope identifier required to access to the buy/order api member checkout (user access token)
+	ScopeBuyOrder string = "https://api.ebay.com/oauth/api_scope/buy.order"
+	// ScopeCommerceCatalogReadOnly is the scope identifier required to access to the commerce/catalog api
+	ScopeCommerceCatalogReadOnly string = "https://api.ebay.com/oauth/api_scope/commerce.catalog.readonly"
+	// ScopeCommerceIdentityReadOnly is the scope identifier required to access to the commerce/identity api (user access token)
+	ScopeCommerceIdentityReadOnly string = "https://api.ebay.com/oauth/api_scope/commerce.identity.readonly"
+)
+
+// RequestedScopes returns the scopes requested for the tokens of an HTTP client created by this package.
+// eBay fails the token requests when a scope cannot be granted, so the tokens are granted the requested scopes.
+// It returns false if the client was not created by this package, in which case the scopes are unknown.
+func RequestedScopes(httpClient *http.Client) ([]string, bool) {
+	if httpClient == nil {
+		return nil, false
+	}
+
+	switch t := httpClient.Transport.(type) {
+	case *Transport:
+		return t.Scopes, t.Scopes != nil
+	case *Pool:
+		return t.scopes, t.scopes != nil
+	}
+	return nil, false
+}
+
+// MissingScopes returns the required scopes which are not in requested
+func MissingScopes(requested, required []string) []string {
+	has := make(map[string]bool, len(requested))
+	for _, s := range requested {
+		has[s] = true
+	}
+
+	var missing []string
+	for _, s := range required {
+		if !has[s] {
+			missing = append(missing, s)
+		}
+	}
+	return missing
+}
+
+// CheckScopes returns an error if the HTTP client tokens are not requested with all the required scopes.
+// No error is returned if the client scopes are unknown (see RequestedScopes).
+func CheckScopes(httpClient *http.Client, required []string) error {
+	requested, ok := RequestedScopes(httpClient)
+	if !ok {
+		return nil
+	}
+
+	if missing := MissingScopes(requested, required); len(missing) > 0 {
+		return fmt.Errorf("CheckScopes(): client is missing the required scopes: %v", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package oauth2
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestMissingScopes(t *testing.T) {
+	missing := MissingScopes([]string{ScopeAPI, ScopeBuyFeedAPI}, []string{ScopeBuyFeedAPI, ScopeBuyItemBulk, ScopeBuyMarketing})
+	assert.DeepEqual(t, missing, []string{ScopeBuyItemBulk, ScopeBuyMarketing})
+
+	assert.Assert(t, MissingScopes([]string{ScopeAPI}, nil) == nil)
+}
+
+func TestIsRequestedScopesReadFromClient(t *testing.T) {
+	c, err := NewClientCredentialsClient(context.Background(), NewStaticCredentials("ID", "SECRET"), SandBoxEndpoint.TokenURL, []string{ScopeBuyFeedAPI})
+	assert.NilError(t, err)
+
+	scopes, ok := RequestedScopes(c)
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, scopes, []string{ScopeBuyFeedAPI})
+
+	p, err := NewPool(context.Background(), PoolConfig{
+		TokenURL: SandBoxEndpoint.TokenURL,
+		Scopes:   []string{ScopeBuyItemBulk},
+		Keysets:  []PoolKeyset{{Name: "A", Credentials: NewStaticCredentials("ID", "SECRET")}},
+	})
+	assert.NilError(t, err)
+
+	scopes, ok = RequestedScopes(p.Client())
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, scopes, []string{ScopeBuyItemBulk})
+
+	_, ok = RequestedScopes(http.DefaultClient)
+	assert.Assert(t, !ok)
+}
+
+func TestIsCheckScopesReturningErrorIfScopeMissing(t *testing.T) {
+	c, err := NewClientCredentialsClient(context.Background(), NewStaticCredentials("ID", "SECRET"), SandBoxEndpoint.TokenURL, []string{ScopeAPI})
+	assert.NilError(t, err)
+
+	err = CheckScopes(c, []string{ScopeBuyFeedAPI, ScopeAPI})
+	assert.Error(t, err, "CheckScopes(): client is missing the required scopes: "+ScopeBuyFeedAPI)
+
+	assert.NilError(t, CheckScopes(c, []string{ScopeAPI}))
+	assert.NilError(t, CheckScopes(http.DefaultClient, []string{ScopeBuyFeedAPI}))
+}
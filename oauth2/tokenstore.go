@@ -0,0 +1,336 @@
+package oauth2
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	// tokenExpiryLeeway is how long before its expiry a stored token stops being reused
+	tokenExpiryLeeway = 5 * time.Minute
+
+	// lockRetryInterval is the interval between two attempts to acquire a file lock
+	lockRetryInterval = 50 * time.Millisecond
+	// lockTimeout is the maximum time spent waiting for a file lock
+	lockTimeout = 30 * time.Second
+	// lockStaleAfter is the age after which a file lock is considered abandoned by a crashed process
+	lockStaleAfter = time.Minute
+)
+
+// TokenStore persists the access tokens so that they can be shared across HTTP clients and processes.
+// Tokens are identified by a key which is unique for each client id, token URL and scopes combination.
+type TokenStore interface {
+	// Load returns the token stored with the given key or nil if no token is stored
+	Load(key string) (*oauth2.Token, error)
+	// Save stores the token with the given key
+	Save(key string, tk *oauth2.Token) error
+	// Lock acquires the exclusive access to the token with the given key. The returned function releases it.
+	Lock(key string) (func(), error)
+}
+
+// MemoryTokenStore is a TokenStore keeping the tokens in memory, shared by the HTTP clients of the same process.
+type MemoryTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]oauth2.Token
+	locks  map[string]*sync.Mutex
+}
+
+// NewMemoryTokenStore creates a new empty MemoryTokenStore
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{
+		tokens: make(map[string]oauth2.Token),
+		locks:  make(map[string]*sync.Mutex),
+	}
+}
+
+// Load returns the token stored with the given key or nil if no token is stored
+func (m *MemoryTokenStore) Load(key string) (*oauth2.Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tk, ok := m.tokens[key]
+	if !ok {
+		return nil, nil
+	}
+	return &tk, nil
+}
+
+// Save stores the token with the given key
+func (m *MemoryTokenStore) Save(key string, tk *oauth2.Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tokens[key] = *tk
+	return nil
+}
+
+// Lock acquires the exclusive access to the token with the given key
+func (m *MemoryTokenStore) Lock(key string) (func(), error) {
+	m.mu.Lock()
+	l, ok := m.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		m.locks[key] = l
+	}
+	m.mu.Unlock()
+
+	l.Lock()
+	return l.Unlock, nil
+}
+
+// FileTokenStore is a TokenStore keeping each token in a JSON file readable only by the owner.
+// Processes sharing the same directory share the tokens; a lock file prevents them to mint a new token at the same time.
+type FileTokenStore struct {
+	// Dir is the directory where the tokens are stored
+	Dir string
+}
+
+// NewFileTokenStore creates a new FileTokenStore storing the tokens in the given directory.
+// The directory is created if it does not exist.
+func NewFileTokenStore(dir string) (*FileTokenStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("NewFileTokenStore(): cannot create %v: %v", dir, err)
+	}
+	return &FileTokenStore{Dir: dir}, nil
+}
+
+// DefaultTokenStoreDir returns the default directory for the FileTokenStore in the user cache directory
+func DefaultTokenStoreDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("DefaultTokenStoreDir(): %v", err)
+	}
+	return filepath.Join(dir, "ebay-api-client", "tokens"), nil
+}
+
+// Load returns the token stored with the given key or nil if no token is stored
+func (f *FileTokenStore) Load(key string) (*oauth2.Token, error) {
+	data, err := ioutil.ReadFile(f.path(key, ".json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Load(): cannot read token: %v", err)
+	}
+
+	tk := &oauth2.Token{}
+	if err := json.Unmarshal(data, tk); err != nil {
+		return nil, fmt.Errorf("Load(): cannot decode token: %v", err)
+	}
+	return tk, nil
+}
+
+// Save stores the token with the given key. The file is replaced atomically so that readers never see a partial token.
+func (f *FileTokenStore) Save(key string, tk *oauth2.Token) error {
+	data, err := json.Marshal(tk)
+	if err != nil {
+		return fmt.Errorf("Save(): cannot encode token: %v", err)
+	}
+
+	tmp, err := ioutil.TempFile(f.Dir, key+".tmp")
+	if err != nil {
+		return fmt.Errorf("Save(): cannot create token file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Save(): cannot restrict token file permissions: %v", err)
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Save(): cannot write token file: %v", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Save(): cannot write token file: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), f.path(key, ".json")); err != nil {
+		return fmt.Errorf("Save(): cannot write token file: %v", err)
+	}
+	return nil
+}
+
+// Lock acquires the exclusive access to the token with the given key by creating a lock file holding a random
+// owner nonce. Lock files older than one minute are considered abandoned and removed. A lock file is only removed
+// if it still holds the nonce of its owner, so that a late owner never removes the lock acquired by another one.
+func (f *FileTokenStore) Lock(key string) (func(), error) {
+	lockPath := f.path(key, ".lock")
+	deadline := time.Now().Add(lockTimeout)
+
+	nonce, err := newLockNonce()
+	if err != nil {
+		return nil, fmt.Errorf("Lock(): cannot create lock nonce: %v", err)
+	}
+
+	for {
+		lock, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_, err = lock.WriteString(nonce)
+			if cerr := lock.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(lockPath)
+				return nil, fmt.Errorf("Lock(): cannot write lock file: %v", err)
+			}
+			return func() { removeLock(lockPath, nonce, nonce, false) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("Lock(): cannot create lock file: %v", err)
+		}
+
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > lockStaleAfter {
+			if owner, err := ioutil.ReadFile(lockPath); err == nil {
+				removeLock(lockPath, string(owner), nonce, true)
+			}
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Lock(): timeout waiting for %v", lockPath)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// newLockNonce returns a random value identifying the owner of a lock file
+func newLockNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// removeLock removes the lock file if it holds the owner nonce, and if it is still abandoned when abandoned is set.
+// The file is first moved to a name unique to the caller (claimer), so that checking and removing it is atomic:
+// a lock file replaced meanwhile by another owner is moved back instead of being removed.
+func removeLock(path, owner, claimer string, abandoned bool) {
+	claimed := path + "." + claimer
+	if err := os.Rename(path, claimed); err != nil {
+		return
+	}
+	defer os.Remove(claimed)
+
+	data, err := ioutil.ReadFile(claimed)
+	if err == nil && string(data) == owner {
+		info, err := os.Stat(claimed)
+		if !abandoned || (err == nil && time.Since(info.ModTime()) > lockStaleAfter) {
+			return
+		}
+	}
+
+	// Link does not replace a lock file created in the meanwhile
+	os.Link(claimed, path)
+}
+
+func (f *FileTokenStore) path(key, ext string) string {
+	return filepath.Join(f.Dir, key+ext)
+}
+
+// TokenKey returns the key identifying the tokens minted for the given client id, token URL and scopes.
+// The key does not disclose the client id and it is safe to be used as file name.
+func TokenKey(clientID, tokenURL string, scopes []string) string {
+	sorted := append([]string(nil), scopes...)
+	sort.Strings(sorted)
+
+	h := sha256.Sum256([]byte(clientID + "\n" + tokenURL + "\n" + strings.Join(sorted, " ")))
+	return hex.EncodeToString(h[:16])
+}
+
+// cachedTokenSource reuses the token found in the TokenStore until shortly before its expiry,
+// then mints a new one through the wrapped token source and saves it into the store.
+type cachedTokenSource struct {
+	key   string
+	store TokenStore
+	orig  oauth2.TokenSource
+
+	mu sync.Mutex
+	tk *oauth2.Token
+	// rejected is the last access token rejected by eBay, which must not be loaded again from the store
+	rejected string
+}
+
+func newCachedTokenSource(key string, store TokenStore, orig oauth2.TokenSource) *cachedTokenSource {
+	return &cachedTokenSource{
+		key:   key,
+		store: store,
+		orig:  orig,
+	}
+}
+
+func (c *cachedTokenSource) Token() (*oauth2.Token, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if isReusable(c.tk) {
+		return c.tk, nil
+	}
+
+	unlock, err := c.store.Lock(c.key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Another client or process may have minted a new token in the meanwhile
+	tk, err := c.store.Load(c.key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isReusable(tk) || tk.AccessToken == c.rejected {
+		tk, err = c.orig.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := c.store.Save(c.key, tk); err != nil {
+			return nil, err
+		}
+	}
+
+	c.tk = tk
+	return tk, nil
+}
+
+// invalidate drops the rejected token so that the next call to Token mints a new one.
+// The rejection of a token which is not the cached one anymore is ignored, it was already replaced.
+func (c *cachedTokenSource) invalidate(rejected *oauth2.Token) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if rejected == nil || c.tk == nil || rejected.AccessToken != c.tk.AccessToken {
+		return
+	}
+
+	c.tk = nil
+	c.rejected = rejected.AccessToken
+
+	if orig, ok := c.orig.(refreshableTokenSource); ok {
+		orig.invalidate(rejected)
+	}
+}
+
+// isReusable reports whether the token is valid and not about to expire
+func isReusable(tk *oauth2.Token) bool {
+	if tk == nil || tk.AccessToken == "" {
+		return false
+	}
+	return tk.Expiry.IsZero() || time.Now().Add(tokenExpiryLeeway).Before(tk.Expiry)
+}
@@ -0,0 +1,217 @@
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	"gotest.tools/assert"
+)
+
+// newTestMintingServer is a stand-in for eBay oAuth2 token endpoint counting the minted tokens
+func newTestMintingServer(minted *int32, expiresIn int) *httptest.Server {
+	apiHandler := http.NewServeMux()
+	apiHandler.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(minted, 1)
+		w.Header().Add("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "ACCESS-%d", "expires_in": %d, "token_type": "Application Access Token"}`, n, expiresIn)
+	})
+	return httptest.NewServer(apiHandler)
+}
+
+func newTestFileTokenStore(t *testing.T) *FileTokenStore {
+	dir, err := ioutil.TempDir("", "tokens")
+	assert.NilError(t, err)
+
+	store, err := NewFileTokenStore(filepath.Join(dir, "store"))
+	assert.NilError(t, err)
+	return store
+}
+
+func TestIsMemoryTokenStoreSavingToken(t *testing.T) {
+	store := NewMemoryTokenStore()
+
+	tk, err := store.Load("key")
+	assert.NilError(t, err)
+	assert.Assert(t, tk == nil)
+
+	assert.NilError(t, store.Save("key", &oauth2.Token{AccessToken: "ACCESS"}))
+
+	tk, err = store.Load("key")
+	assert.NilError(t, err)
+	assert.Equal(t, tk.AccessToken, "ACCESS")
+}
+
+func TestIsFileTokenStoreSavingTokenWithRestrictedPermissions(t *testing.T) {
+	store := newTestFileTokenStore(t)
+	defer os.RemoveAll(filepath.Dir(store.Dir))
+
+	tk, err := store.Load("key")
+	assert.NilError(t, err)
+	assert.Assert(t, tk == nil)
+
+	expiry := time.Now().Add(time.Hour).Round(time.Second)
+	assert.NilError(t, store.Save("key", &oauth2.Token{AccessToken: "ACCESS", TokenType: "bearer", Expiry: expiry}))
+
+	info, err := os.Stat(filepath.Join(store.Dir, "key.json"))
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0600))
+
+	tk, err = store.Load("key")
+	assert.NilError(t, err)
+	assert.Equal(t, tk.AccessToken, "ACCESS")
+	assert.Assert(t, tk.Expiry.Equal(expiry))
+}
+
+func TestIsFileTokenStoreLockExclusive(t *testing.T) {
+	store := newTestFileTokenStore(t)
+	defer os.RemoveAll(filepath.Dir(store.Dir))
+
+	unlock, err := store.Lock("key")
+	assert.NilError(t, err)
+
+	acquired := make(chan struct{})
+	go func() {
+		unlock, err := store.Lock("key")
+		if err == nil {
+			unlock()
+		}
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("lock acquired twice")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	unlock()
+	<-acquired
+}
+
+func TestIsFileTokenStoreRemovingStaleLock(t *testing.T) {
+	store := newTestFileTokenStore(t)
+	defer os.RemoveAll(filepath.Dir(store.Dir))
+
+	lockPath := filepath.Join(store.Dir, "key.lock")
+	assert.NilError(t, ioutil.WriteFile(lockPath, nil, 0600))
+
+	stale := time.Now().Add(-2 * lockStaleAfter)
+	assert.NilError(t, os.Chtimes(lockPath, stale, stale))
+
+	unlock, err := store.Lock("key")
+	assert.NilError(t, err)
+	unlock()
+}
+
+func TestIsFileTokenStoreUnlockKeepingLockOfAnotherOwner(t *testing.T) {
+	store := newTestFileTokenStore(t)
+	defer os.RemoveAll(filepath.Dir(store.Dir))
+
+	unlock, err := store.Lock("key")
+	assert.NilError(t, err)
+
+	// The lock has been considered abandoned and acquired by another process
+	lockPath := filepath.Join(store.Dir, "key.lock")
+	assert.NilError(t, ioutil.WriteFile(lockPath, []byte("other"), 0600))
+
+	unlock()
+	data, err := ioutil.ReadFile(lockPath)
+	assert.NilError(t, err)
+	assert.Equal(t, string(data), "other")
+
+	files, err := ioutil.ReadDir(store.Dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(files), 1)
+}
+
+func TestIsFileTokenStoreKeepingLockRefreshedMeanwhile(t *testing.T) {
+	store := newTestFileTokenStore(t)
+	defer os.RemoveAll(filepath.Dir(store.Dir))
+
+	// The lock file is found abandoned, but replaced by a new owner before being removed
+	lockPath := filepath.Join(store.Dir, "key.lock")
+	assert.NilError(t, ioutil.WriteFile(lockPath, []byte("owner"), 0600))
+
+	removeLock(lockPath, "previous owner", "claimer", true)
+	data, err := ioutil.ReadFile(lockPath)
+	assert.NilError(t, err)
+	assert.Equal(t, string(data), "owner")
+
+	removeLock(lockPath, "owner", "claimer", true)
+	_, err = os.Stat(lockPath)
+	assert.NilError(t, err)
+
+	removeLock(lockPath, "owner", "claimer", false)
+	_, err = os.Stat(lockPath)
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func TestIsTokenKeyIndependentFromScopesOrder(t *testing.T) {
+	k1 := TokenKey("ID", SandBoxEndpoint.TokenURL, []string{"a", "b"})
+	k2 := TokenKey("ID", SandBoxEndpoint.TokenURL, []string{"b", "a"})
+	k3 := TokenKey("ID", ProdEndpoint.TokenURL, []string{"a", "b"})
+
+	assert.Equal(t, k1, k2)
+	assert.Assert(t, k1 != k3)
+}
+
+func TestIsCachedClientSharingTokenAcrossClients(t *testing.T) {
+	var minted int32
+	server := newTestMintingServer(&minted, 7200)
+	defer server.Close()
+
+	store := newTestFileTokenStore(t)
+	defer os.RemoveAll(filepath.Dir(store.Dir))
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, server.Client())
+	creds := NewStaticCredentials("EBAY_CLIENT_ID", "EBAY_CLIENT_SECRET")
+
+	// Each client simulates a different short lived process sharing the same store
+	for i := 0; i < 3; i++ {
+		c, err := NewCachedClientCredentialsClient(ctx, creds, server.URL+"/token", []string{ScopeBuyFeedAPI}, store)
+		assert.NilError(t, err)
+
+		tk, err := c.Transport.(*Transport).Source.Token()
+		assert.NilError(t, err)
+		assert.Equal(t, tk.AccessToken, "ACCESS-1")
+		assert.Equal(t, tk.TokenType, "bearer")
+	}
+
+	assert.Equal(t, atomic.LoadInt32(&minted), int32(1))
+}
+
+func TestIsCachedTokenSourceMintingTokenCloseToExpiry(t *testing.T) {
+	var minted int32
+
+	// Tokens expiring within the leeway are never reused
+	server := newTestMintingServer(&minted, int(tokenExpiryLeeway.Seconds())-1)
+	defer server.Close()
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, server.Client())
+	conf := &clientcredentials.Config{
+		ClientID:     "EBAY_API_CLIENT_ID",
+		ClientSecret: "EBAY_API_CLIENT_SECRET",
+		TokenURL:     server.URL + "/token",
+		AuthStyle:    oauth2.AuthStyleInHeader,
+	}
+
+	ts := newCachedTokenSource("key", NewMemoryTokenStore(), &tokenSource{ctx: ctx, conf: conf, orig: mintTokenSource{ctx: ctx, conf: conf}})
+
+	tk, err := ts.Token()
+	assert.NilError(t, err)
+	assert.Equal(t, tk.AccessToken, "ACCESS-1")
+
+	tk, err = ts.Token()
+	assert.NilError(t, err)
+	assert.Equal(t, tk.AccessToken, "ACCESS-2")
+}
@@ -0,0 +1,166 @@
+package oauth2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// invalidTokenErrors are the eBay error ids reporting that the access token was not accepted
+// Check for details https://developer.ebay.com/api-docs/static/handling-error-messages.html
+var invalidTokenErrors = map[int]bool{
+	1001: true, // Invalid access token
+	1002: true, // Missing access token
+}
+
+// refreshableTokenSource is a TokenSource which can be forced to drop a rejected token and get a new one
+type refreshableTokenSource interface {
+	oauth2.TokenSource
+	invalidate(rejected *oauth2.Token)
+}
+
+// Transport is an http.RoundTripper authorizing the requests with the tokens given by Source.
+// Differently from golang.org/x/oauth2 Transport, when eBay rejects the token with 401 and one of the
+// invalid token error ids (i.e. the token was revoked or expired early), the token is refreshed and the request
+// is replayed once. The request is replayed only if it is idempotent and has no body, or if its body can be replayed (GetBody is set).
+type Transport struct {
+	// Source supplies the tokens. Only the token sources created by this package can be refreshed on demand.
+	Source oauth2.TokenSource
+	// Base is the underlying RoundTripper. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// Scopes are the scopes requested for the tokens, checked against the services requirements. Nil means unknown.
+	Scopes []string
+}
+
+// RoundTrip authorizes and sends the request, replaying it once with a refreshed token if the token is rejected
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tk, err := t.Source.Token()
+	if err != nil {
+		closeRequestBody(req)
+		return nil, err
+	}
+
+	rs, err := t.base().RoundTrip(authorize(req, tk))
+	if err != nil || !isInvalidTokenResponse(rs) {
+		return rs, err
+	}
+
+	source, ok := t.Source.(refreshableTokenSource)
+	if !ok || !isReplayable(req) {
+		return rs, nil
+	}
+
+	replay, err := replayRequest(req)
+	if err != nil {
+		return rs, nil
+	}
+
+	source.invalidate(tk)
+
+	tk, err = source.Token()
+	if err != nil {
+		closeRequestBody(replay)
+		return rs, nil
+	}
+
+	io.Copy(ioutil.Discard, rs.Body)
+	rs.Body.Close()
+
+	return t.base().RoundTrip(authorize(replay, tk))
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// authorize returns a copy of the request with the Authorization header set, as a RoundTripper must not modify the request
+func authorize(req *http.Request, tk *oauth2.Token) *http.Request {
+	r := req.Clone(req.Context())
+	tk.SetAuthHeader(r)
+	return r
+}
+
+// isReplayable reports whether the request can be sent again
+func isReplayable(req *http.Request) bool {
+	if req.GetBody != nil {
+		return true
+	}
+
+	noBody := req.Body == nil || req.Body == http.NoBody
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return noBody
+	}
+	return false
+}
+
+// replayRequest returns a copy of the request with a fresh body
+func replayRequest(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("replayRequest(): cannot replay request body: %v", err)
+		}
+		r.Body = body
+	}
+	return r, nil
+}
+
+// isInvalidTokenResponse reports whether eBay rejected the request access token.
+func isInvalidTokenResponse(rs *http.Response) bool {
+	if rs.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+
+	for _, id := range responseErrorIDs(rs) {
+		if invalidTokenErrors[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// responseErrorIDs returns the ids of the errors reported in the eBay error response payload.
+// The response body is read and restored so that it can still be consumed by the caller.
+func responseErrorIDs(rs *http.Response) []int {
+	if rs.Body == nil {
+		return nil
+	}
+
+	data, err := ioutil.ReadAll(rs.Body)
+	rs.Body.Close()
+	rs.Body = ioutil.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+
+	payload := struct {
+		Errors []struct {
+			ErrorID int `json:"errorId"`
+		} `json:"errors"`
+	}{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil
+	}
+
+	ids := make([]int, 0, len(payload.Errors))
+	for _, e := range payload.Errors {
+		ids = append(ids, e.ErrorID)
+	}
+	return ids
+}
+
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
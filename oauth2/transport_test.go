@@ -0,0 +1,228 @@
+package oauth2
+
+import (
+	"context"
+	"ebay-api-client/oauth2/oauth2test"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/oauth2"
+	"gotest.tools/assert"
+)
+
+const (
+	invalidTokenResponse = `{"errors": [{"errorId": 1001, "domain": "OAuth", "category": "REQUEST", "message": "Invalid access token"}]}`
+	accessDeniedResponse = `{"errors": [{"errorId": 1100, "domain": "ACCESS", "category": "REQUEST", "message": "Access denied"}]}`
+)
+
+// newTestAPIServer is a stand-in for an eBay API rejecting the given access token with the given payload.
+// The bodies of the accepted requests are appended to the given list.
+func newTestAPIServer(rejectedToken, rejectPayload string, bodies *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer "+rejectedToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, rejectPayload)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		*bodies = append(*bodies, string(body))
+		fmt.Fprint(w, "OK")
+	}))
+}
+
+func newTestTransportClient(t *testing.T, tokenServer *httptest.Server) *http.Client {
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, tokenServer.Client())
+	c, err := NewClientCredentialsClient(ctx, NewStaticCredentials("EBAY_API_CLIENT_ID", "EBAY_API_CLIENT_SECRET"), tokenServer.URL+"/token", []string{ScopeBuyFeedAPI})
+	assert.NilError(t, err)
+	return c
+}
+
+func TestIsTransportRefreshingInvalidTokenAndReplayingGet(t *testing.T) {
+	var minted int32
+	tokenServer := newTestMintingServer(&minted, 7200)
+	defer tokenServer.Close()
+
+	var bodies []string
+	apiServer := newTestAPIServer("ACCESS-1", invalidTokenResponse, &bodies)
+	defer apiServer.Close()
+
+	rs, err := newTestTransportClient(t, tokenServer).Get(apiServer.URL)
+	assert.NilError(t, err)
+	defer rs.Body.Close()
+
+	assert.Equal(t, rs.StatusCode, http.StatusOK)
+	assert.Equal(t, atomic.LoadInt32(&minted), int32(2))
+	assert.Equal(t, len(bodies), 1)
+}
+
+func TestIsTransportReplayingRequestWithReplayableBody(t *testing.T) {
+	var minted int32
+	tokenServer := newTestMintingServer(&minted, 7200)
+	defer tokenServer.Close()
+
+	var bodies []string
+	apiServer := newTestAPIServer("ACCESS-1", invalidTokenResponse, &bodies)
+	defer apiServer.Close()
+
+	rs, err := newTestTransportClient(t, tokenServer).Post(apiServer.URL, "application/json", strings.NewReader(`{"itemId": "1"}`))
+	assert.NilError(t, err)
+	defer rs.Body.Close()
+
+	assert.Equal(t, rs.StatusCode, http.StatusOK)
+	assert.DeepEqual(t, bodies, []string{`{"itemId": "1"}`})
+}
+
+func TestIsTransportNotReplayingRequestWithOneShotBody(t *testing.T) {
+	var minted int32
+	tokenServer := newTestMintingServer(&minted, 7200)
+	defer tokenServer.Close()
+
+	var bodies []string
+	apiServer := newTestAPIServer("ACCESS-1", invalidTokenResponse, &bodies)
+	defer apiServer.Close()
+
+	// Wrapping the reader hides it from http.NewRequest which cannot set GetBody anymore
+	body := ioutil.NopCloser(strings.NewReader(`{"itemId": "1"}`))
+
+	rs, err := newTestTransportClient(t, tokenServer).Post(apiServer.URL, "application/json", body)
+	assert.NilError(t, err)
+	defer rs.Body.Close()
+
+	assert.Equal(t, rs.StatusCode, http.StatusUnauthorized)
+	assert.Equal(t, atomic.LoadInt32(&minted), int32(1))
+
+	// The error payload is still readable by the caller
+	payload, err := ioutil.ReadAll(rs.Body)
+	assert.NilError(t, err)
+	assert.Equal(t, string(payload), invalidTokenResponse)
+}
+
+func TestIsTransportNotReplayingOtherUnauthorizedErrors(t *testing.T) {
+	var minted int32
+	tokenServer := newTestMintingServer(&minted, 7200)
+	defer tokenServer.Close()
+
+	var bodies []string
+	apiServer := newTestAPIServer("ACCESS-1", accessDeniedResponse, &bodies)
+	defer apiServer.Close()
+
+	rs, err := newTestTransportClient(t, tokenServer).Get(apiServer.URL)
+	assert.NilError(t, err)
+	defer rs.Body.Close()
+
+	assert.Equal(t, rs.StatusCode, http.StatusUnauthorized)
+	assert.Equal(t, atomic.LoadInt32(&minted), int32(1))
+}
+
+func TestIsTransportReplayingOnlyOnce(t *testing.T) {
+	var minted int32
+	tokenServer := newTestMintingServer(&minted, 7200)
+	defer tokenServer.Close()
+
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, invalidTokenResponse)
+	}))
+	defer apiServer.Close()
+
+	rs, err := newTestTransportClient(t, tokenServer).Get(apiServer.URL)
+	assert.NilError(t, err)
+	defer rs.Body.Close()
+
+	assert.Equal(t, rs.StatusCode, http.StatusUnauthorized)
+	assert.Equal(t, atomic.LoadInt32(&minted), int32(2))
+}
+
+func TestIsCachedTransportNotReloadingRejectedToken(t *testing.T) {
+	var minted int32
+	tokenServer := newTestMintingServer(&minted, 7200)
+	defer tokenServer.Close()
+
+	var bodies []string
+	apiServer := newTestAPIServer("ACCESS-1", invalidTokenResponse, &bodies)
+	defer apiServer.Close()
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, tokenServer.Client())
+	store := NewMemoryTokenStore()
+
+	c, err := NewCachedClientCredentialsClient(ctx, NewStaticCredentials("EBAY_API_CLIENT_ID", "EBAY_API_CLIENT_SECRET"), tokenServer.URL+"/token", []string{ScopeBuyFeedAPI}, store)
+	assert.NilError(t, err)
+
+	rs, err := c.Get(apiServer.URL)
+	assert.NilError(t, err)
+	defer rs.Body.Close()
+
+	assert.Equal(t, rs.StatusCode, http.StatusOK)
+
+	tk, err := store.Load(TokenKey("EBAY_API_CLIENT_ID", tokenServer.URL+"/token", []string{ScopeBuyFeedAPI}))
+	assert.NilError(t, err)
+	assert.Equal(t, tk.AccessToken, "ACCESS-2")
+}
+
+func TestIsTransportMintingOnceOnConcurrentInvalidTokens(t *testing.T) {
+	tokens := oauth2test.NewTokenServer()
+	defer tokens.Close()
+	tokens.AddClient("EBAY_API_CLIENT_ID", "EBAY_API_CLIENT_SECRET", ScopeBuyFeedAPI)
+
+	// The rejected requests are answered once all of them are received, so that all are sent with the same token
+	const parallel = 8
+	var rejected sync.WaitGroup
+	rejected.Add(parallel)
+	var mu sync.Mutex
+	var used []string
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		used = append(used, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		mu.Unlock()
+
+		if !tokens.Authorize(r, ScopeBuyFeedAPI) {
+			rejected.Done()
+			rejected.Wait()
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, invalidTokenResponse)
+			return
+		}
+		fmt.Fprint(w, "OK")
+	}))
+	defer apiServer.Close()
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, tokens.Client())
+	c, err := NewClientCredentialsClient(ctx, NewStaticCredentials("EBAY_API_CLIENT_ID", "EBAY_API_CLIENT_SECRET"), tokens.TokenURL(), []string{ScopeBuyFeedAPI})
+	assert.NilError(t, err)
+
+	rs, err := c.Get(apiServer.URL)
+	assert.NilError(t, err)
+	rs.Body.Close()
+	assert.Equal(t, tokens.Requests(), 1)
+	tokens.Revoke(used[0])
+
+	var wg sync.WaitGroup
+	statuses := make(chan int, parallel)
+	for n := 0; n < parallel; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rs, err := c.Get(apiServer.URL)
+			if err != nil {
+				statuses <- 0
+				return
+			}
+			rs.Body.Close()
+			statuses <- rs.StatusCode
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	for status := range statuses {
+		assert.Equal(t, status, http.StatusOK)
+	}
+	assert.Equal(t, tokens.Requests(), 2)
+}
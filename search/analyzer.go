@@ -0,0 +1,113 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Language is the language of the items of a marketplace, it selects the stop words and the normalization of the tokens
+type Language string
+
+// Supported languages
+const (
+	English Language = "en"
+	German  Language = "de"
+	French  Language = "fr"
+	Italian Language = "it"
+	Spanish Language = "es"
+)
+
+// marketLanguages are the languages of the eBay marketplaces, the others are English
+var marketLanguages = map[string]Language{
+	"EBAY_DE": German,
+	"EBAY_AT": German,
+	"EBAY_CH": German,
+	"EBAY_FR": French,
+	"EBAY_BE": French,
+	"EBAY_IT": Italian,
+	"EBAY_ES": Spanish,
+}
+
+// MarketLanguage returns the language of the eBay marketplace, i.e. German for EBAY_DE. It defaults to English.
+func MarketLanguage(marketID string) Language {
+	if l, ok := marketLanguages[strings.ToUpper(marketID)]; ok {
+		return l
+	}
+	return English
+}
+
+// stopWords are the words not indexed, by language
+var stopWords = map[Language][]string{
+	English: {"a", "an", "and", "for", "in", "of", "on", "or", "the", "to", "with"},
+	German:  {"der", "die", "das", "den", "dem", "des", "ein", "eine", "einen", "und", "oder", "mit", "fuer", "von", "zu", "im", "in"},
+	French:  {"le", "la", "les", "l", "un", "une", "des", "du", "de", "d", "et", "ou", "pour", "avec", "en", "a", "au", "aux"},
+	Italian: {"il", "lo", "la", "i", "gli", "le", "l", "un", "una", "uno", "di", "da", "e", "o", "per", "con", "in", "del", "della"},
+	Spanish: {"el", "la", "los", "las", "un", "una", "unos", "unas", "de", "del", "y", "o", "para", "con", "en", "al"},
+}
+
+// foldings are the replacements of the letters with diacritics, applied after lower casing
+var foldings = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'å': "a", 'ā': "a",
+	'ç': "c", 'č': "c",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i",
+	'ñ': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ø': "o",
+	'ù': "u", 'ú': "u", 'û': "u",
+	'ý': "y", 'ÿ': "y",
+	'š': "s", 'ž': "z",
+	'æ': "ae", 'œ': "oe", 'ß': "ss",
+}
+
+// germanFoldings are the German spellings of the umlauts
+var germanFoldings = map[rune]string{'ä': "ae", 'ö': "oe", 'ü': "ue"}
+
+// Analyzer splits a text into normalized tokens: lower case, without diacritics, without the stop words of the
+// language and, in English, without the plural s.
+type Analyzer struct {
+	Language Language
+	stop     map[string]bool
+}
+
+// NewAnalyzer creates a new Analyzer of the given language
+func NewAnalyzer(l Language) *Analyzer {
+	stop := make(map[string]bool)
+	for _, w := range stopWords[l] {
+		stop[w] = true
+	}
+	return &Analyzer{Language: l, stop: stop}
+}
+
+// Tokens returns the tokens of the text, in order, repeated tokens included
+func (a *Analyzer) Tokens(text string) []string {
+	var tokens []string
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		token := a.normalize(word)
+		if token == "" || a.stop[token] {
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func (a *Analyzer) normalize(word string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(word) {
+		if f, ok := germanFoldings[r]; ok && a.Language == German {
+			b.WriteString(f)
+		} else if f, ok := foldings[r]; ok {
+			b.WriteString(f)
+		} else if f, ok := germanFoldings[r]; ok {
+			b.WriteString(f[:1])
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	token := b.String()
+
+	if a.Language == English && len(token) > 3 && strings.HasSuffix(token, "s") && !strings.HasSuffix(token, "ss") {
+		token = strings.TrimSuffix(token, "s")
+	}
+	return token
+}
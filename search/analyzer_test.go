@@ -0,0 +1,33 @@
+package search
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func Test_IsAnalyzerNormalizingTokens(t *testing.T) {
+	tests := []struct {
+		language Language
+		text     string
+		want     []string
+	}{
+		{English, "The Red Phones, with 2 Cases!", []string{"red", "phone", "2", "case"}},
+		{English, "Glass bus", []string{"glass", "bus"}},
+		{German, "Schöne Tasche für die Straße", []string{"schoene", "tasche", "strasse"}},
+		{French, "Téléphone à clapet de l'été", []string{"telephone", "clapet", "ete"}},
+		{Italian, "Borsa della nonna", []string{"borsa", "nonna"}},
+		{Spanish, "Teléfono para niños", []string{"telefono", "ninos"}},
+		{English, "Schöne", []string{"schone"}},
+	}
+	for _, tt := range tests {
+		assert.DeepEqual(t, NewAnalyzer(tt.language).Tokens(tt.text), tt.want)
+	}
+}
+
+func Test_IsMarketLanguageMappingMarketplaces(t *testing.T) {
+	assert.Equal(t, MarketLanguage("EBAY_DE"), German)
+	assert.Equal(t, MarketLanguage("ebay_fr"), French)
+	assert.Equal(t, MarketLanguage("EBAY_US"), English)
+	assert.Equal(t, MarketLanguage(""), English)
+}
@@ -0,0 +1,576 @@
+// Package search is a full-text search index of the feed items, on their Title and localized aspect values.
+// The index is an inverted index kept in a bbolt database file: it is built from an item stream, i.e. a bootstrap,
+// then updated incrementally with the snapshot deltas. The results are ranked with BM25 and can be filtered
+// by category and price.
+package search
+
+import (
+	"bytes"
+	"ebay-api-client/ebay"
+	"ebay-api-client/inventory"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// DefaultK1 is the default BM25 term frequency saturation
+	DefaultK1 = 1.2
+	// DefaultB is the default BM25 document length normalization
+	DefaultB = 0.75
+	// DefaultLimit is the default number of results returned by Search
+	DefaultLimit = 20
+
+	// batchSize is the number of items indexed in a transaction by Build and Update
+	batchSize = 10000
+	// openTimeout is how long Open waits for the lock of an index used by another process
+	openTimeout = time.Second
+)
+
+var (
+	docsBucket     = []byte("docs")
+	postingsBucket = []byte("postings")
+	metaBucket     = []byte("meta")
+	languageKey    = []byte("language")
+	countKey       = []byte("count")
+	lengthKey      = []byte("length")
+	generationKey  = []byte("generation")
+)
+
+// content is a generation of the documents, the postings and their statistics. Build writes a new generation
+// and makes it current in its last transaction, so that a failed Build leaves the previous one in place.
+type content struct {
+	tx  *bolt.Tx
+	gen uint64
+}
+
+// current returns the current content, its generation is 0 in the files written by the previous versions
+func current(tx *bolt.Tx) content {
+	return content{tx: tx, gen: getUint(tx.Bucket(metaBucket), generationKey)}
+}
+
+// name returns the name of the bucket or of the meta key in the generation of the content
+func (c content) name(key []byte) []byte {
+	if c.gen == 0 {
+		return key
+	}
+	return []byte(fmt.Sprintf("%s@%v", key, c.gen))
+}
+
+func (c content) docs() *bolt.Bucket {
+	return c.tx.Bucket(c.name(docsBucket))
+}
+
+func (c content) postings() *bolt.Bucket {
+	return c.tx.Bucket(c.name(postingsBucket))
+}
+
+// stats returns the number of documents and their total length
+func (c content) stats() (uint64, uint64) {
+	meta := c.tx.Bucket(metaBucket)
+	return getUint(meta, c.name(countKey)), getUint(meta, c.name(lengthKey))
+}
+
+// create creates the empty buckets of the content
+func (c content) create() error {
+	for _, name := range [][]byte{docsBucket, postingsBucket} {
+		if _, err := c.tx.CreateBucket(c.name(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drop deletes the buckets and the statistics of the content which exist
+func (c content) drop() error {
+	for _, name := range [][]byte{docsBucket, postingsBucket} {
+		if err := c.tx.DeleteBucket(c.name(name)); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+	}
+	meta := c.tx.Bucket(metaBucket)
+	if err := meta.Delete(c.name(countKey)); err != nil {
+		return err
+	}
+	return meta.Delete(c.name(lengthKey))
+}
+
+// document is an indexed item, its text is kept to remove its postings when it is updated or removed
+type document struct {
+	Title         string
+	Aspects       string `json:",omitempty"`
+	CategoryID    string `json:",omitempty"`
+	PriceValue    string `json:",omitempty"`
+	PriceCurrency string `json:",omitempty"`
+	Length        int
+}
+
+// Query is a search
+type Query struct {
+	// Text are the searched keywords, the items matching more keywords rank first
+	Text string
+	// CategoryIDs restricts the results to the items of these categories, all the categories if empty
+	CategoryIDs []string
+	// MinPrice restricts the results to the items whose price is at least MinPrice, if not zero
+	MinPrice float64
+	// MaxPrice restricts the results to the items whose price is at most MaxPrice, if not zero
+	MaxPrice float64
+	// Limit is the maximum number of results, defaults to DefaultLimit
+	Limit int
+}
+
+// Result is a found item
+type Result struct {
+	ID            string
+	Title         string
+	CategoryID    string
+	PriceValue    string
+	PriceCurrency string
+	// Score is the BM25 score of the item for the query
+	Score float64
+}
+
+// Changes counts the changes of an Update
+type Changes struct {
+	// Indexed is the number of items added or replaced
+	Indexed int
+	// Removed is the number of items removed
+	Removed int
+}
+
+// Index is a full-text search index kept in a bbolt database file
+type Index struct {
+	bolt     *bolt.DB
+	analyzer *Analyzer
+	// K1 is the BM25 term frequency saturation, defaults to DefaultK1
+	K1 float64
+	// B is the BM25 document length normalization, defaults to DefaultB
+	B float64
+	// Removed reports whether an updated item is removed from the index, defaults to inventory.IsRemoved
+	Removed func(item *ebay.Item) bool
+}
+
+// Open opens the index file, creating it if needed. The language is the one of the indexed items (see MarketLanguage);
+// it is recorded in a new index, and an error is returned if an existing index has a different language.
+// It fails if the file is used by another process.
+func Open(path string, language Language) (*Index, error) {
+	b, err := bolt.Open(path, 0644, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("Open(): cannot open %v: %v", path, err)
+	}
+
+	err = b.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+		// The buckets of a new index are the ones of the generation 0
+		if c := current(tx); c.gen == 0 {
+			for _, name := range [][]byte{docsBucket, postingsBucket} {
+				if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+					return err
+				}
+			}
+		}
+
+		if l := meta.Get(languageKey); l != nil && Language(l) != language {
+			return fmt.Errorf("index language is %v, not %v", string(l), language)
+		}
+		return meta.Put(languageKey, []byte(language))
+	})
+	if err != nil {
+		b.Close()
+		return nil, fmt.Errorf("Open(): %v", err)
+	}
+
+	return &Index{bolt: b, analyzer: NewAnalyzer(language), K1: DefaultK1, B: DefaultB, Removed: inventory.IsRemoved}, nil
+}
+
+// Close closes the index file
+func (ix *Index) Close() error {
+	return ix.bolt.Close()
+}
+
+// Language returns the language of the index
+func (ix *Index) Language() Language {
+	return ix.analyzer.Language
+}
+
+// Build replaces the content of the index with the items of the stream, i.e. a bootstrap.
+// The items are indexed into a new content by transactions of batchSize items, which replaces the content in the
+// last transaction: if Build fails, the index keeps its previous content. It returns the number of indexed items.
+func (ix *Index) Build(it ebay.ItemIterator) (int, error) {
+	var gen uint64
+	err := ix.bolt.Update(func(tx *bolt.Tx) error {
+		next := content{tx: tx, gen: current(tx).gen + 1}
+		gen = next.gen
+		if err := next.drop(); err != nil {
+			return err
+		}
+		return next.create()
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Build(): cannot create buckets: %v", err)
+	}
+
+	staging := func(tx *bolt.Tx) content {
+		return content{tx: tx, gen: gen}
+	}
+	replace := func(tx *bolt.Tx) error {
+		previous := current(tx)
+		if err := tx.Bucket(metaBucket).Put(generationKey, putUint(gen)); err != nil {
+			return err
+		}
+		return previous.drop()
+	}
+
+	changes, err := ix.ingest(it, func(*ebay.Item) bool { return false }, staging, replace)
+	if err != nil {
+		ix.bolt.Update(func(tx *bolt.Tx) error {
+			return staging(tx).drop()
+		})
+		return 0, fmt.Errorf("Build(): %v", err)
+	}
+	return changes.Indexed, nil
+}
+
+// Update applies the items of the stream, i.e. a snapshot delta: the items are added or replaced, or removed
+// if Removed returns true for them. If Update fails, the index keeps the changes written so far.
+func (ix *Index) Update(it ebay.ItemIterator) (*Changes, error) {
+	removed := ix.Removed
+	if removed == nil {
+		removed = func(*ebay.Item) bool { return false }
+	}
+
+	changes, err := ix.ingest(it, removed, current, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Update(): %v", err)
+	}
+	return changes, nil
+}
+
+// ingest indexes the items into the content returned by contentOf, then runs commit in the last transaction if not nil
+func (ix *Index) ingest(it ebay.ItemIterator, removed func(*ebay.Item) bool, contentOf func(tx *bolt.Tx) content, commit func(tx *bolt.Tx) error) (*Changes, error) {
+	changes := &Changes{}
+
+	items := make([]*ebay.Item, 0, batchSize)
+	flush := func(last bool) error {
+		if len(items) == 0 && (!last || commit == nil) {
+			return nil
+		}
+		err := ix.bolt.Update(func(tx *bolt.Tx) error {
+			w := ix.newWriter(contentOf(tx))
+			for _, item := range items {
+				if removed(item) {
+					ok, err := w.remove(item.ID)
+					if err != nil {
+						return err
+					}
+					if ok {
+						changes.Removed++
+					}
+					continue
+				}
+				if err := w.add(item); err != nil {
+					return err
+				}
+				changes.Indexed++
+			}
+			if err := w.close(); err != nil {
+				return err
+			}
+			if last && commit != nil {
+				return commit(tx)
+			}
+			return nil
+		})
+		items = items[:0]
+		return err
+	}
+
+	for {
+		item, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if item.ID == "" {
+			continue
+		}
+
+		items = append(items, item)
+		if len(items) == batchSize {
+			if err := flush(false); err != nil {
+				return nil, fmt.Errorf("cannot write index: %v", err)
+			}
+		}
+	}
+	if err := flush(true); err != nil {
+		return nil, fmt.Errorf("cannot write index: %v", err)
+	}
+	return changes, nil
+}
+
+// Len returns the number of indexed items
+func (ix *Index) Len() (int, error) {
+	n := 0
+	err := ix.bolt.View(func(tx *bolt.Tx) error {
+		count, _ := current(tx).stats()
+		n = int(count)
+		return nil
+	})
+	return n, err
+}
+
+// Search returns the items matching at least one keyword of the query and its filters, best score first
+func (ix *Index) Search(q *Query) ([]*Result, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	categories := make(map[string]bool, len(q.CategoryIDs))
+	for _, id := range q.CategoryIDs {
+		categories[id] = true
+	}
+
+	terms := unique(ix.analyzer.Tokens(q.Text))
+
+	var results []*Result
+	err := ix.bolt.View(func(tx *bolt.Tx) error {
+		content := current(tx)
+		n, length := content.stats()
+		count := float64(n)
+		if count == 0 {
+			return nil
+		}
+		avgLength := float64(length) / count
+
+		docs := content.docs()
+		found := make(map[string]*Result)
+		rejected := make(map[string]bool)
+		lengths := make(map[string]float64)
+
+		c := content.postings().Cursor()
+		for _, term := range terms {
+			prefix := postingKey(term, "")
+
+			type posting struct {
+				id string
+				tf float64
+			}
+			var postings []posting
+			for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+				tf, _ := binary.Uvarint(v)
+				postings = append(postings, posting{id: string(k[len(prefix):]), tf: float64(tf)})
+			}
+
+			df := float64(len(postings))
+			idf := math.Log(1 + (count-df+0.5)/(df+0.5))
+
+			for _, p := range postings {
+				if rejected[p.id] {
+					continue
+				}
+
+				r, ok := found[p.id]
+				if !ok {
+					doc := &document{}
+					if err := json.Unmarshal(docs.Get([]byte(p.id)), doc); err != nil {
+						return fmt.Errorf("cannot read document %v: %v", p.id, err)
+					}
+					if !q.accepts(doc, categories) {
+						rejected[p.id] = true
+						continue
+					}
+
+					r = &Result{ID: p.id, Title: doc.Title, CategoryID: doc.CategoryID, PriceValue: doc.PriceValue, PriceCurrency: doc.PriceCurrency}
+					found[p.id], lengths[p.id] = r, float64(doc.Length)
+				}
+
+				r.Score += idf * p.tf * (ix.K1 + 1) / (p.tf + ix.K1*(1-ix.B+ix.B*lengths[p.id]/avgLength))
+			}
+		}
+
+		for _, r := range found {
+			results = append(results, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Search(): %v", err)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// accepts reports whether the document passes the category and price filters of the query
+func (q *Query) accepts(doc *document, categories map[string]bool) bool {
+	if len(categories) > 0 && !categories[doc.CategoryID] {
+		return false
+	}
+	if q.MinPrice == 0 && q.MaxPrice == 0 {
+		return true
+	}
+
+	price, err := strconv.ParseFloat(doc.PriceValue, 64)
+	if err != nil {
+		return false
+	}
+	return (q.MinPrice == 0 || price >= q.MinPrice) && (q.MaxPrice == 0 || price <= q.MaxPrice)
+}
+
+// writer updates the documents, the postings and the index statistics in a transaction
+type writer struct {
+	analyzer *Analyzer
+	content  content
+	docs     *bolt.Bucket
+	postings *bolt.Bucket
+	count    uint64
+	length   uint64
+}
+
+func (ix *Index) newWriter(c content) *writer {
+	count, length := c.stats()
+	return &writer{
+		analyzer: ix.analyzer,
+		content:  c,
+		docs:     c.docs(),
+		postings: c.postings(),
+		count:    count,
+		length:   length,
+	}
+}
+
+// add indexes the item, replacing its previous version
+func (w *writer) add(item *ebay.Item) error {
+	if _, err := w.remove(item.ID); err != nil {
+		return err
+	}
+
+	doc := &document{
+		Title:         item.Title,
+		Aspects:       item.LocalizedAspects,
+		CategoryID:    item.CategoryID,
+		PriceValue:    item.PriceValue,
+		PriceCurrency: item.PriceCurrency,
+	}
+	tokens := w.tokens(doc)
+	doc.Length = len(tokens)
+
+	for term, tf := range frequencies(tokens) {
+		if err := w.postings.Put(postingKey(term, item.ID), putUint(uint64(tf))); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	if err := w.docs.Put([]byte(item.ID), data); err != nil {
+		return err
+	}
+
+	w.count++
+	w.length += uint64(doc.Length)
+	return nil
+}
+
+// remove removes the item from the index, it returns false if the item is not indexed
+func (w *writer) remove(id string) (bool, error) {
+	data := w.docs.Get([]byte(id))
+	if data == nil {
+		return false, nil
+	}
+
+	doc := &document{}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return false, err
+	}
+	for term := range frequencies(w.tokens(doc)) {
+		if err := w.postings.Delete(postingKey(term, id)); err != nil {
+			return false, err
+		}
+	}
+	if err := w.docs.Delete([]byte(id)); err != nil {
+		return false, err
+	}
+
+	w.count--
+	w.length -= uint64(doc.Length)
+	return true, nil
+}
+
+// tokens returns the tokens of the title and of the aspect values of the document
+func (w *writer) tokens(doc *document) []string {
+	tokens := w.analyzer.Tokens(doc.Title)
+	// The aspects which cannot be decoded are not indexed
+	aspects, _ := ebay.ParseAspects(doc.Aspects)
+	for _, aspect := range aspects {
+		tokens = append(tokens, w.analyzer.Tokens(aspect.Value)...)
+	}
+	return tokens
+}
+
+// close writes the index statistics
+func (w *writer) close() error {
+	meta := w.content.tx.Bucket(metaBucket)
+	if err := meta.Put(w.content.name(countKey), putUint(w.count)); err != nil {
+		return err
+	}
+	return meta.Put(w.content.name(lengthKey), putUint(w.length))
+}
+
+// postingKey is the key of a posting: the term, a zero byte, then the item id
+func postingKey(term, id string) []byte {
+	return []byte(term + "\x00" + id)
+}
+
+func putUint(n uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return buf[:binary.PutUvarint(buf, n)]
+}
+
+func getUint(b *bolt.Bucket, key []byte) uint64 {
+	n, _ := binary.Uvarint(b.Get(key))
+	return n
+}
+
+func frequencies(tokens []string) map[string]int {
+	tf := make(map[string]int)
+	for _, t := range tokens {
+		tf[t]++
+	}
+	return tf
+}
+
+func unique(tokens []string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, t := range tokens {
+		if !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
@@ -0,0 +1,206 @@
+package search
+
+import (
+	"ebay-api-client/ebay"
+	"ebay-api-client/ebay/ebaytest"
+	"ebay-api-client/filter"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+	"gotest.tools/v3/assert"
+)
+
+var items = []*ebay.Item{
+	{ID: "v1|1|0", Title: "Red phone", CategoryID: "9355", PriceValue: "99", PriceCurrency: "USD"},
+	{ID: "v1|2|0", Title: "Phone case for red phones", CategoryID: "9355", PriceValue: "9.5", PriceCurrency: "USD"},
+	{ID: "v1|3|0", Title: "Laptop sleeve", CategoryID: "177", PriceValue: "25", PriceCurrency: "USD",
+		LocalizedAspects: "Q29sb3I=:UmVk;TWF0ZXJpYWw=:TmVvcHJlbmU="}, // Color:Red;Material:Neoprene
+	{ID: "v1|4|0", Title: "Blue laptop", CategoryID: "177", PriceValue: "350", PriceCurrency: "USD"},
+}
+
+// newTestIndex opens an index in a temporary directory, returning its path and the cleanup function
+func newTestIndex(t *testing.T) (*Index, string, func()) {
+	dir, err := ioutil.TempDir("", "search")
+	assert.NilError(t, err)
+
+	path := filepath.Join(dir, "items.idx")
+	ix, err := Open(path, English)
+	assert.NilError(t, err)
+
+	return ix, path, func() {
+		ix.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// resultIDs returns the ids of the results
+func resultIDs(results []*Result) []string {
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func Test_IsIndexRankingWithBM25(t *testing.T) {
+	ix, _, cleanup := newTestIndex(t)
+	defer cleanup()
+
+	n, err := ix.Build(ebaytest.ItemReader(items...))
+	assert.NilError(t, err)
+	assert.Equal(t, n, 4)
+
+	results, err := ix.Search(&Query{Text: "red phone"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, resultIDs(results), []string{"v1|1|0", "v1|2|0", "v1|3|0"})
+	assert.Assert(t, results[0].Score > results[1].Score && results[1].Score > results[2].Score)
+	assert.Equal(t, results[0].Title, "Red phone")
+	assert.Equal(t, results[0].PriceValue, "99")
+
+	// The aspects values are searched
+	results, err = ix.Search(&Query{Text: "NEOPRENE"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, resultIDs(results), []string{"v1|3|0"})
+
+	results, err = ix.Search(&Query{Text: "the"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(results), 0)
+
+	results, err = ix.Search(&Query{Text: "red phone", Limit: 1})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, resultIDs(results), []string{"v1|1|0"})
+}
+
+func Test_IsIndexFilteringByCategoryAndPrice(t *testing.T) {
+	ix, _, cleanup := newTestIndex(t)
+	defer cleanup()
+
+	_, err := ix.Build(ebaytest.ItemReader(items...))
+	assert.NilError(t, err)
+
+	tests := []struct {
+		query *Query
+		want  []string
+	}{
+		{&Query{Text: "red laptop", CategoryIDs: []string{"177"}}, []string{"v1|3|0", "v1|4|0"}},
+		{&Query{Text: "red laptop", CategoryIDs: []string{"9355"}}, []string{"v1|1|0", "v1|2|0"}},
+		{&Query{Text: "red laptop", MinPrice: 20, MaxPrice: 100}, []string{"v1|3|0", "v1|1|0"}},
+		{&Query{Text: "red laptop", MaxPrice: 10}, []string{"v1|2|0"}},
+		{&Query{Text: "red laptop", MinPrice: 300}, []string{"v1|4|0"}},
+	}
+	for _, tt := range tests {
+		results, err := ix.Search(tt.query)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, resultIDs(results), tt.want)
+	}
+}
+
+func Test_IsIndexUpdatingIncrementally(t *testing.T) {
+	ix, path, cleanup := newTestIndex(t)
+	defer cleanup()
+
+	_, err := ix.Build(ebaytest.ItemReader(items...))
+	assert.NilError(t, err)
+
+	delta := []*ebay.Item{
+		{ID: "v1|1|0", Title: "Green phone", CategoryID: "9355", PriceValue: "89"},
+		{ID: "v1|3|0", Availability: "OUT_OF_STOCK"},
+		{ID: "v1|5|0", Title: "Red laptop bag", CategoryID: "177", PriceValue: "30"},
+		{ID: "v1|6|0", Availability: "UNAVAILABLE"},
+	}
+	changes, err := ix.Update(ebaytest.ItemReader(delta...))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, changes, &Changes{Indexed: 2, Removed: 1})
+
+	n, err := ix.Len()
+	assert.NilError(t, err)
+	assert.Equal(t, n, 4)
+
+	results, err := ix.Search(&Query{Text: "red"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, resultIDs(results), []string{"v1|5|0", "v1|2|0"})
+
+	// The index persists and keeps its language
+	assert.NilError(t, ix.Close())
+	_, err = Open(path, German)
+	assert.ErrorContains(t, err, "index language is en, not de")
+
+	ix, err = Open(path, English)
+	assert.NilError(t, err)
+
+	results, err = ix.Search(&Query{Text: "green"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, resultIDs(results), []string{"v1|1|0"})
+
+	// Build replaces the content, the items can be filtered before being indexed
+	p, err := filter.Parse("CategoryID = 177")
+	assert.NilError(t, err)
+	n, err = ix.Build(filter.NewReader(ebaytest.ItemReader(items...), p))
+	assert.NilError(t, err)
+	assert.Equal(t, n, 2)
+
+	results, err = ix.Search(&Query{Text: "green phone"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(results), 0)
+}
+
+// failingIterator returns the items then the error
+type failingIterator struct {
+	items []*ebay.Item
+	err   error
+}
+
+func (f *failingIterator) Next() (*ebay.Item, error) {
+	if len(f.items) == 0 {
+		return nil, f.err
+	}
+	item := f.items[0]
+	f.items = f.items[1:]
+	return item, nil
+}
+
+func Test_IsFailedBuildKeepingContent(t *testing.T) {
+	ix, path, cleanup := newTestIndex(t)
+	defer cleanup()
+
+	_, err := ix.Build(ebaytest.ItemReader(items...))
+	assert.NilError(t, err)
+
+	_, err = ix.Build(&failingIterator{items: []*ebay.Item{{ID: "v1|9|0", Title: "Red mouse"}}, err: errors.New("truncated")})
+	assert.ErrorContains(t, err, "truncated")
+
+	n, err := ix.Len()
+	assert.NilError(t, err)
+	assert.Equal(t, n, 4)
+	results, err := ix.Search(&Query{Text: "red phone"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, resultIDs(results), []string{"v1|1|0", "v1|2|0", "v1|3|0"})
+
+	// The buckets of the failed Build are dropped, the next Build replaces the content and survives a reopen
+	var buckets []string
+	assert.NilError(t, ix.bolt.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			buckets = append(buckets, string(name))
+			return nil
+		})
+	}))
+	assert.Equal(t, len(buckets), 3)
+
+	_, err = ix.Build(ebaytest.ItemReader(&ebay.Item{ID: "v1|9|0", Title: "Red mouse"}))
+	assert.NilError(t, err)
+	assert.NilError(t, ix.Close())
+
+	ix, err = Open(path, English)
+	assert.NilError(t, err)
+	defer ix.Close()
+	n, err = ix.Len()
+	assert.NilError(t, err)
+	assert.Equal(t, n, 1)
+	results, err = ix.Search(&Query{Text: "red"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, resultIDs(results), []string{"v1|9|0"})
+}
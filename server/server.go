@@ -0,0 +1,358 @@
+// Package server serves the items of a store over HTTP as JSON, so that several services can query the mirrored feeds
+// without downloading them. The items are got by id, listed by legacy id, GTIN, EPID, MPN, seller or category, filtered
+// with filter expressions and paginated with cursors; the categories are listed with their item counts.
+// Each response reports the freshness of the store, i.e. the FeedInfo of the last loaded feed, in the X-Feed headers.
+//
+// The routes are:
+//
+//	GET /items/{id}    the item with the given id
+//	GET /items         the items ordered by id
+//	GET /categories    the categories with their item counts, by decreasing count
+//
+// The query parameters of /items are:
+//
+//	legacy_item_id, gtin, epid, mpn, seller, category_id
+//	        the value of the indexed field, ignoring the case
+//	filter  a filter expression, see filter.Parse
+//	limit   the number of items of the page, DefaultLimit if not given, at most MaxLimit
+//	cursor  the next_cursor of the previous page
+//
+// The errors are returned as JSON objects with an error message.
+package server
+
+import (
+	"ebay-api-client/ebay"
+	"ebay-api-client/filter"
+	"ebay-api-client/store"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLimit is the number of items listed when the limit parameter is not given
+	DefaultLimit = 100
+	// MaxLimit is the maximum number of items listed in a response
+	MaxLimit = 1000
+)
+
+// lookupParams are the query parameters of /items selecting the items by an indexed field, in lookup order
+var lookupParams = []struct {
+	param string
+	field string
+}{
+	{"legacy_item_id", "LegacyItemID"},
+	{"gtin", "GTIN"},
+	{"epid", "EPID"},
+	{"mpn", "MPN"},
+	{"seller", "SellerUsername"},
+	{"category_id", "CategoryID"},
+}
+
+// Response headers reporting the freshness of the store
+const (
+	HeaderFeedType         = "X-Feed-Type"
+	HeaderFeedMarketID     = "X-Feed-Market-Id"
+	HeaderFeedCategoryID   = "X-Feed-Category-Id"
+	HeaderFeedLastModified = "X-Feed-Last-Modified"
+	HeaderFeedLoadedAt     = "X-Feed-Loaded-At"
+	HeaderFeedWatermark    = "X-Feed-Watermark"
+)
+
+// Store is the item store queried by the server, it is implemented by store.DB
+type Store interface {
+	Item(id string) (*ebay.Item, bool, error)
+	Lookup(field, value, after string, limit int) ([]*ebay.Item, error)
+	Scan(after string, fn func(id, row string) error) error
+	Counts(field string) (map[string]int, error)
+	LastBatch() (*store.Batch, error)
+	Watermark() (time.Time, error)
+}
+
+// Items is the JSON response listing items
+type Items struct {
+	// Items are the listed items, ordered by id
+	Items []*ebay.Item `json:"items"`
+	// NextCursor is the cursor parameter of the next page, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Category is the number of items of a category
+type Category struct {
+	CategoryID string `json:"category_id"`
+	Count      int    `json:"count"`
+}
+
+// Categories is the JSON response listing the categories
+type Categories struct {
+	Categories []Category `json:"categories"`
+}
+
+// Error is the JSON response of a failed request
+type Error struct {
+	Error string `json:"error"`
+}
+
+// Server is the http.Handler serving the items of the store
+type Server struct {
+	// Store is the queried item store
+	Store Store
+	// Logger reports the internal errors. Nothing is logged if nil.
+	Logger *log.Logger
+
+	mux *http.ServeMux
+}
+
+// New creates a new Server of the store
+func New(s Store) *Server {
+	srv := &Server{Store: s, mux: http.NewServeMux()}
+	srv.mux.HandleFunc("/items/", srv.getItem)
+	srv.mux.HandleFunc("/items", srv.listItems)
+	srv.mux.HandleFunc("/categories", srv.listCategories)
+	srv.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		srv.writeError(w, http.StatusNotFound, fmt.Errorf("%v not found", r.URL.Path))
+	})
+	return srv
+}
+
+// ServeHTTP sets the freshness headers and serves the request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		s.writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+		return
+	}
+
+	if err := s.setFreshness(w.Header()); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// setFreshness sets the headers describing the last loaded feed and the watermark of the store
+func (s *Server) setFreshness(h http.Header) error {
+	batch, err := s.Store.LastBatch()
+	if err != nil {
+		return err
+	}
+	if batch != nil {
+		h.Set("Last-Modified", batch.LoadedAt.UTC().Format(http.TimeFormat))
+		h.Set(HeaderFeedLoadedAt, batch.LoadedAt.UTC().Format(time.RFC3339))
+		setHeader(h, HeaderFeedType, batch.Info.Type)
+		setHeader(h, HeaderFeedMarketID, batch.Info.MarketID)
+		setHeader(h, HeaderFeedCategoryID, batch.Info.CategoryID)
+		if !batch.Info.LastModified.IsZero() {
+			h.Set(HeaderFeedLastModified, batch.Info.LastModified.UTC().Format(time.RFC3339))
+		}
+	}
+
+	watermark, err := s.Store.Watermark()
+	if err != nil {
+		return err
+	}
+	if !watermark.IsZero() {
+		h.Set(HeaderFeedWatermark, watermark.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+func setHeader(h http.Header, key, value string) {
+	if value != "" {
+		h.Set(key, value)
+	}
+}
+
+// getItem serves the item whose id follows /items/, 404 if the item is not in the store
+func (s *Server) getItem(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/items/")
+	if id == "" {
+		s.listItems(w, r)
+		return
+	}
+
+	item, ok, err := s.Store.Item(id)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !ok {
+		s.writeError(w, http.StatusNotFound, fmt.Errorf("item %v not found", id))
+		return
+	}
+	s.writeJSON(w, http.StatusOK, item)
+}
+
+// listItems serves a page of the items matching the query parameters, ordered by id
+func (s *Server) listItems(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit := DefaultLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > MaxLimit {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q, expected 1 to %v", v, MaxLimit))
+			return
+		}
+		limit = n
+	}
+
+	after, err := decodeCursor(q.Get("cursor"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	p, err := filter.Parse(q.Get("filter"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid filter: %v", err))
+		return
+	}
+
+	var field, value string
+	var predicates []filter.Predicate
+	for _, l := range lookupParams {
+		v := q.Get(l.param)
+		if v == "" {
+			continue
+		}
+		if field == "" {
+			field, value = l.field, v
+		} else {
+			predicates = append(predicates, equalFold(l.field, v))
+		}
+	}
+	p = filter.And(append(predicates, p)...)
+
+	// One more item than the limit is read to know if there is a next page
+	var items []*ebay.Item
+	if field != "" {
+		items, err = s.lookup(field, value, after, limit+1, p)
+	} else {
+		items, err = s.scan(after, limit+1, p)
+	}
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := &Items{Items: items}
+	if len(items) > limit {
+		res.Items = items[:limit]
+		res.NextCursor = encodeCursor(items[limit-1].ID)
+	}
+	if res.Items == nil {
+		res.Items = []*ebay.Item{}
+	}
+	s.writeJSON(w, http.StatusOK, res)
+}
+
+// lookup returns at most limit items matching p, with the value of the indexed field and an id greater than after.
+// The index is read by pages of limit items until enough items match.
+func (s *Server) lookup(field, value, after string, limit int, p filter.Predicate) ([]*ebay.Item, error) {
+	var items []*ebay.Item
+	for {
+		found, err := s.Store.Lookup(field, value, after, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range found {
+			if p.Match(item) {
+				items = append(items, item)
+				if len(items) == limit {
+					return items, nil
+				}
+			}
+		}
+		if len(found) < limit {
+			return items, nil
+		}
+		after = found[len(found)-1].ID
+	}
+}
+
+// scan returns at most limit items matching p, with an id greater than after
+func (s *Server) scan(after string, limit int, p filter.Predicate) ([]*ebay.Item, error) {
+	var items []*ebay.Item
+	err := s.Store.Scan(after, func(id, row string) error {
+		if len(items) == limit {
+			return io.EOF
+		}
+		if item := ebay.NewItemFromTSV(row); p.Match(item) {
+			items = append(items, item)
+		}
+		return nil
+	})
+	return items, err
+}
+
+// equalFold returns the predicate matching the items whose field has the value, ignoring the case
+func equalFold(field, value string) filter.Predicate {
+	return filter.PredicateFunc(func(item *ebay.Item) bool {
+		v, _ := item.Field(field)
+		return strings.EqualFold(v, value)
+	})
+}
+
+func encodeCursor(id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(id))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	id, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return string(id), nil
+}
+
+// listCategories serves the categories of the items with their item counts, by decreasing count then category id
+func (s *Server) listCategories(w http.ResponseWriter, r *http.Request) {
+	counts, err := s.Store.Counts("CategoryID")
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := &Categories{Categories: make([]Category, 0, len(counts))}
+	for id, n := range counts {
+		res.Categories = append(res.Categories, Category{CategoryID: id, Count: n})
+	}
+	sort.Slice(res.Categories, func(i, j int) bool {
+		a, b := res.Categories[i], res.Categories[j]
+		return a.Count > b.Count || a.Count == b.Count && a.CategoryID < b.CategoryID
+	})
+	s.writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.logf("cannot write response: %v", err)
+	}
+}
+
+// writeError writes the JSON error, logging the internal errors
+func (s *Server) writeError(w http.ResponseWriter, status int, err error) {
+	if status == http.StatusInternalServerError {
+		s.logf("%v", err)
+		err = errors.New(http.StatusText(status))
+	}
+	s.writeJSON(w, status, &Error{Error: err.Error()})
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.Logger != nil {
+		s.Logger.Printf(format, args...)
+	}
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"ebay-api-client/ebay"
+	"ebay-api-client/ebay/ebaytest"
+	"ebay-api-client/store"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+var items = []*ebay.Item{
+	{ID: "v1|1|0", LegacyItemID: "1", Title: "Red phone", CategoryID: "9355", GTIN: "0190198459558", SellerUsername: "alice", PriceValue: "9.5"},
+	{ID: "v1|2|0", LegacyItemID: "2", Title: "Blue phone", CategoryID: "9355", GTIN: "0190198459558", SellerUsername: "bob", PriceValue: "12"},
+	{ID: "v1|3|0", LegacyItemID: "3", Title: "Laptop", CategoryID: "177", SellerUsername: "alice", PriceValue: "350"},
+	{ID: "v1|4|0", LegacyItemID: "4", Title: "Green phone", CategoryID: "9355", SellerUsername: "Alice", PriceValue: "20"},
+}
+
+var feedInfo = &ebay.FeedInfo{Type: "snapshot", MarketID: "EBAY_US", CategoryID: "9355", LastModified: time.Date(2020, 5, 5, 9, 0, 0, 0, time.UTC)}
+
+// newTestServer serves a store loaded with the items, returning the server and the cleanup function
+func newTestServer(t *testing.T) (*httptest.Server, func()) {
+	dir, err := ioutil.TempDir("", "server")
+	assert.NilError(t, err)
+
+	db, err := store.Open(filepath.Join(dir, "items.db"))
+	assert.NilError(t, err)
+
+	_, err = db.Upsert(ebaytest.ItemReader(items...), feedInfo)
+	assert.NilError(t, err)
+
+	srv := httptest.NewServer(New(db))
+	return srv, func() {
+		srv.Close()
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// get requests the path and decodes the JSON response into v
+func get(t *testing.T, srv *httptest.Server, path string, v interface{}) *http.Response {
+	res, err := http.Get(srv.URL + path)
+	assert.NilError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, res.Header.Get("Content-Type"), "application/json")
+	assert.NilError(t, json.NewDecoder(res.Body).Decode(v))
+	return res
+}
+
+// ids returns the ids of the items
+func ids(items []*ebay.Item) []string {
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func Test_IsServerGettingItems(t *testing.T) {
+	srv, cleanup := newTestServer(t)
+	defer cleanup()
+
+	item := &ebay.Item{}
+	res := get(t, srv, "/items/"+url.PathEscape("v1|2|0"), item)
+	assert.Equal(t, res.StatusCode, http.StatusOK)
+	assert.DeepEqual(t, item, items[1])
+
+	// The freshness of the store is reported in the headers
+	assert.Equal(t, res.Header.Get(HeaderFeedType), "snapshot")
+	assert.Equal(t, res.Header.Get(HeaderFeedMarketID), "EBAY_US")
+	assert.Equal(t, res.Header.Get(HeaderFeedCategoryID), "9355")
+	assert.Equal(t, res.Header.Get(HeaderFeedLastModified), "2020-05-05T09:00:00Z")
+	assert.Assert(t, res.Header.Get(HeaderFeedLoadedAt) != "")
+	assert.Assert(t, res.Header.Get("Last-Modified") != "")
+	assert.Equal(t, res.Header.Get(HeaderFeedWatermark), "")
+
+	e := &Error{}
+	res = get(t, srv, "/items/"+url.PathEscape("v1|9|0"), e)
+	assert.Equal(t, res.StatusCode, http.StatusNotFound)
+	assert.Equal(t, e.Error, "item v1|9|0 not found")
+
+	res = get(t, srv, "/unknown", e)
+	assert.Equal(t, res.StatusCode, http.StatusNotFound)
+
+	res, err := http.Post(srv.URL+"/items", "application/json", nil)
+	assert.NilError(t, err)
+	res.Body.Close()
+	assert.Equal(t, res.StatusCode, http.StatusMethodNotAllowed)
+}
+
+func Test_IsServerListingItems(t *testing.T) {
+	srv, cleanup := newTestServer(t)
+	defer cleanup()
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"v1|1|0", "v1|2|0", "v1|3|0", "v1|4|0"}},
+		{"?legacy_item_id=3", []string{"v1|3|0"}},
+		{"?gtin=0190198459558", []string{"v1|1|0", "v1|2|0"}},
+		{"?seller=ALICE&category_id=9355", []string{"v1|1|0", "v1|4|0"}},
+		{"?seller=alice&filter=" + url.QueryEscape("PriceValue > 10"), []string{"v1|3|0", "v1|4|0"}},
+		{"?filter=" + url.QueryEscape(`Title ~ "phone$"`), []string{"v1|1|0", "v1|2|0", "v1|4|0"}},
+		{"?epid=1", nil},
+	}
+	for _, tt := range tests {
+		res := &Items{}
+		assert.Equal(t, get(t, srv, "/items"+tt.query, res).StatusCode, http.StatusOK, tt.query)
+		assert.DeepEqual(t, ids(res.Items), tt.want)
+		assert.Equal(t, res.NextCursor, "", tt.query)
+	}
+
+	for _, query := range []string{"?limit=0", "?limit=1001", "?cursor=not*base64", "?filter=" + url.QueryEscape("Title =")} {
+		e := &Error{}
+		assert.Equal(t, get(t, srv, "/items"+query, e).StatusCode, http.StatusBadRequest, query)
+		assert.Assert(t, e.Error != "")
+	}
+}
+
+func Test_IsServerPaginatingItems(t *testing.T) {
+	srv, cleanup := newTestServer(t)
+	defer cleanup()
+
+	// The filtered lookup reads the index beyond the first page to fill a page
+	for _, query := range []string{"?filter=", "?seller=alice", "?seller=alice&filter=" + url.QueryEscape("PriceValue > 10")} {
+		var got []string
+		cursor := ""
+		for pages := 0; pages < 10; pages++ {
+			res := &Items{}
+			get(t, srv, "/items"+query+"&limit=1&cursor="+cursor, res)
+			assert.Assert(t, len(res.Items) <= 1)
+			got = append(got, ids(res.Items)...)
+			if res.NextCursor == "" {
+				break
+			}
+			cursor = res.NextCursor
+		}
+		assert.Assert(t, cursor != "", query)
+
+		all := &Items{}
+		get(t, srv, "/items"+query, all)
+		assert.DeepEqual(t, got, ids(all.Items))
+	}
+}
+
+func Test_IsServerCountingCategories(t *testing.T) {
+	srv, cleanup := newTestServer(t)
+	defer cleanup()
+
+	res := &Categories{}
+	assert.Equal(t, get(t, srv, "/categories", res).StatusCode, http.StatusOK)
+	assert.DeepEqual(t, res.Categories, []Category{{CategoryID: "9355", Count: 3}, {CategoryID: "177", Count: 1}})
+}
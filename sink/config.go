@@ -0,0 +1,166 @@
+package sink
+
+import (
+	"ebay-api-client/config"
+	"ebay-api-client/export"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// Sink types of the configuration
+const (
+	TypeFile = "file"
+	TypeHTTP = "http"
+)
+
+// Config describes the sinks of a pipeline and their batches
+type Config struct {
+	// BatchSize is the maximum number of items of a batch, defaults to DefaultBatchSize
+	BatchSize int `json:"batch_size" yaml:"batch_size" toml:"batch_size"`
+	// FlushInterval is the maximum time an item waits in a partial batch, as a Go duration, i.e. 5s
+	FlushInterval string `json:"flush_interval" yaml:"flush_interval" toml:"flush_interval"`
+	// BufferSize is the number of items buffered before the feed reader blocks, defaults to BatchSize
+	BufferSize int `json:"buffer_size" yaml:"buffer_size" toml:"buffer_size"`
+	// Sinks are the destinations of the items, they all receive all the items
+	Sinks []SinkConfig `json:"sinks" yaml:"sinks" toml:"sinks"`
+}
+
+// SinkConfig describes a destination of the items
+type SinkConfig struct {
+	// Type is the sink type, file or http
+	Type string `json:"type" yaml:"type" toml:"type"`
+	// Path is the output file of a file sink, - for the standard output
+	Path string `json:"path" yaml:"path" toml:"path"`
+	// Format is the format of a file sink, jsonl (default) or csv. The http sinks post JSON Lines.
+	Format string `json:"format" yaml:"format" toml:"format"`
+	// URL is the endpoint of an http sink
+	URL string `json:"url" yaml:"url" toml:"url"`
+	// Headers are added to the requests of an http sink
+	Headers map[string]string `json:"headers" yaml:"headers" toml:"headers"`
+	// Fields are the written fields, all of them if empty (see export.Options)
+	Fields []string `json:"fields" yaml:"fields" toml:"fields"`
+	// Typed writes the typed values instead of the raw feed values (see export.Options)
+	Typed bool `json:"typed" yaml:"typed" toml:"typed"`
+	// Gzip compresses the file or the request bodies
+	Gzip bool `json:"gzip" yaml:"gzip" toml:"gzip"`
+}
+
+// LoadConfig reads the sinks configuration from the given file.
+// The file format is detected from its extension: .json, .yaml, .yml and .toml are supported.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("LoadConfig(): cannot read configuration file: %v", err)
+	}
+
+	c := &Config{}
+	if err := config.Unmarshal(path, data, c); err != nil {
+		return nil, fmt.Errorf("LoadConfig(): %v", err)
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Validate checks that the configuration is complete
+func (c *Config) Validate() error {
+	if c.BatchSize < 0 || c.BufferSize < 0 {
+		return fmt.Errorf("Validate(): batch_size and buffer_size cannot be negative")
+	}
+	if c.FlushInterval != "" {
+		if d, err := time.ParseDuration(c.FlushInterval); err != nil || d < 0 {
+			return fmt.Errorf("Validate(): invalid flush_interval %q, expected a duration such as 5s", c.FlushInterval)
+		}
+	}
+
+	if len(c.Sinks) == 0 {
+		return fmt.Errorf("Validate(): at least one sink is required")
+	}
+
+	for n, s := range c.Sinks {
+		switch s.Type {
+		case TypeFile:
+			if s.Path == "" {
+				return fmt.Errorf("Validate(): path is required for file sink %v", n+1)
+			}
+			if s.Format != "" && s.Format != "jsonl" && s.Format != "csv" {
+				return fmt.Errorf("Validate(): unknown format %q for file sink %v, expected jsonl or csv", s.Format, n+1)
+			}
+		case TypeHTTP:
+			if s.URL == "" {
+				return fmt.Errorf("Validate(): url is required for http sink %v", n+1)
+			}
+		default:
+			return fmt.Errorf("Validate(): unknown type %q for sink %v, expected file or http", s.Type, n+1)
+		}
+	}
+	return nil
+}
+
+// Options returns the batch options of the configuration
+func (c *Config) Options() *Options {
+	interval, _ := time.ParseDuration(c.FlushInterval)
+	return &Options{BatchSize: c.BatchSize, FlushInterval: interval, BufferSize: c.BufferSize}
+}
+
+// Open creates the configured sinks, a MultiSink if there are several of them.
+// The file sinks with the - path write to stdout.
+func (c *Config) Open(stdout io.Writer) (ItemSink, error) {
+	var sinks []ItemSink
+	for _, s := range c.Sinks {
+		sink, err := s.open(stdout)
+		if err != nil {
+			NewMultiSink(sinks...).Close()
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if len(sinks) == 1 {
+		return sinks[0], nil
+	}
+	return NewMultiSink(sinks...), nil
+}
+
+// open creates the sink
+func (s *SinkConfig) open(stdout io.Writer) (ItemSink, error) {
+	opts := &export.Options{Fields: s.Fields, Typed: s.Typed, Gzip: s.Gzip}
+
+	if s.Type == TypeHTTP {
+		sink := NewHTTPSink(s.URL)
+		sink.Options = opts
+		for key, value := range s.Headers {
+			sink.Header.Set(key, value)
+		}
+		return sink, nil
+	}
+
+	out, closer := stdout, io.Closer(nil)
+	if s.Path != "-" {
+		f, err := os.Create(s.Path)
+		if err != nil {
+			return nil, fmt.Errorf("Open(): cannot create %v: %v", s.Path, err)
+		}
+		out, closer = f, f
+	}
+
+	var w export.Writer
+	var err error
+	if s.Format == "csv" {
+		w, err = export.NewCSVWriter(out, opts)
+	} else {
+		w, err = export.NewJSONLinesWriter(out, opts)
+	}
+	if err != nil {
+		if closer != nil {
+			closer.Close()
+		}
+		return nil, fmt.Errorf("Open(): %v", err)
+	}
+	return NewWriterSink(w, closer), nil
+}
@@ -0,0 +1,78 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func Test_IsConfigOpeningSinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	srv, posted := ingestionServer(t, 0)
+	defer srv.Close()
+
+	output := filepath.Join(dir, "items.csv")
+	path := filepath.Join(dir, "sinks.yaml")
+	assert.NilError(t, ioutil.WriteFile(path, []byte(`
+batch_size: 10
+flush_interval: 5s
+sinks:
+  - type: file
+    path: `+output+`
+    format: csv
+    fields: [ItemID, Title]
+  - type: file
+    path: "-"
+  - type: http
+    url: `+srv.URL+`
+    headers:
+      Authorization: Bearer token
+`), 0644))
+
+	c, err := LoadConfig(path)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, c.Options(), &Options{BatchSize: 10, FlushInterval: 5 * time.Second})
+
+	stdout := new(bytes.Buffer)
+	s, err := c.Open(stdout)
+	assert.NilError(t, err)
+
+	b := NewBatcher(context.Background(), s, c.Options())
+	for _, item := range items {
+		assert.NilError(t, b.Put(item))
+	}
+	assert.NilError(t, b.Close())
+
+	data, err := ioutil.ReadFile(output)
+	assert.NilError(t, err)
+	assert.Equal(t, string(data), "ID,Title\nv1|1|0,Red phone\nv1|2|0,Laptop\n")
+	assert.Equal(t, bytes.Count(stdout.Bytes(), []byte("\n")), 2)
+	assert.Equal(t, len(*posted), 2)
+}
+
+func Test_IsConfigValidated(t *testing.T) {
+	tests := []struct {
+		config *Config
+		want   string
+	}{
+		{&Config{}, "at least one sink is required"},
+		{&Config{FlushInterval: "5", Sinks: []SinkConfig{{Type: TypeHTTP, URL: "http://localhost"}}}, `invalid flush_interval "5"`},
+		{&Config{BatchSize: -1, Sinks: []SinkConfig{{Type: TypeHTTP, URL: "http://localhost"}}}, "cannot be negative"},
+		{&Config{Sinks: []SinkConfig{{Type: TypeFile}}}, "path is required for file sink 1"},
+		{&Config{Sinks: []SinkConfig{{Type: TypeFile, Path: "-", Format: "xml"}}}, `unknown format "xml"`},
+		{&Config{Sinks: []SinkConfig{{Type: TypeFile, Path: "-"}, {Type: TypeHTTP}}}, "url is required for http sink 2"},
+		{&Config{Sinks: []SinkConfig{{Type: "kafka"}}}, `unknown type "kafka" for sink 1`},
+	}
+	for _, tt := range tests {
+		assert.ErrorContains(t, tt.config.Validate(), tt.want)
+	}
+}
@@ -0,0 +1,214 @@
+// Package sink publishes item streams to destinations: export writers (files), channels, HTTP ingestion endpoints,
+// or several of them at once. The destinations implement ItemSink and receive the items in batches from a Batcher,
+// which flushes the batches when they are full or on an interval. The Batcher buffers a bounded number of items:
+// when a destination is slow, Put blocks the feed reader, and when a destination fails, Put returns its error so
+// that the feed reader stops. The sinks of a pipeline can be read from a configuration file (see LoadConfig).
+package sink
+
+import (
+	"context"
+	"ebay-api-client/ebay"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBatchSize is the number of items of a batch when Options.BatchSize is not set
+	DefaultBatchSize = 1000
+)
+
+// ErrClosed is returned by Put and Close when the batcher is already closed
+var ErrClosed = errors.New("batcher is closed")
+
+// ItemSink is a destination of the items
+type ItemSink interface {
+	// WriteBatch writes the items. The sink must not keep the slice, it is reused by the caller.
+	WriteBatch(ctx context.Context, items []*ebay.Item) error
+	// Close flushes the written items and releases the sink
+	Close() error
+}
+
+// Options tunes the batches of a Batcher
+type Options struct {
+	// BatchSize is the maximum number of items of a batch, defaults to DefaultBatchSize
+	BatchSize int
+	// FlushInterval is the maximum time an item waits in a partial batch, the batches are only flushed when full if 0
+	FlushInterval time.Duration
+	// BufferSize is the number of items buffered before Put blocks, defaults to BatchSize
+	BufferSize int
+}
+
+// Batcher groups the items in batches written to a sink by a background goroutine.
+// Put can be called concurrently, also with Close: the Put calls after Close return ErrClosed.
+type Batcher struct {
+	sink     ItemSink
+	ctx      context.Context
+	size     int
+	interval time.Duration
+
+	items  chan *ebay.Item
+	done   chan struct{}
+	failed chan struct{}
+
+	// closing is held for reading by Put while it sends an item, and for writing by Close to close the items
+	closing sync.RWMutex
+	closed  bool
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewBatcher creates a new Batcher writing to the sink until the context is cancelled
+func NewBatcher(ctx context.Context, sink ItemSink, opts *Options) *Batcher {
+	if opts == nil {
+		opts = &Options{}
+	}
+	size := opts.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	buffer := opts.BufferSize
+	if buffer <= 0 {
+		buffer = size
+	}
+
+	b := &Batcher{
+		sink:     sink,
+		ctx:      ctx,
+		size:     size,
+		interval: opts.FlushInterval,
+		items:    make(chan *ebay.Item, buffer),
+		done:     make(chan struct{}),
+		failed:   make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Put adds the item to the current batch. It blocks while the buffer is full and returns the error of the sink
+// if a batch failed, the error of the context if it is cancelled, or ErrClosed after Close.
+func (b *Batcher) Put(item *ebay.Item) error {
+	b.closing.RLock()
+	defer b.closing.RUnlock()
+
+	if b.closed {
+		return ErrClosed
+	}
+	if err := b.Err(); err != nil {
+		return err
+	}
+	if err := b.ctx.Err(); err != nil {
+		return err
+	}
+
+	select {
+	case b.items <- item:
+		return nil
+	case <-b.failed:
+		return b.Err()
+	case <-b.ctx.Done():
+		return b.ctx.Err()
+	}
+}
+
+// Err returns the error of the first failed batch, nil if none failed
+func (b *Batcher) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+// Close flushes the buffered items, waits for the last batch and closes the sink.
+// It returns the error of the first failed batch or the error closing the sink.
+func (b *Batcher) Close() error {
+	// The pending Put calls return before the items are closed, as the goroutine keeps reading them
+	b.closing.Lock()
+	if b.closed {
+		b.closing.Unlock()
+		return ErrClosed
+	}
+	b.closed = true
+	close(b.items)
+	b.closing.Unlock()
+
+	<-b.done
+
+	err := b.sink.Close()
+	if failed := b.Err(); failed != nil {
+		return failed
+	}
+	if err != nil {
+		return fmt.Errorf("Close(): cannot close sink: %v", err)
+	}
+	return nil
+}
+
+// run writes the batches until the items channel is closed or a batch fails
+func (b *Batcher) run() {
+	defer close(b.done)
+
+	var tick <-chan time.Time
+	if b.interval > 0 {
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	batch := make([]*ebay.Item, 0, b.size)
+	flush := func() bool {
+		if len(batch) == 0 {
+			return true
+		}
+		err := b.sink.WriteBatch(b.ctx, batch)
+		batch = batch[:0]
+		if err != nil {
+			b.mu.Lock()
+			b.err = err
+			b.mu.Unlock()
+			close(b.failed)
+			return false
+		}
+		return true
+	}
+
+	for {
+		select {
+		case item, ok := <-b.items:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, item)
+			if len(batch) == b.size && !flush() {
+				return
+			}
+		case <-tick:
+			if !flush() {
+				return
+			}
+		}
+	}
+}
+
+// Copy puts the items of the iterator until io.EOF and returns the number of items put.
+// It stops at the first error of the iterator or of the batcher, which is not closed.
+func Copy(b *Batcher, it ebay.ItemIterator) (int, error) {
+	put := 0
+	for {
+		item, err := it.Next()
+		if err == io.EOF {
+			return put, nil
+		}
+		if err != nil {
+			return put, err
+		}
+
+		if err := b.Put(item); err != nil {
+			return put, err
+		}
+		put++
+	}
+}
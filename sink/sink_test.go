@@ -0,0 +1,192 @@
+package sink
+
+import (
+	"context"
+	"ebay-api-client/ebay"
+	"ebay-api-client/ebay/ebaytest"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+// numberedItems returns n items numbered from 1
+func numberedItems(n int) []*ebay.Item {
+	items := make([]*ebay.Item, n)
+	for i := range items {
+		items[i] = &ebay.Item{ID: fmt.Sprintf("v1|%v|0", i+1), Title: "Item"}
+	}
+	return items
+}
+
+// recordingSink records the sizes of the batches, failing from the batch number failAt if not 0
+type recordingSink struct {
+	mu      sync.Mutex
+	batches []int
+	items   int
+	failAt  int
+	closed  bool
+	written chan struct{}
+}
+
+func newRecordingSink() *recordingSink {
+	return &recordingSink{written: make(chan struct{}, 100)}
+}
+
+func (s *recordingSink) WriteBatch(ctx context.Context, items []*ebay.Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failAt > 0 && len(s.batches)+1 >= s.failAt {
+		return errors.New("ingestion failed")
+	}
+	s.batches = append(s.batches, len(items))
+	s.items += len(items)
+	s.written <- struct{}{}
+	return nil
+}
+
+func (s *recordingSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func Test_IsBatcherWritingFullBatches(t *testing.T) {
+	s := newRecordingSink()
+	b := NewBatcher(context.Background(), s, &Options{BatchSize: 4})
+
+	n, err := Copy(b, ebaytest.ItemReader(numberedItems(10)...))
+	assert.NilError(t, err)
+	assert.Equal(t, n, 10)
+	assert.NilError(t, b.Close())
+
+	assert.DeepEqual(t, s.batches, []int{4, 4, 2})
+	assert.Assert(t, s.closed)
+	assert.Equal(t, b.Close(), ErrClosed)
+	assert.Equal(t, b.Put(&ebay.Item{ID: "v1|11|0"}), ErrClosed)
+}
+
+func Test_IsBatcherClosedWhilePutting(t *testing.T) {
+	s := newRecordingSink()
+	b := NewBatcher(context.Background(), s, &Options{BatchSize: 1000, BufferSize: 1})
+
+	var (
+		wg      sync.WaitGroup
+		started sync.WaitGroup
+		mu      sync.Mutex
+		put     int
+	)
+	for n := 0; n < 8; n++ {
+		wg.Add(1)
+		started.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, item := range numberedItems(10000) {
+				if i == 10 {
+					started.Done()
+				}
+				err := b.Put(item)
+				if err == ErrClosed {
+					return
+				}
+				assert.Check(t, err)
+				mu.Lock()
+				put++
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// The Put calls racing with Close return ErrClosed instead of sending on the closed channel
+	started.Wait()
+	assert.NilError(t, b.Close())
+	wg.Wait()
+	assert.Equal(t, s.items, put)
+}
+
+func Test_IsBatcherFlushingOnInterval(t *testing.T) {
+	s := newRecordingSink()
+	b := NewBatcher(context.Background(), s, &Options{BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	defer b.Close()
+
+	assert.NilError(t, b.Put(&ebay.Item{ID: "v1|1|0"}))
+	assert.NilError(t, b.Put(&ebay.Item{ID: "v1|2|0"}))
+
+	select {
+	case <-s.written:
+	case <-time.After(time.Second):
+		t.Fatal("the partial batch is not flushed")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	assert.DeepEqual(t, s.batches, []int{2})
+}
+
+func Test_IsBatcherApplyingBackpressure(t *testing.T) {
+	ch := make(chan *ebay.Item)
+	b := NewBatcher(context.Background(), NewChannelSink(ch), &Options{BatchSize: 2, BufferSize: 2})
+
+	// Nothing is received: one batch is being sent and the buffer is full, so the next Put blocks
+	put := make(chan int)
+	go func() {
+		n := 0
+		for ; n < 10; n++ {
+			if err := b.Put(&ebay.Item{ID: fmt.Sprintf("v1|%v|0", n+1)}); err != nil {
+				break
+			}
+			put <- n + 1
+		}
+		close(put)
+	}()
+
+	got := 0
+	for timeout := false; !timeout; {
+		select {
+		case got = <-put:
+		case <-time.After(50 * time.Millisecond):
+			timeout = true
+		}
+	}
+	assert.Equal(t, got, 4)
+
+	var received []string
+	done := make(chan struct{})
+	go func() {
+		for item := range ch {
+			received = append(received, item.ID)
+		}
+		close(done)
+	}()
+	for range put {
+	}
+	assert.NilError(t, b.Close())
+	<-done
+	assert.Equal(t, len(received), 10)
+	assert.Equal(t, received[9], "v1|10|0")
+}
+
+func Test_IsBatcherPropagatingErrors(t *testing.T) {
+	s := newRecordingSink()
+	s.failAt = 2
+	b := NewBatcher(context.Background(), s, &Options{BatchSize: 3, BufferSize: 1})
+
+	n, err := Copy(b, ebaytest.ItemReader(numberedItems(100)...))
+	assert.ErrorContains(t, err, "ingestion failed")
+	assert.Assert(t, n < 100)
+	assert.ErrorContains(t, b.Put(&ebay.Item{}), "ingestion failed")
+	assert.ErrorContains(t, b.Close(), "ingestion failed")
+	assert.DeepEqual(t, s.batches, []int{3})
+	assert.Assert(t, s.closed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b = NewBatcher(ctx, NewChannelSink(make(chan *ebay.Item)), &Options{BatchSize: 1, BufferSize: 1})
+	cancel()
+	n, err = Copy(b, ebaytest.ItemReader(numberedItems(10)...))
+	assert.Equal(t, err, context.Canceled)
+	assert.Equal(t, n, 0)
+	assert.NilError(t, b.Close())
+}
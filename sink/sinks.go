@@ -0,0 +1,187 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"ebay-api-client/ebay"
+	"ebay-api-client/export"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// WriterSink writes the items with an export writer, i.e. to a JSON Lines or CSV file
+type WriterSink struct {
+	w      export.Writer
+	closer io.Closer
+}
+
+// NewWriterSink creates a new WriterSink. Close closes the export writer then the closer, if not nil.
+func NewWriterSink(w export.Writer, closer io.Closer) *WriterSink {
+	return &WriterSink{w: w, closer: closer}
+}
+
+// WriteBatch writes the items
+func (s *WriterSink) WriteBatch(ctx context.Context, items []*ebay.Item) error {
+	for _, item := range items {
+		if err := s.w.Write(item); err != nil {
+			return fmt.Errorf("WriteBatch(): %v", err)
+		}
+	}
+	return nil
+}
+
+// Close closes the export writer then the closer
+func (s *WriterSink) Close() error {
+	err := s.w.Close()
+	if s.closer != nil {
+		if cerr := s.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// ChannelSink sends the items to a channel, i.e. to an in-process consumer
+type ChannelSink struct {
+	ch chan<- *ebay.Item
+}
+
+// NewChannelSink creates a new ChannelSink. Close closes the channel.
+func NewChannelSink(ch chan<- *ebay.Item) *ChannelSink {
+	return &ChannelSink{ch: ch}
+}
+
+// WriteBatch sends the items, blocking until they are received or the context is cancelled
+func (s *ChannelSink) WriteBatch(ctx context.Context, items []*ebay.Item) error {
+	for _, item := range items {
+		select {
+		case s.ch <- item:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Close closes the channel
+func (s *ChannelSink) Close() error {
+	close(s.ch)
+	return nil
+}
+
+// HTTPSink posts each batch to an ingestion endpoint as JSON Lines, one request per batch
+type HTTPSink struct {
+	// URL is the endpoint the batches are posted to
+	URL string
+	// Client sends the requests, defaults to http.DefaultClient
+	Client ebay.HTTPClient
+	// Header is added to the requests, i.e. for the authorization
+	Header http.Header
+	// Options selects the posted fields and their types. The body is gzip encoded with Options.Gzip.
+	Options *export.Options
+}
+
+// NewHTTPSink creates a new HTTPSink posting the raw items to the URL
+func NewHTTPSink(url string) *HTTPSink {
+	return &HTTPSink{URL: url, Client: http.DefaultClient, Header: make(http.Header), Options: &export.Options{}}
+}
+
+// WriteBatch posts the items, the request fails if the response status is not 2xx
+func (s *HTTPSink) WriteBatch(ctx context.Context, items []*ebay.Item) error {
+	body := new(bytes.Buffer)
+	w, err := export.NewJSONLinesWriter(body, s.Options)
+	if err != nil {
+		return fmt.Errorf("WriteBatch(): %v", err)
+	}
+	for _, item := range items {
+		if err := w.Write(item); err != nil {
+			return fmt.Errorf("WriteBatch(): %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("WriteBatch(): %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL, body)
+	if err != nil {
+		return fmt.Errorf("WriteBatch(): cannot create request: %v", err)
+	}
+	req = req.WithContext(ctx)
+	for key, values := range s.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.Options != nil && s.Options.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("WriteBatch(): cannot post %v items: %v", len(items), err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("WriteBatch(): cannot post %v items: %v %s", len(items), res.Status, bytes.TrimSpace(msg))
+	}
+	_, err = io.Copy(ioutil.Discard, res.Body)
+	return err
+}
+
+// Close does nothing, the batches are posted by WriteBatch
+func (s *HTTPSink) Close() error {
+	return nil
+}
+
+// MultiSink writes the batches to several sinks concurrently
+type MultiSink struct {
+	sinks []ItemSink
+}
+
+// NewMultiSink creates a new MultiSink writing to the sinks
+func NewMultiSink(sinks ...ItemSink) *MultiSink {
+	return &MultiSink{sinks: sinks}
+}
+
+// WriteBatch writes the items to all the sinks and waits for them. It returns the error of the first failed sink,
+// in the sinks order.
+func (m *MultiSink) WriteBatch(ctx context.Context, items []*ebay.Item) error {
+	errs := make([]error, len(m.sinks))
+	var wg sync.WaitGroup
+	for n, s := range m.sinks {
+		wg.Add(1)
+		go func(n int, s ItemSink) {
+			defer wg.Done()
+			errs[n] = s.WriteBatch(ctx, items)
+		}(n, s)
+	}
+	wg.Wait()
+
+	return firstError(errs)
+}
+
+// Close closes all the sinks and returns the error of the first failed sink, in the sinks order
+func (m *MultiSink) Close() error {
+	errs := make([]error, len(m.sinks))
+	for n, s := range m.sinks {
+		errs[n] = s.Close()
+	}
+	return firstError(errs)
+}
+
+func firstError(errs []error) error {
+	for n, err := range errs {
+		if err != nil {
+			return fmt.Errorf("sink %v: %v", n+1, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"ebay-api-client/ebay"
+	"ebay-api-client/export"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+var items = []*ebay.Item{
+	{ID: "v1|1|0", Title: "Red phone", PriceValue: "9.5"},
+	{ID: "v1|2|0", Title: "Laptop", PriceValue: "350"},
+}
+
+// closeRecorder records that it is closed
+type closeRecorder struct{ closed bool }
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func Test_IsWriterSinkWritingItems(t *testing.T) {
+	out := new(bytes.Buffer)
+	w, err := export.NewCSVWriter(out, &export.Options{Fields: []string{"ItemID", "Title"}})
+	assert.NilError(t, err)
+	closer := &closeRecorder{}
+
+	s := NewWriterSink(w, closer)
+	assert.NilError(t, s.WriteBatch(context.Background(), items))
+	assert.NilError(t, s.Close())
+	assert.Equal(t, out.String(), "ID,Title\nv1|1|0,Red phone\nv1|2|0,Laptop\n")
+	assert.Assert(t, closer.closed)
+}
+
+func Test_IsChannelSinkSendingItems(t *testing.T) {
+	ch := make(chan *ebay.Item, len(items))
+	s := NewChannelSink(ch)
+	assert.NilError(t, s.WriteBatch(context.Background(), items))
+	assert.NilError(t, s.Close())
+
+	var got []*ebay.Item
+	for item := range ch {
+		got = append(got, item)
+	}
+	assert.DeepEqual(t, got, items)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, NewChannelSink(make(chan *ebay.Item)).WriteBatch(ctx, items), context.Canceled)
+}
+
+// ingestionServer records the posted items, failing with the given status if not 0
+func ingestionServer(t *testing.T, status int) (*httptest.Server, *[]map[string]interface{}) {
+	var mu sync.Mutex
+	var posted []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != 0 {
+			http.Error(w, "ingestion is down", status)
+			return
+		}
+		assert.Equal(t, r.Header.Get("Content-Type"), "application/x-ndjson")
+		assert.Equal(t, r.Header.Get("Authorization"), "Bearer token")
+
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			assert.NilError(t, err)
+			body = gz
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			item := make(map[string]interface{})
+			assert.NilError(t, json.Unmarshal(scanner.Bytes(), &item))
+			posted = append(posted, item)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	return srv, &posted
+}
+
+func Test_IsHTTPSinkPostingBatches(t *testing.T) {
+	srv, posted := ingestionServer(t, 0)
+	defer srv.Close()
+
+	s := NewHTTPSink(srv.URL)
+	s.Header.Set("Authorization", "Bearer token")
+	s.Options = &export.Options{Fields: []string{"itemId", "priceValue"}, Typed: true, Gzip: true}
+	assert.NilError(t, s.WriteBatch(context.Background(), items))
+	assert.NilError(t, s.Close())
+	assert.DeepEqual(t, *posted, []map[string]interface{}{
+		{"itemId": "v1|1|0", "priceValue": 9.5},
+		{"itemId": "v1|2|0", "priceValue": float64(350)},
+	})
+
+	failing, _ := ingestionServer(t, http.StatusServiceUnavailable)
+	defer failing.Close()
+
+	err := NewHTTPSink(failing.URL).WriteBatch(context.Background(), items)
+	assert.ErrorContains(t, err, "cannot post 2 items: 503 Service Unavailable ingestion is down")
+}
+
+func Test_IsMultiSinkFanningOut(t *testing.T) {
+	srv, posted := ingestionServer(t, 0)
+	defer srv.Close()
+	h := NewHTTPSink(srv.URL)
+	h.Header.Set("Authorization", "Bearer token")
+
+	ch := make(chan *ebay.Item, 10)
+	b := NewBatcher(context.Background(), NewMultiSink(NewChannelSink(ch), h), &Options{BatchSize: 1})
+	for _, item := range items {
+		assert.NilError(t, b.Put(item))
+	}
+	assert.NilError(t, b.Close())
+
+	assert.Equal(t, len(ch), 2)
+	assert.Equal(t, len(*posted), 2)
+
+	failing, _ := ingestionServer(t, http.StatusInternalServerError)
+	defer failing.Close()
+
+	m := NewMultiSink(NewChannelSink(make(chan *ebay.Item, 10)), NewHTTPSink(failing.URL))
+	err := m.WriteBatch(context.Background(), items)
+	assert.ErrorContains(t, err, "sink 2: ")
+	assert.Assert(t, strings.Contains(err.Error(), "500"))
+	assert.NilError(t, m.Close())
+}
@@ -0,0 +1,187 @@
+package store
+
+import (
+	"ebay-api-client/ebay"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// lockSuffix is the suffix of the lock file serializing the updates of a database file
+	lockSuffix = ".lock"
+	// stagingSuffix is the suffix of the copy of a database file being updated
+	stagingSuffix = ".update"
+)
+
+// Update applies fn to a copy of the database file, created if needed, and replaces the file with the copy when fn
+// succeeds. The readers of the file (see OpenReadOnly and Reloader) are never blocked by the update and never see
+// a partial one. The concurrent updates of the same file are serialized by a lock file, the file path + ".lock".
+func Update(path string, fn func(db *DB) error) error {
+	lock, err := bolt.Open(path+lockSuffix, 0644, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return fmt.Errorf("Update(): cannot lock %v: %v", path, err)
+	}
+	defer lock.Close()
+
+	staging := path + stagingSuffix
+	if err := copyFile(path, staging); err != nil {
+		return fmt.Errorf("Update(): cannot copy %v: %v", path, err)
+	}
+
+	db, err := Open(staging)
+	if err != nil {
+		os.Remove(staging)
+		return fmt.Errorf("Update(): %v", err)
+	}
+	if err := fn(db); err != nil {
+		db.Close()
+		os.Remove(staging)
+		return err
+	}
+	if err := db.Close(); err != nil {
+		os.Remove(staging)
+		return fmt.Errorf("Update(): cannot close %v: %v", staging, err)
+	}
+
+	if err := os.Rename(staging, path); err != nil {
+		os.Remove(staging)
+		return fmt.Errorf("Update(): cannot replace %v: %v", path, err)
+	}
+	return nil
+}
+
+// copyFile writes a consistent copy of the database file to dst, it only removes dst if the file does not exist
+func copyFile(path, dst string) error {
+	// dst is the leftover of a crashed update if it exists
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	b, err := bolt.Open(path, 0644, &bolt.Options{Timeout: openTimeout, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	return b.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(dst, 0644)
+	})
+}
+
+// Reloader reads a database file opened with OpenReadOnly and reopens it when the file is replaced, i.e. by Update,
+// so that a long running process serves the updates made by other processes. The reads can be concurrent with Reload.
+type Reloader struct {
+	path string
+
+	mu   sync.RWMutex
+	db   *DB
+	info os.FileInfo
+}
+
+// OpenReloader opens the database file for reading only
+func OpenReloader(path string) (*Reloader, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("OpenReloader(): %v", err)
+	}
+	db, err := OpenReadOnly(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Reloader{path: path, db: db, info: info}, nil
+}
+
+// Reload reopens the database file if it has been replaced since it was opened, and reports whether it did.
+// The replaced file is closed once the pending reads are done.
+func (r *Reloader) Reload() (bool, error) {
+	// The file is checked before being opened, so that a replacement in the meanwhile is reloaded next time
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false, fmt.Errorf("Reload(): %v", err)
+	}
+
+	r.mu.RLock()
+	same := os.SameFile(info, r.info)
+	r.mu.RUnlock()
+	if same {
+		return false, nil
+	}
+
+	db, err := OpenReadOnly(r.path)
+	if err != nil {
+		return false, fmt.Errorf("Reload(): %v", err)
+	}
+
+	r.mu.Lock()
+	replaced := r.db
+	r.db, r.info = db, info
+	r.mu.Unlock()
+
+	if err := replaced.Close(); err != nil {
+		return true, fmt.Errorf("Reload(): cannot close replaced file: %v", err)
+	}
+	return true, nil
+}
+
+// Close closes the database file
+func (r *Reloader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.db.Close()
+}
+
+// Item returns the item with the given id, see DB.Item
+func (r *Reloader) Item(id string) (*ebay.Item, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.db.Item(id)
+}
+
+// Lookup returns the items whose indexed field has the given value, see DB.Lookup
+func (r *Reloader) Lookup(field, value, after string, limit int) ([]*ebay.Item, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.db.Lookup(field, value, after, limit)
+}
+
+// Scan calls fn for each item row with an id greater than after, see DB.Scan
+func (r *Reloader) Scan(after string, fn func(id, row string) error) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.db.Scan(after, fn)
+}
+
+// Counts returns the number of items by value of the indexed field, see DB.Counts
+func (r *Reloader) Counts(field string) (map[string]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.db.Counts(field)
+}
+
+// LastBatch returns the last loaded batch, see DB.LastBatch
+func (r *Reloader) LastBatch() (*Batch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.db.LastBatch()
+}
+
+// Watermark returns the time up to which the changes are applied, see DB.Watermark
+func (r *Reloader) Watermark() (time.Time, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.db.Watermark()
+}
+
+// Len returns the number of items, see DB.Len
+func (r *Reloader) Len() (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.db.Len()
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"ebay-api-client/ebay"
+	"ebay-api-client/ebay/ebaytest"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func Test_IsUpdateReplacingFileReadByReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "items.db")
+
+	err = Update(path, func(db *DB) error {
+		_, err := db.Load(ebaytest.ItemReader(bootstrap...), nil)
+		return err
+	})
+	assert.NilError(t, err)
+
+	r, err := OpenReloader(path)
+	assert.NilError(t, err)
+	defer r.Close()
+
+	reloaded, err := r.Reload()
+	assert.NilError(t, err)
+	assert.Assert(t, !reloaded)
+
+	// The file is updated while it is read
+	err = Update(path, func(db *DB) error {
+		_, err := db.Upsert(ebaytest.ItemReader(&ebay.Item{ID: "v1|4|0", Title: "Tablet", SellerUsername: "alice"}), nil)
+		return err
+	})
+	assert.NilError(t, err)
+
+	size, err := r.Len()
+	assert.NilError(t, err)
+	assert.Equal(t, size, 3)
+
+	reloaded, err = r.Reload()
+	assert.NilError(t, err)
+	assert.Assert(t, reloaded)
+
+	items, err := r.Lookup("SellerUsername", "alice", "", 0)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ids(items), []string{"v1|1|0", "v1|3|0", "v1|4|0"})
+	batch, err := r.LastBatch()
+	assert.NilError(t, err)
+	assert.Equal(t, batch.Mode, ModeUpsert)
+
+	// A failed update leaves the file as it is
+	err = Update(path, func(db *DB) error {
+		if err := db.Clear(); err != nil {
+			return err
+		}
+		return errors.New("failed")
+	})
+	assert.Error(t, err, "failed")
+
+	reloaded, err = r.Reload()
+	assert.NilError(t, err)
+	assert.Assert(t, !reloaded)
+	size, err = r.Len()
+	assert.NilError(t, err)
+	assert.Equal(t, size, 4)
+
+	_, err = os.Stat(path + stagingSuffix)
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func Test_IsOpenReadOnlyFailingWithoutBuckets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = OpenReloader(filepath.Join(dir, "missing.db"))
+	assert.ErrorContains(t, err, "no such file")
+
+	// A database file with all the buckets is created by Open
+	path := filepath.Join(dir, "items.db")
+	db, err := Open(path)
+	assert.NilError(t, err)
+	_, err = OpenReadOnly(path)
+	assert.ErrorContains(t, err, "timeout")
+	assert.NilError(t, db.Close())
+
+	db, err = OpenReadOnly(path)
+	assert.NilError(t, err)
+	assert.NilError(t, db.Close())
+}
@@ -0,0 +1,675 @@
+// Package store keeps the feed items in an embedded bbolt database file, so that they can be looked up by id and by
+// the secondary indexes (LegacyItemID, GTIN, EPID, MPN, SellerUsername and CategoryID) without parsing the feed files
+// again. The items are loaded from item streams, i.e. a bootstrap replacing the content, then the snapshots upserting and
+// deleting items; each loaded batch is recorded with the FeedInfo of its feed. DB implements inventory.Store.
+// A file can be read by long running processes with a Reloader while other processes update it with Update.
+package store
+
+import (
+	"bytes"
+	"ebay-api-client/ebay"
+	"ebay-api-client/inventory"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// batchSize is the number of items written in a transaction by Load and Upsert
+	batchSize = 10000
+	// openTimeout is how long Open waits for the lock of a database used by another process
+	openTimeout = time.Second
+)
+
+var (
+	itemsBucket   = []byte("items")
+	batchesBucket = []byte("batches")
+	metaBucket    = []byte("meta")
+	watermarkKey  = []byte("watermark")
+	generationKey = []byte("generation")
+)
+
+// IndexedFields are the Item fields with a secondary index
+var IndexedFields = []string{"LegacyItemID", "GTIN", "EPID", "MPN", "SellerUsername", "CategoryID"}
+
+// ErrNotIndexed is returned by Lookup when the field has no index
+var ErrNotIndexed = errors.New("field is not indexed")
+
+// Mode is the way a batch has been loaded
+type Mode string
+
+const (
+	// ModeLoad is a batch replacing the content of the store, i.e. a bootstrap
+	ModeLoad Mode = "load"
+	// ModeUpsert is a batch inserting, replacing and deleting items, i.e. a snapshot
+	ModeUpsert Mode = "upsert"
+)
+
+// Batch is a loaded item stream
+type Batch struct {
+	// Seq is the sequence number of the batch, increasing with each batch
+	Seq uint64
+	// Mode is the way the batch has been loaded
+	Mode Mode
+	// Info describes the feed of the items, it is empty if not given
+	Info ebay.FeedInfo
+	// LoadedAt is when the batch has been loaded
+	LoadedAt time.Time
+	// Put is the number of items inserted or replaced
+	Put int
+	// Deleted is the number of items deleted
+	Deleted int
+}
+
+// DB is an item store backed by a bbolt database file
+type DB struct {
+	bolt *bolt.DB
+	// Removed reports whether an upserted item deletes the item from the store, defaults to inventory.IsRemoved
+	Removed func(item *ebay.Item) bool
+
+	now func() time.Time
+}
+
+var _ inventory.Store = (*DB)(nil)
+
+// Open opens the database file, creating it if needed. The indexes missing in the file, i.e. created by a previous
+// version, are built. It fails if the file is used by another process.
+func Open(path string) (*DB, error) {
+	b, err := bolt.Open(path, 0644, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("Open(): cannot open %v: %v", path, err)
+	}
+
+	err = b.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(metaBucket); err != nil {
+			return err
+		}
+		c := current(tx)
+		for _, name := range [][]byte{itemsBucket, batchesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(c.name(name)); err != nil {
+				return err
+			}
+		}
+		for _, field := range IndexedFields {
+			if c.index(field) != nil {
+				continue
+			}
+			if err := buildIndex(c, field); err != nil {
+				return err
+			}
+		}
+		// The next generation is left by a Load which failed
+		return content{tx: tx, gen: c.gen + 1}.drop()
+	})
+	if err != nil {
+		b.Close()
+		return nil, fmt.Errorf("Open(): cannot create buckets: %v", err)
+	}
+
+	return &DB{bolt: b, Removed: inventory.IsRemoved, now: time.Now}, nil
+}
+
+// OpenReadOnly opens the existing database file for reading only. Several processes can read the same file, but
+// the file cannot be opened by Open meanwhile: the writers update a copy of the file instead (see Update).
+func OpenReadOnly(path string) (*DB, error) {
+	b, err := bolt.Open(path, 0644, &bolt.Options{Timeout: openTimeout, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("OpenReadOnly(): cannot open %v: %v", path, err)
+	}
+
+	err = b.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(metaBucket) == nil {
+			return fmt.Errorf("bucket %s is missing, the file has to be opened by Open first", metaBucket)
+		}
+		c := current(tx)
+		for _, name := range contentBuckets() {
+			if tx.Bucket(c.name(name)) == nil {
+				return fmt.Errorf("bucket %s is missing, the file has to be opened by Open first", c.name(name))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		b.Close()
+		return nil, fmt.Errorf("OpenReadOnly(): %v: %v", path, err)
+	}
+
+	return &DB{bolt: b, Removed: inventory.IsRemoved, now: time.Now}, nil
+}
+
+// Close closes the database file
+func (db *DB) Close() error {
+	return db.bolt.Close()
+}
+
+func indexBucket(field string) []byte {
+	return []byte("index/" + field)
+}
+
+// contentBuckets returns the names of the buckets of the content: the items, the batches and the indexes
+func contentBuckets() [][]byte {
+	buckets := [][]byte{itemsBucket, batchesBucket}
+	for _, field := range IndexedFields {
+		buckets = append(buckets, indexBucket(field))
+	}
+	return buckets
+}
+
+// content are the buckets of a generation of the store content. Load fills the buckets of the next generation,
+// then makes it the current one in its last transaction, so that the content is never partially replaced.
+type content struct {
+	tx  *bolt.Tx
+	gen uint64
+}
+
+// current returns the current content, its generation is 0 in the files written by the previous versions
+func current(tx *bolt.Tx) content {
+	c := content{tx: tx}
+	if v := tx.Bucket(metaBucket).Get(generationKey); len(v) == 8 {
+		c.gen = binary.BigEndian.Uint64(v)
+	}
+	return c
+}
+
+// name returns the name of the bucket in the generation of the content
+func (c content) name(bucket []byte) []byte {
+	if c.gen == 0 {
+		return bucket
+	}
+	return []byte(fmt.Sprintf("%s@%v", bucket, c.gen))
+}
+
+func (c content) items() *bolt.Bucket {
+	return c.tx.Bucket(c.name(itemsBucket))
+}
+
+func (c content) batches() *bolt.Bucket {
+	return c.tx.Bucket(c.name(batchesBucket))
+}
+
+func (c content) index(field string) *bolt.Bucket {
+	return c.tx.Bucket(c.name(indexBucket(field)))
+}
+
+// create creates the empty buckets of the content
+func (c content) create() error {
+	for _, name := range contentBuckets() {
+		if _, err := c.tx.CreateBucket(c.name(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drop deletes the buckets of the content which exist
+func (c content) drop() error {
+	for _, name := range contentBuckets() {
+		if err := c.tx.DeleteBucket(c.name(name)); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildIndex creates the index of the field from the stored items
+func buildIndex(c content, field string) error {
+	index, err := c.tx.CreateBucket(c.name(indexBucket(field)))
+	if err != nil {
+		return err
+	}
+
+	return c.items().ForEach(func(k, v []byte) error {
+		if value, _ := ebay.NewItemFromTSV(string(v)).Field(field); value != "" {
+			return index.Put(indexKey(value, string(k)), nil)
+		}
+		return nil
+	})
+}
+
+// indexKey is the key of the item in a secondary index: the lower case value, a zero byte, then the item id
+func indexKey(value, id string) []byte {
+	return []byte(strings.ToLower(value) + "\x00" + id)
+}
+
+// put inserts or replaces the row of the item, updating the secondary indexes
+func put(c content, id, row string) error {
+	if err := unindex(c, id); err != nil {
+		return err
+	}
+	if err := c.items().Put([]byte(id), []byte(row)); err != nil {
+		return err
+	}
+
+	item := ebay.NewItemFromTSV(row)
+	for _, field := range IndexedFields {
+		if value, _ := item.Field(field); value != "" {
+			if err := c.index(field).Put(indexKey(value, id), nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// remove deletes the item and its secondary index entries, it returns false if the item is not in the store
+func remove(c content, id string) (bool, error) {
+	if c.items().Get([]byte(id)) == nil {
+		return false, nil
+	}
+	if err := unindex(c, id); err != nil {
+		return false, err
+	}
+	return true, c.items().Delete([]byte(id))
+}
+
+// unindex removes the secondary index entries of the stored item
+func unindex(c content, id string) error {
+	row := c.items().Get([]byte(id))
+	if row == nil {
+		return nil
+	}
+
+	item := ebay.NewItemFromTSV(string(row))
+	for _, field := range IndexedFields {
+		if value, _ := item.Field(field); value != "" {
+			if err := c.index(field).Delete(indexKey(value, id)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Load replaces the content of the store with the items of the stream, i.e. a bootstrap, and records the batch
+// with the feed info (it can be nil). The watermark is reset. The items are written into a new content by
+// transactions of batchSize items, which replaces the content in the last transaction: if Load fails, the store
+// keeps its previous content.
+func (db *DB) Load(it ebay.ItemIterator, info *ebay.FeedInfo) (*Batch, error) {
+	var gen uint64
+	err := db.bolt.Update(func(tx *bolt.Tx) error {
+		next := content{tx: tx, gen: current(tx).gen + 1}
+		gen = next.gen
+		if err := next.drop(); err != nil {
+			return err
+		}
+		return next.create()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Load(): cannot create buckets: %v", err)
+	}
+
+	staging := func(tx *bolt.Tx) content {
+		return content{tx: tx, gen: gen}
+	}
+	replace := func(tx *bolt.Tx) error {
+		previous := current(tx)
+		meta := tx.Bucket(metaBucket)
+		if err := meta.Put(generationKey, seqKey(gen)); err != nil {
+			return err
+		}
+		if err := meta.Delete(watermarkKey); err != nil {
+			return err
+		}
+		return previous.drop()
+	}
+
+	batch, err := db.ingest(it, info, ModeLoad, staging, replace)
+	if err != nil {
+		db.bolt.Update(func(tx *bolt.Tx) error {
+			return staging(tx).drop()
+		})
+		return nil, fmt.Errorf("Load(): %v", err)
+	}
+	return batch, nil
+}
+
+// Upsert inserts or replaces the items of the stream, i.e. a snapshot, and records the batch with the feed info
+// (it can be nil). The items for which Removed returns true are deleted. If Upsert fails, the store keeps the
+// changes written so far and the batch is not recorded.
+func (db *DB) Upsert(it ebay.ItemIterator, info *ebay.FeedInfo) (*Batch, error) {
+	batch, err := db.ingest(it, info, ModeUpsert, current, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Upsert(): %v", err)
+	}
+	return batch, nil
+}
+
+// ingest writes the items into the content returned by contentOf, then records the batch in the same transaction
+// as commit if not nil
+func (db *DB) ingest(it ebay.ItemIterator, info *ebay.FeedInfo, mode Mode, contentOf func(tx *bolt.Tx) content, commit func(tx *bolt.Tx) error) (*Batch, error) {
+	batch := &Batch{Mode: mode}
+	if info != nil {
+		batch.Info = *info
+	}
+
+	removed := db.Removed
+	if removed == nil || mode == ModeLoad {
+		removed = func(*ebay.Item) bool { return false }
+	}
+
+	items := make([]*ebay.Item, 0, batchSize)
+	flush := func() error {
+		if len(items) == 0 {
+			return nil
+		}
+		err := db.bolt.Update(func(tx *bolt.Tx) error {
+			c := contentOf(tx)
+			for _, item := range items {
+				if removed(item) {
+					ok, err := remove(c, item.ID)
+					if err != nil {
+						return err
+					}
+					if ok {
+						batch.Deleted++
+					}
+					continue
+				}
+				if err := put(c, item.ID, strings.Join(item.Values(), "\t")); err != nil {
+					return err
+				}
+				batch.Put++
+			}
+			return nil
+		})
+		items = items[:0]
+		return err
+	}
+
+	for {
+		item, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if item.ID == "" {
+			continue
+		}
+
+		items = append(items, item)
+		if len(items) == batchSize {
+			if err := flush(); err != nil {
+				return nil, fmt.Errorf("cannot write items: %v", err)
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, fmt.Errorf("cannot write items: %v", err)
+	}
+
+	err := db.bolt.Update(func(tx *bolt.Tx) error {
+		b := contentOf(tx).batches()
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		batch.Seq, batch.LoadedAt = seq, db.now().UTC()
+
+		data, err := json.Marshal(batch)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(seqKey(seq), data); err != nil {
+			return err
+		}
+		if commit != nil {
+			return commit(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot record batch: %v", err)
+	}
+	return batch, nil
+}
+
+func seqKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// Batches returns the batches loaded since the last Load or Clear, in loading order
+func (db *DB) Batches() ([]*Batch, error) {
+	var batches []*Batch
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		return current(tx).batches().ForEach(func(k, v []byte) error {
+			batch := &Batch{}
+			if err := json.Unmarshal(v, batch); err != nil {
+				return err
+			}
+			batches = append(batches, batch)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Batches(): cannot read batches: %v", err)
+	}
+	return batches, nil
+}
+
+// LastBatch returns the last loaded batch, nil if no batch has been loaded since the last Load or Clear
+func (db *DB) LastBatch() (*Batch, error) {
+	var batch *Batch
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		if _, v := current(tx).batches().Cursor().Last(); v != nil {
+			batch = &Batch{}
+			return json.Unmarshal(v, batch)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("LastBatch(): cannot read batch: %v", err)
+	}
+	return batch, nil
+}
+
+// Item returns the item with the given id, false if the item is not in the store
+func (db *DB) Item(id string) (*ebay.Item, bool, error) {
+	row, ok, err := db.Get(id)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	return ebay.NewItemFromTSV(row), true, nil
+}
+
+// Lookup returns the items whose indexed field (see IndexedFields) has the given value, ignoring the case, ordered
+// by id and with an id greater than after, to read the items by pages. At most limit items are returned, all if
+// limit is 0. ErrNotIndexed is returned if the field has no index.
+func (db *DB) Lookup(field, value, after string, limit int) ([]*ebay.Item, error) {
+	indexed, ok := indexedField(field)
+	if !ok {
+		return nil, ErrNotIndexed
+	}
+
+	var items []*ebay.Item
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		rows := current(tx).items()
+		prefix := indexKey(value, "")
+		start := indexKey(value, after)
+
+		c := current(tx).index(indexed).Cursor()
+		k, _ := c.Seek(start)
+		if k != nil && after != "" && bytes.Equal(k, start) {
+			k, _ = c.Next()
+		}
+		for ; k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			if limit > 0 && len(items) == limit {
+				break
+			}
+			if row := rows.Get(k[len(prefix):]); row != nil {
+				items = append(items, ebay.NewItemFromTSV(string(row)))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Lookup(): %v", err)
+	}
+	return items, nil
+}
+
+// Counts returns the number of items by value of the indexed field (see IndexedFields), the values being lower case.
+// The items without value are not counted. ErrNotIndexed is returned if the field has no index.
+func (db *DB) Counts(field string) (map[string]int, error) {
+	indexed, ok := indexedField(field)
+	if !ok {
+		return nil, ErrNotIndexed
+	}
+
+	counts := make(map[string]int)
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		return current(tx).index(indexed).ForEach(func(k, _ []byte) error {
+			if n := bytes.IndexByte(k, 0); n >= 0 {
+				counts[string(k[:n])]++
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Counts(): %v", err)
+	}
+	return counts, nil
+}
+
+// indexedField returns the name of the indexed field matching the given name, ignoring the case
+func indexedField(name string) (string, bool) {
+	for _, f := range IndexedFields {
+		if strings.EqualFold(f, name) {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+// Scan calls fn for each item row whose id is greater than after, ordered by id, all the rows if after is empty.
+// It stops at the first error, io.EOF stopping it without error. The store must not be modified by fn.
+func (db *DB) Scan(after string, fn func(id, row string) error) error {
+	return db.bolt.View(func(tx *bolt.Tx) error {
+		c := current(tx).items().Cursor()
+		k, v := c.Seek([]byte(after))
+		if k != nil && string(k) == after {
+			k, v = c.Next()
+		}
+		for ; k != nil; k, v = c.Next() {
+			if err := fn(string(k), string(v)); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get returns the feed row of the item with the given id, false if the item is not in the store
+func (db *DB) Get(id string) (string, bool, error) {
+	var row string
+	var ok bool
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		if v := current(tx).items().Get([]byte(id)); v != nil {
+			row, ok = string(v), true
+		}
+		return nil
+	})
+	return row, ok, err
+}
+
+// Put inserts or replaces the feed row of the item with the given id, updating the secondary indexes.
+// Each call is a transaction, use Load, Upsert or WriteBatch to write many items.
+func (db *DB) Put(id, row string) error {
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		return put(current(tx), id, row)
+	})
+}
+
+// Delete removes the item with the given id, it does nothing if the item is not in the store
+func (db *DB) Delete(id string) error {
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		_, err := remove(current(tx), id)
+		return err
+	})
+}
+
+// WriteBatch applies the changes in a single transaction, updating the secondary indexes
+func (db *DB) WriteBatch(changes []inventory.Change) error {
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		content := current(tx)
+		for _, c := range changes {
+			var err error
+			if c.Delete {
+				_, err = remove(content, c.ID)
+			} else {
+				err = put(content, c.ID, c.Row)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Len returns the number of items in the store
+func (db *DB) Len() (int, error) {
+	n := 0
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		n = current(tx).items().Stats().KeyN
+		return nil
+	})
+	return n, err
+}
+
+// ForEach calls fn for each item row, ordered by id, stopping at the first error. The store must not be modified by fn.
+func (db *DB) ForEach(fn func(id, row string) error) error {
+	return db.bolt.View(func(tx *bolt.Tx) error {
+		return current(tx).items().ForEach(func(k, v []byte) error {
+			return fn(string(k), string(v))
+		})
+	})
+}
+
+// Clear removes all the items, the indexes and the batches and resets the watermark
+func (db *DB) Clear() error {
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		c := current(tx)
+		if err := c.drop(); err != nil {
+			return err
+		}
+		if err := c.create(); err != nil {
+			return err
+		}
+		return tx.Bucket(metaBucket).Delete(watermarkKey)
+	})
+}
+
+// Watermark returns the time up to which the changes are applied, zero if unknown
+func (db *DB) Watermark() (time.Time, error) {
+	var t time.Time
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(metaBucket).Get(watermarkKey); v != nil {
+			return t.UnmarshalText(v)
+		}
+		return nil
+	})
+	return t, err
+}
+
+// SetWatermark sets the time up to which the changes are applied
+func (db *DB) SetWatermark(t time.Time) error {
+	data, err := t.MarshalText()
+	if err != nil {
+		return err
+	}
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(metaBucket).Put(watermarkKey, data)
+	})
+}
@@ -0,0 +1,336 @@
+package store
+
+import (
+	"bytes"
+	"ebay-api-client/ebay"
+	"ebay-api-client/ebay/ebaytest"
+	"ebay-api-client/inventory"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"gotest.tools/v3/assert"
+)
+
+var bootstrap = []*ebay.Item{
+	{ID: "v1|1|0", LegacyItemID: "1", Title: "Red phone", GTIN: "0190198459558", EPID: "94740293", MPN: "MQAM2LL/A", SellerUsername: "Alice", PriceValue: "9.5"},
+	{ID: "v1|2|0", LegacyItemID: "2", Title: "Blue phone", GTIN: "0190198459558", MPN: "mqam2ll/a", SellerUsername: "bob", PriceValue: "12"},
+	{ID: "v1|3|0", LegacyItemID: "3", Title: "Laptop", EPID: "1234", SellerUsername: "alice", PriceValue: "350"},
+}
+
+// newTestDB opens a database in a temporary directory, returning its path and the cleanup function
+func newTestDB(t *testing.T) (*DB, string, func()) {
+	dir, err := ioutil.TempDir("", "store")
+	assert.NilError(t, err)
+
+	path := filepath.Join(dir, "items.db")
+	db, err := Open(path)
+	assert.NilError(t, err)
+	db.now = func() time.Time { return time.Date(2020, 5, 5, 10, 0, 0, 0, time.UTC) }
+
+	return db, path, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// ids returns the ids of the items
+func ids(items []*ebay.Item) []string {
+	var ids []string
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func Test_IsStoreLookingUpIndexedFields(t *testing.T) {
+	db, _, cleanup := newTestDB(t)
+	defer cleanup()
+
+	info := &ebay.FeedInfo{Type: "bootstrap", CategoryID: "9355", MarketID: "EBAY_US", LastModified: time.Date(2020, 5, 5, 9, 0, 0, 0, time.UTC)}
+	batch, err := db.Load(ebaytest.ItemReader(bootstrap...), info)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, batch, &Batch{Seq: 1, Mode: ModeLoad, Info: *info, LoadedAt: db.now(), Put: 3})
+
+	item, ok, err := db.Item("v1|2|0")
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, item, bootstrap[1])
+
+	_, ok, err = db.Item("v1|9|0")
+	assert.NilError(t, err)
+	assert.Assert(t, !ok)
+
+	tests := []struct {
+		field string
+		value string
+		after string
+		limit int
+		want  []string
+	}{
+		{"LegacyItemID", "3", "", 0, []string{"v1|3|0"}},
+		{"gtin", "0190198459558", "", 0, []string{"v1|1|0", "v1|2|0"}},
+		{"GTIN", "0190198459558", "", 1, []string{"v1|1|0"}},
+		{"GTIN", "0190198459558", "v1|1|0", 1, []string{"v1|2|0"}},
+		{"GTIN", "0190198459558", "v1|2|0", 0, nil},
+		{"SellerUsername", "alice", "v1|2|0", 0, []string{"v1|3|0"}},
+		{"EPID", "94740293", "", 0, []string{"v1|1|0"}},
+		{"MPN", "MQAM2LL/A", "", 0, []string{"v1|1|0", "v1|2|0"}},
+		{"SellerUsername", "ALICE", "", 0, []string{"v1|1|0", "v1|3|0"}},
+		{"SellerUsername", "ali", "", 0, nil},
+		{"EPID", "", "", 0, nil},
+	}
+	for _, tt := range tests {
+		items, err := db.Lookup(tt.field, tt.value, tt.after, tt.limit)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, ids(items), tt.want)
+	}
+
+	_, err = db.Lookup("Title", "Laptop", "", 0)
+	assert.Equal(t, err, ErrNotIndexed)
+}
+
+func Test_IsStoreUpsertingAndDeletingItems(t *testing.T) {
+	db, path, cleanup := newTestDB(t)
+	defer cleanup()
+
+	_, err := db.Load(ebaytest.ItemReader(bootstrap...), nil)
+	assert.NilError(t, err)
+
+	snapshot := []*ebay.Item{
+		{ID: "v1|1|0", LegacyItemID: "1", Title: "Red phone", GTIN: "0888462064101", SellerUsername: "alice", PriceValue: "8"},
+		{ID: "v1|2|0", Availability: "UNAVAILABLE"},
+		{ID: "v1|4|0", LegacyItemID: "4", Title: "Tablet", GTIN: "0888462064101", SellerUsername: "carol", PriceValue: "200"},
+		{ID: "v1|5|0"},
+	}
+	info := &ebay.FeedInfo{Type: "snapshot", LastModified: time.Date(2020, 5, 5, 9, 30, 0, 0, time.UTC)}
+	batch, err := db.Upsert(ebaytest.ItemReader(snapshot...), info)
+	assert.NilError(t, err)
+	assert.Equal(t, batch.Seq, uint64(2))
+	assert.Equal(t, batch.Put, 2)
+	assert.Equal(t, batch.Deleted, 1)
+
+	n, err := db.Len()
+	assert.NilError(t, err)
+	assert.Equal(t, n, 3)
+
+	// The index entries of the replaced and deleted items are removed
+	items, err := db.Lookup("GTIN", "0190198459558", "", 0)
+	assert.NilError(t, err)
+	assert.Equal(t, len(items), 0)
+
+	items, err = db.Lookup("GTIN", "0888462064101", "", 0)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ids(items), []string{"v1|1|0", "v1|4|0"})
+
+	items, err = db.Lookup("MPN", "mqam2ll/a", "", 0)
+	assert.NilError(t, err)
+	assert.Equal(t, len(items), 0)
+
+	assert.NilError(t, db.Delete("v1|4|0"))
+	assert.NilError(t, db.Delete("v1|4|0"))
+	items, err = db.Lookup("SellerUsername", "carol", "", 0)
+	assert.NilError(t, err)
+	assert.Equal(t, len(items), 0)
+
+	// The items, indexes and batches survive a restart
+	assert.NilError(t, db.SetWatermark(time.Date(2020, 5, 5, 10, 0, 0, 0, time.UTC)))
+	assert.NilError(t, db.Close())
+
+	db, err = Open(path)
+	assert.NilError(t, err)
+
+	items, err = db.Lookup("SellerUsername", "alice", "", 0)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ids(items), []string{"v1|1|0", "v1|3|0"})
+	assert.Equal(t, items[0].PriceValue, "8")
+
+	batches, err := db.Batches()
+	assert.NilError(t, err)
+	assert.Equal(t, len(batches), 2)
+	assert.Equal(t, batches[0].Mode, ModeLoad)
+	assert.Equal(t, batches[1].Mode, ModeUpsert)
+	assert.DeepEqual(t, batches[1].Info, *info)
+
+	watermark, err := db.Watermark()
+	assert.NilError(t, err)
+	assert.Equal(t, watermark, time.Date(2020, 5, 5, 10, 0, 0, 0, time.UTC))
+
+	// Load replaces the content and the batches
+	_, err = db.Load(ebaytest.ItemReader(bootstrap[2]), nil)
+	assert.NilError(t, err)
+
+	n, err = db.Len()
+	assert.NilError(t, err)
+	assert.Equal(t, n, 1)
+
+	batches, err = db.Batches()
+	assert.NilError(t, err)
+	assert.Equal(t, len(batches), 1)
+
+	watermark, err = db.Watermark()
+	assert.NilError(t, err)
+	assert.Assert(t, watermark.IsZero())
+}
+
+// failingIterator returns the items then the error
+type failingIterator struct {
+	items []*ebay.Item
+	err   error
+}
+
+func (f *failingIterator) Next() (*ebay.Item, error) {
+	if len(f.items) == 0 {
+		return nil, f.err
+	}
+	item := f.items[0]
+	f.items = f.items[1:]
+	return item, nil
+}
+
+func Test_IsFailedLoadKeepingContent(t *testing.T) {
+	db, _, cleanup := newTestDB(t)
+	defer cleanup()
+
+	_, err := db.Load(ebaytest.ItemReader(bootstrap...), nil)
+	assert.NilError(t, err)
+	watermark := time.Date(2020, 5, 5, 9, 0, 0, 0, time.UTC)
+	assert.NilError(t, db.SetWatermark(watermark))
+
+	_, err = db.Load(&failingIterator{items: []*ebay.Item{{ID: "v1|9|0", Title: "Mouse"}}, err: errors.New("truncated")}, nil)
+	assert.ErrorContains(t, err, "truncated")
+
+	n, err := db.Len()
+	assert.NilError(t, err)
+	assert.Equal(t, n, 3)
+	items, err := db.Lookup("SellerUsername", "alice", "", 0)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ids(items), []string{"v1|1|0", "v1|3|0"})
+	batches, err := db.Batches()
+	assert.NilError(t, err)
+	assert.Equal(t, len(batches), 1)
+	got, err := db.Watermark()
+	assert.NilError(t, err)
+	assert.Equal(t, got, watermark)
+
+	// The buckets of the failed Load are dropped, the next Load replaces the content
+	var buckets []string
+	assert.NilError(t, db.bolt.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			buckets = append(buckets, string(name))
+			return nil
+		})
+	}))
+	assert.Equal(t, len(buckets), len(contentBuckets())+1)
+
+	_, err = db.Load(ebaytest.ItemReader(&ebay.Item{ID: "v1|9|0", Title: "Mouse"}), nil)
+	assert.NilError(t, err)
+	n, err = db.Len()
+	assert.NilError(t, err)
+	assert.Equal(t, n, 1)
+	got, err = db.Watermark()
+	assert.NilError(t, err)
+	assert.Assert(t, got.IsZero())
+}
+
+func Test_IsStoreLockedByAnotherProcess(t *testing.T) {
+	_, path, cleanup := newTestDB(t)
+	defer cleanup()
+
+	_, err := Open(path)
+	assert.ErrorContains(t, err, "cannot open")
+}
+
+func Test_IsStoreBackingInventory(t *testing.T) {
+	db, _, cleanup := newTestDB(t)
+	defer cleanup()
+
+	inv := inventory.New(db)
+	generatedAt := time.Date(2020, 5, 5, 9, 0, 0, 0, time.UTC)
+	n, err := inv.LoadBootstrap(bytes.NewReader(ebaytest.ItemFeed(bootstrap...)), generatedAt)
+	assert.NilError(t, err)
+	assert.Equal(t, n, 3)
+
+	snapshot := ebaytest.ItemFeed(&ebay.Item{ID: "v1|3|0", Availability: "OUT_OF_STOCK"})
+	changes, err := inv.ApplySnapshot(bytes.NewReader(snapshot), generatedAt)
+	assert.NilError(t, err)
+	assert.Equal(t, changes.Removed, 1)
+
+	items, err := db.Lookup("SellerUsername", "alice", "", 0)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ids(items), []string{"v1|1|0"})
+
+	var got []string
+	assert.NilError(t, db.ForEach(func(id, row string) error {
+		got = append(got, id)
+		return nil
+	}))
+	assert.DeepEqual(t, got, []string{"v1|1|0", "v1|2|0"})
+}
+
+func Test_IsStoreScanningAndCountingItems(t *testing.T) {
+	db, path, cleanup := newTestDB(t)
+	defer cleanup()
+
+	batch, err := db.LastBatch()
+	assert.NilError(t, err)
+	assert.Assert(t, batch == nil)
+
+	items := []*ebay.Item{
+		{ID: "v1|1|0", CategoryID: "9355"},
+		{ID: "v1|2|0", CategoryID: "9355"},
+		{ID: "v1|3|0", CategoryID: "177"},
+		{ID: "v1|4|0"},
+	}
+	_, err = db.Load(ebaytest.ItemReader(items...), nil)
+	assert.NilError(t, err)
+	info := &ebay.FeedInfo{Type: "snapshot", LastModified: time.Date(2020, 5, 5, 9, 30, 0, 0, time.UTC)}
+	_, err = db.Upsert(ebaytest.ItemReader(&ebay.Item{ID: "v1|5|0", CategoryID: "177"}), info)
+	assert.NilError(t, err)
+
+	batch, err = db.LastBatch()
+	assert.NilError(t, err)
+	assert.Equal(t, batch.Seq, uint64(2))
+	assert.DeepEqual(t, batch.Info, *info)
+
+	counts, err := db.Counts("categoryId")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, counts, map[string]int{"9355": 2, "177": 2})
+
+	_, err = db.Counts("Title")
+	assert.Equal(t, err, ErrNotIndexed)
+
+	scan := func(after string, limit int) []string {
+		var got []string
+		assert.NilError(t, db.Scan(after, func(id, row string) error {
+			if len(got) == limit {
+				return io.EOF
+			}
+			got = append(got, id)
+			return nil
+		}))
+		return got
+	}
+	assert.DeepEqual(t, scan("", 2), []string{"v1|1|0", "v1|2|0"})
+	assert.DeepEqual(t, scan("v1|2|0", 2), []string{"v1|3|0", "v1|4|0"})
+	assert.DeepEqual(t, scan("v1|4", 10), []string{"v1|4|0", "v1|5|0"})
+	assert.DeepEqual(t, scan("v1|5|0", 10), []string(nil))
+
+	// The indexes missing in the file are built when opening it
+	assert.NilError(t, db.bolt.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket(current(tx).name(indexBucket("CategoryID")))
+	}))
+	assert.NilError(t, db.Close())
+
+	db, err = Open(path)
+	assert.NilError(t, err)
+
+	found, err := db.Lookup("CategoryID", "177", "", 0)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ids(found), []string{"v1|3|0", "v1|5|0"})
+}
@@ -1,24 +1,63 @@
 package test
 
 import (
+	"bytes"
 	"compress/gzip"
 	"context"
 	"ebay-api-client/ebay"
+	"ebay-api-client/ebay/ebaytest"
 	"ebay-api-client/oauth2"
+	"ebay-api-client/oauth2/oauth2test"
+	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"gotest.tools/v3/assert"
 )
 
+const (
+	// weeklyItemBoostrapCassette stores the sandbox interactions of Test_WeekItemBoostrap. It is not committed yet:
+	// it has to be recorded once with the sandbox credentials (EBAY_API_CLIENT_ID and EBAY_API_CLIENT_SECRET set),
+	// until then the test is skipped without credentials.
+	weeklyItemBoostrapCassette string = "testdata/weeklyitemboostrap.json"
+	// envRecord is the os variable name forcing the tests to record the cassettes again against the sandbox
+	envRecord string = "EBAY_TEST_RECORD"
+)
+
+// newSandboxHTTPClient returns the HTTP client of the sandbox tests. The cassette is replayed if it exists,
+// otherwise the sandbox interactions are recorded when the credentials are available. The returned function
+// saves the recorded cassette. The test is skipped if neither the cassette nor the credentials are available.
+func newSandboxHTTPClient(t *testing.T, cassette string, scopes []string) (ebay.HTTPClient, func()) {
+	if _, err := os.Stat(cassette); err == nil && os.Getenv(envRecord) == "" {
+		c, err := ebaytest.LoadCassette(cassette)
+		assert.NilError(t, err)
+		return ebaytest.NewReplayer(c), func() {}
+	}
+
+	httpClient, err := oauth2.NewSandboxClientCredentialsClient(context.Background(), oauth2.NewEnvCredentials(), scopes)
+	if err != nil {
+		t.Skipf("no cassette %v and no sandbox credentials: %v", cassette, err)
+	}
+
+	recorder := ebaytest.NewRecorder(httpClient)
+	return recorder, func() {
+		if !t.Failed() {
+			assert.NilError(t, recorder.Save(cassette))
+		}
+	}
+}
+
 func Test_WeekItemBoostrap(t *testing.T) {
 
 	filename := "feed.tsv.gz"
 
 	ctx := context.Background()
-	httpClient, err := oauth2.NewSandboxClientCredentialsClient(ctx, []string{oauth2.ScopeBuyFeedAPI})
-	assert.NilError(t, err)
+	httpClient, save := newSandboxHTTPClient(t, weeklyItemBoostrapCassette, []string{oauth2.ScopeBuyFeedAPI})
+	defer save()
 
 	feedClient := ebay.NewSandboxFeedService(httpClient)
 	feedClient.ChunkSize = 1816
@@ -46,3 +85,86 @@ func Test_WeekItemBoostrap(t *testing.T) {
 	assert.Assert(t, len(b) != 0)
 	t.Logf(string(b))
 }
+
+// newOfflineFeedService wires a FeedService to the fake token endpoint and the fake Feed API
+func newOfflineFeedService(t *testing.T, tokens *oauth2test.TokenServer, feeds *ebaytest.FeedServer) *ebay.FeedService {
+	tokens.AddClient("TEST-SBX-ID", "TEST-SBX-SECRET", oauth2.ScopeBuyFeedAPI)
+	feeds.SetAuthorizer(func(r *http.Request) bool {
+		return tokens.Authorize(r, oauth2.ScopeBuyFeedAPI)
+	})
+
+	httpClient, err := oauth2.NewClientCredentialsClient(context.Background(), oauth2.NewStaticCredentials("TEST-SBX-ID", "TEST-SBX-SECRET"), tokens.TokenURL(), []string{oauth2.ScopeBuyFeedAPI})
+	assert.NilError(t, err)
+
+	feedClient, err := ebay.NewCheckedSandboxFeedService(httpClient)
+	assert.NilError(t, err)
+	feedClient.BaseURL = feeds.BaseURL()
+	feedClient.ChunkSize = 1816
+
+	return feedClient
+}
+
+func Test_WeekItemBoostrapOffline(t *testing.T) {
+	tokens := oauth2test.NewTokenServer()
+	defer tokens.Close()
+
+	feeds := ebaytest.NewFeedServer()
+	defer feeds.Close()
+
+	var rows [][]string
+	for i := 0; i < 500; i++ {
+		rows = append(rows, []string{fmt.Sprintf("v1|%v|0", 110000000000+i), fmt.Sprintf("Item %v", i)})
+	}
+	feeds.AddFeed(&ebaytest.Feed{
+		Path:         ebaytest.PathItem,
+		MarketID:     "EBAY_US",
+		CategoryID:   "1",
+		Scope:        "ALL_ACTIVE",
+		LastModified: time.Date(2020, 5, 4, 7, 0, 0, 0, time.UTC),
+		Data:         ebaytest.GzipTSV([]string{"ItemId", "Title"}, rows...),
+	})
+
+	feedClient := newOfflineFeedService(t, tokens, feeds)
+
+	buffer := new(bytes.Buffer)
+	info, err := feedClient.WeeklyItemBoostrap(context.Background(), "EBAY_US", "1", buffer)
+	assert.NilError(t, err)
+	assert.Equal(t, info.Size, int64(buffer.Len()))
+	assert.Assert(t, len(feeds.Requests()) > 1)
+
+	// The token is minted once and reused for all the chunks
+	assert.Equal(t, tokens.Requests(), 1)
+
+	gunzip, err := gzip.NewReader(buffer)
+	assert.NilError(t, err)
+
+	b, err := ioutil.ReadAll(gunzip)
+	assert.NilError(t, err)
+	assert.Equal(t, strings.Count(string(b), "\n"), 501)
+}
+
+func Test_WeekItemBoostrapOfflineRefreshingRevokedToken(t *testing.T) {
+	tokens := oauth2test.NewTokenServer()
+	defer tokens.Close()
+
+	feeds := ebaytest.NewFeedServer()
+	defer feeds.Close()
+
+	feeds.AddFeed(&ebaytest.Feed{
+		Path: ebaytest.PathItem,
+		Data: ebaytest.GzipTSV([]string{"ItemId", "Title"}, []string{"v1|110000000000|0", "Item"}),
+	})
+
+	feedClient := newOfflineFeedService(t, tokens, feeds)
+
+	_, err := feedClient.WeeklyItemBoostrap(context.Background(), "EBAY_US", "1", new(bytes.Buffer))
+	assert.NilError(t, err)
+
+	// Revoking the token makes the Feed API answer 401, the client mints a new token and replays the request
+	authorization := feeds.Requests()[0].Header.Get("Authorization")
+	tokens.Revoke(strings.TrimPrefix(authorization, "Bearer "))
+
+	_, err = feedClient.WeeklyItemBoostrap(context.Background(), "EBAY_US", "1", new(bytes.Buffer))
+	assert.NilError(t, err)
+	assert.Equal(t, tokens.Requests(), 2)
+}